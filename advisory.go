@@ -0,0 +1,132 @@
+package supersql
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+)
+
+//Advisory lock key for a name i.e. AdvisoryKey("cron:nightly-report"), the 64 bit FNV-1a hash of the
+//name so every process deriving the key from the same name contends for the same lock
+func AdvisoryKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+//A session level advisory lock. The connection holding it is kept out of the pool until Unlock(...)
+//since postgres releases the lock when that connection goes away.
+type AdvisoryLock struct {
+	key     int64
+	session *SqlQuery
+	release func()
+
+	mu       sync.Mutex
+	unlocked bool
+}
+
+//Wait for the session level advisory lock on key. The wait ends early when the context of the query
+//root is cancelled.
+func (q *SqlQuery) ADVISORY_LOCK(key int64) (*AdvisoryLock, error) {
+	l, err := q.pin(key)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := l.session.RAW("SELECT pg_advisory_lock(?)", key).GO(); err != nil {
+		l.release()
+		return nil, err
+	}
+	return l, nil
+}
+
+//Take the session level advisory lock on key if no other session holds it. The lock is nil and ok is
+//false when it is taken.
+func (q *SqlQuery) TRY_ADVISORY_LOCK(key int64) (lock *AdvisoryLock, ok bool, err error) {
+	l, err := q.pin(key)
+	if err != nil {
+		return nil, false, err
+	}
+	if ok, err = locked(l.session.RAW("SELECT pg_try_advisory_lock(?) AS locked", key).GO()); err != nil || !ok {
+		l.release()
+		return nil, false, err
+	}
+	return l, true, nil
+}
+
+//Take a connection out of the pool for the lock, roots bound to a single connection or transaction
+//lock on it instead
+func (q *SqlQuery) pin(key int64) (*AdvisoryLock, error) {
+	session, release, err := q.pinned()
+	if err != nil {
+		return nil, err
+	}
+	return &AdvisoryLock{key: key, session: session, release: release}, nil
+}
+
+//Wait for the transaction level advisory lock on key which is released when the transaction ends.
+//Only valid on the query root handed to TRANSACTION(...).
+func (q *SqlQuery) ADVISORY_XACT_LOCK(key int64) error {
+	if _, ok := q.exec.(Transaction); !ok {
+		return fmt.Errorf("ADVISORY_XACT_LOCK must be invoked inside a TRANSACTION")
+	}
+	_, err := q.RAW("SELECT pg_advisory_xact_lock(?)", key).GO()
+	return err
+}
+
+//Take the transaction level advisory lock on key if no other transaction holds it
+func (q *SqlQuery) TRY_ADVISORY_XACT_LOCK(key int64) (bool, error) {
+	if _, ok := q.exec.(Transaction); !ok {
+		return false, fmt.Errorf("TRY_ADVISORY_XACT_LOCK must be invoked inside a TRANSACTION")
+	}
+	return locked(q.RAW("SELECT pg_try_advisory_xact_lock(?) AS locked", key).GO())
+}
+
+func locked(r Results, err error) (bool, error) {
+	if err != nil || r == nil || r.Count() == 0 {
+		return false, err
+	}
+	ok, _ := r.Rows(1).Column("locked").(bool)
+	return ok, nil
+}
+
+func (l *AdvisoryLock) Key() int64 {
+	return l.key
+}
+
+//Query root bound to the connection holding the lock i.e. to do the work the lock guards on the
+//same session
+func (l *AdvisoryLock) Session() *SqlQuery {
+	return l.session
+}
+
+//Report an error when the connection holding the lock can not be reached. The lock is gone with
+//the connection and another session may hold it already.
+func (l *AdvisoryLock) Check(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.unlocked {
+		return fmt.Errorf("advisory lock %d was unlocked", l.key)
+	}
+	_, err := l.session.WithContext(ctx).RAW("SELECT 1").GO()
+	return err
+}
+
+//Release the lock and give its connection back to the pool
+func (l *AdvisoryLock) Unlock(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.unlocked {
+		return nil
+	}
+	l.unlocked = true
+	defer l.release()
+	ok, err := locked(l.session.WithContext(ctx).RAW("SELECT pg_advisory_unlock(?) AS locked", l.key).GO())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("advisory lock %d was not held by its session", l.key)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package supersql_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rayattack/supersql"
+	"github.com/rayattack/supersql/supersqltest"
+)
+
+func TestAdvisoryKey(t *testing.T) {
+	if supersql.AdvisoryKey("cron") != supersql.AdvisoryKey("cron") || supersql.AdvisoryKey("cron") == supersql.AdvisoryKey("jobs") {
+		t.Fatal("keys should be derived from the name only")
+	}
+}
+
+func TestAdvisoryLock(t *testing.T) {
+	ctx := context.Background()
+	fake := supersqltest.New()
+	fake.Expect("pg_try_advisory_lock").Returns([]string{"locked"}, []interface{}{true})
+	fake.Expect("pg_advisory_unlock").Returns([]string{"locked"}, []interface{}{true})
+	lock, ok, err := fake.Root().TRY_ADVISORY_LOCK(42)
+	if err != nil || !ok || lock.Key() != 42 {
+		t.Fatal(lock, ok, err)
+	}
+	if err := lock.Check(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := lock.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := lock.Check(ctx); err == nil {
+		t.Fatal("unlocked locks are not held")
+	}
+	calls := fake.Calls()
+	if calls[0].SQL != "SELECT pg_try_advisory_lock($1) AS locked" || calls[0].Args[0] != int64(42) || calls[2].SQL != "SELECT pg_advisory_unlock($1) AS locked" {
+		t.Fatal(calls)
+	}
+
+	fake.Reset()
+	fake.Expect("pg_try_advisory_lock").Returns([]string{"locked"}, []interface{}{false})
+	if lock, ok, err := fake.Root().TRY_ADVISORY_LOCK(42); err != nil || ok || lock != nil {
+		t.Fatal("locks held elsewhere are not taken", lock, ok, err)
+	}
+
+	fake.Reset()
+	fake.Expect("pg_advisory_lock").Fails(errors.New("canceling statement due to user request"))
+	if _, err := fake.Root().ADVISORY_LOCK(42); err == nil {
+		t.Fatal("failing to lock is an error")
+	}
+	lock, err = fake.Root().ADVISORY_LOCK(supersql.AdvisoryKey("cron"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := lock.Unlock(ctx); err == nil {
+		t.Fatal("unlocking a lock the session does not hold is an error")
+	}
+}
+
+func TestAdvisoryXactLock(t *testing.T) {
+	fake := supersqltest.New()
+	if err := fake.Root().ADVISORY_XACT_LOCK(42); err == nil {
+		t.Fatal("transaction locks need a transaction")
+	}
+	fake.Expect("pg_try_advisory_xact_lock").Returns([]string{"locked"}, []interface{}{true})
+	err := fake.Root().TRANSACTION(func(tx *supersql.SqlQuery) error {
+		if err := tx.ADVISORY_XACT_LOCK(7); err != nil {
+			return err
+		}
+		ok, err := tx.TRY_ADVISORY_XACT_LOCK(8)
+		if !ok {
+			t.Error("lock should be taken")
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls := fake.Calls(); calls[1].SQL != "SELECT pg_advisory_xact_lock($1)" || calls[2].SQL != "SELECT pg_try_advisory_xact_lock($1) AS locked" {
+		t.Fatal(calls)
+	}
+}
@@ -0,0 +1,127 @@
+package supersql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+//Commands queued to be sent to the server together in a single round trip
+//
+//	b := q.Batch()
+//	b.Add(q.SELECT("title").FROM("film").WHERE("film_id = ?", 133))
+//	b.Add(q.INSERT_INTO("rental", columns).VALUES(rental))
+//	results, err := b.GO() // results[0].Results holds the film, results[1].Err whether the rental was written
+type SqlBatch struct {
+	q        *SqlQuery
+	commands []Command
+}
+
+//Outcome of a single command of a batch. Results is nil for commands that do not return records.
+type BatchResult struct {
+	Results Results
+	Err     error
+}
+
+//A statement of a batch as sent to the server, Exec is set for commands that do not return records
+type BatchStatement struct {
+	SQL  string
+	Args []interface{}
+	Exec bool
+}
+
+//Implemented by executors that can send many statements in a single round trip. The outcome of every
+//statement is handed to each in the order of the statements, cursors are only valid until each
+//returns. The error is for the batch as a whole.
+type batcher interface {
+	Batch(ctx context.Context, statements []BatchStatement, each func(i int, cursor Cursor, err error)) error
+}
+
+//Start a batch of commands run by this query root i.e. inside its transaction when it is the root
+//handed to TRANSACTION(...)
+func (q *SqlQuery) Batch() *SqlBatch {
+	return &SqlBatch{q: q}
+}
+
+//Queue commands expantiated from any query root, they are run by the root of the batch
+func (b *SqlBatch) Add(commands ...Command) *SqlBatch {
+	b.commands = append(b.commands, commands...)
+	return b
+}
+
+//Number of commands queued
+func (b *SqlBatch) Len() int {
+	return len(b.commands)
+}
+
+//Send every queued command and return their outcomes in the order they were added. Nothing is sent
+//when a command holds an expantiation error. Postgres runs a batch sent outside a transaction as a
+//single implicit transaction so a failing command aborts the commands after it and undoes the ones
+//before it. Executors that can not send batches run the commands one after the other instead.
+func (b *SqlBatch) GO() ([]BatchResult, error) {
+	queries := []SqlQuery{}
+	statements := []BatchStatement{}
+	for i, command := range b.commands {
+		var q SqlQuery
+		switch c := command.(type) {
+		case SqlQuery:
+			q = c
+		case *SqlQuery:
+			q = *c
+		default:
+			return nil, fmt.Errorf("command %d: %T can not be batched", i+1, command)
+		}
+		if err := q.check(); err != nil {
+			return nil, fmt.Errorf("command %d: %w", i+1, err)
+		}
+		ssql, args, err := q.statement()
+		if err != nil {
+			return nil, fmt.Errorf("command %d: %w", i+1, err)
+		}
+		q.exec, q.ctx, q.hooks = b.q.exec, b.q.ctx, b.q.hooks
+		queries = append(queries, q)
+		statements = append(statements, BatchStatement{ssql, args, q.void})
+	}
+	if len(statements) == 0 {
+		return []BatchResult{}, nil
+	}
+
+	bt, ok := b.q.exec.(batcher)
+	if !ok {
+		results := make([]BatchResult, len(queries))
+		for i, q := range queries {
+			results[i].Results, results[i].Err = q.GO()
+		}
+		return results, nil
+	}
+
+	all := []string{}
+	args := []interface{}{}
+	for _, statement := range statements {
+		all = append(all, statement.SQL)
+		args = append(args, statement.Args...)
+	}
+	results := make([]BatchResult, len(statements))
+	failed := false
+	err := b.q.observe(strings.Join(all, ";\n"), args, func(ctx context.Context) (int64, error) {
+		count := int64(0)
+		err := bt.Batch(ctx, statements, func(i int, cursor Cursor, err error) {
+			if err == nil && cursor != nil {
+				var r SqlResult
+				if r, err = collect(cursor); err == nil {
+					results[i].Results = r
+					count += int64(r.count)
+				}
+			}
+			results[i].Err = err
+			failed = failed || err != nil
+		})
+		if failed {
+			//the batch as a whole fails with the error of the first failing command which already is
+			//the outcome of that command
+			err = nil
+		}
+		return count, err
+	})
+	return results, err
+}
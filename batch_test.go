@@ -0,0 +1,111 @@
+package supersql_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rayattack/supersql"
+	"github.com/rayattack/supersql/supersqltest"
+)
+
+//Executor that can only run statements one at a time
+type sequential struct {
+	supersql.Executor
+}
+
+func TestBatch(t *testing.T) {
+	fake := supersqltest.New()
+	fake.Expect("FROM film").Returns([]string{"title"}, []interface{}{"Chamber Italian"})
+	fake.Expect("RETURNING rental_id").Returns([]string{"rental_id"}, []interface{}{int64(16050)})
+	q := fake.Root()
+
+	b := q.Batch().
+		Add(q.SELECT("title").FROM("film").WHERE("film_id = ?", 133)).
+		Add(q.INSERT_INTO("rental", []string{"inventory_id", "customer_id"}).VALUES([]interface{}{1, 2}).RETURNING("rental_id")).
+		Add(q.RUN("UPDATE customer SET active = 1").WHERE("customer_id = ?", 2))
+	if b.Len() != 3 {
+		t.Fatal(b.Len())
+	}
+	results, err := b.GO()
+	if err != nil || len(results) != 3 {
+		t.Fatal(results, err)
+	}
+	if title, _ := results[0].Results.Rows(1).String("title"); title != "Chamber Italian" || results[0].Err != nil {
+		t.Fatal(results[0])
+	}
+	if id := results[1].Results.Rows(1).Column("rental_id"); id != int64(16050) {
+		t.Fatal(results[1])
+	}
+	if results[2].Results != nil || results[2].Err != nil {
+		t.Fatal(results[2])
+	}
+	calls := fake.Calls()
+	if len(calls) != 3 || calls[1].SQL != "INSERT INTO rental (inventory_id, customer_id) VALUES ($1, $2) RETURNING rental_id" || calls[1].Args[1] != 2 || !calls[2].Exec {
+		t.Fatal(calls)
+	}
+
+	if results, err := q.Batch().GO(); err != nil || len(results) != 0 {
+		t.Fatal("empty batches send nothing", results, err)
+	}
+}
+
+func TestBatchFailures(t *testing.T) {
+	fake := supersqltest.New()
+	fake.Expect("INSERT INTO rental").Fails(errors.New("duplicate key value violates unique constraint"))
+	q := fake.Root()
+	results, err := q.Batch().
+		Add(q.SELECT("title").FROM("film")).
+		Add(q.INSERT_INTO("rental", []string{"rental_id"}).VALUES([]interface{}{1})).
+		Add(q.SELECT("name").FROM("language")).
+		GO()
+	if err != nil {
+		t.Fatal("failures are reported per command", err)
+	}
+	if results[0].Err != nil || results[1].Err == nil || !errors.Is(results[2].Err, results[1].Err) {
+		t.Fatal(results)
+	}
+
+	if _, err := q.Batch().Add(q.SELECT("rating").FROM(film)).GO(); err == nil {
+		t.Fatal("expantiation errors stop the batch")
+	}
+	if len(fake.Calls()) != 3 {
+		t.Fatal("nothing is sent for invalid batches", fake.Calls())
+	}
+}
+
+func TestBatchInTransaction(t *testing.T) {
+	fake := supersqltest.New()
+	q := fake.Root()
+	err := q.TRANSACTION(func(tx *supersql.SqlQuery) error {
+		results, err := tx.Batch().
+			Add(q.RUN("UPDATE film SET rental_rate = 0.99").WHERE("film_id = ?", 133)).
+			Add(q.RUN("DELETE FROM rental").WHERE("inventory_id = ?", 7)).
+			GO()
+		if err == nil && results[1].Err != nil {
+			err = results[1].Err
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := fake.Calls()
+	if len(calls) != 4 || calls[0].SQL != "BEGIN" || calls[1].SQL != "UPDATE film SET rental_rate = 0.99 WHERE film_id = $1" || calls[3].SQL != "COMMIT" {
+		t.Fatal(calls)
+	}
+}
+
+func TestBatchFallback(t *testing.T) {
+	fake := supersqltest.New()
+	fake.Expect("INSERT INTO rental").Fails(errors.New("duplicate key value violates unique constraint"))
+	fake.Expect("FROM language").Returns([]string{"name"}, []interface{}{"English"})
+	q := supersql.QueryWith(context.Background(), sequential{fake}, supersql.Postgres)
+	results, err := q.Batch().
+		Add(q.INSERT_INTO("rental", []string{"rental_id"}).VALUES([]interface{}{1})).
+		Add(q.SELECT("name").FROM("language")).
+		GO()
+	if err != nil || results[0].Err == nil || results[1].Err != nil || results[1].Results.Count() != 1 {
+		t.Fatal("commands run one after the other are independent", results, err)
+	}
+}
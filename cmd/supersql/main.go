@@ -0,0 +1,84 @@
+//Command supersql holds the schema tooling meant to run in CI.
+//
+//	supersql diff -dsn $DATABASE_URL -reference $REFERENCE_URL
+//
+//diff compares a database with a reference database i.e. one built from scratch by applying every
+//migration, prints the statements that bring the database in line with the reference and exits
+//with 0 when they match, 1 when changes are pending and 2 when a database could not be inspected.
+//The database is read from -dsn or the DATABASE_URL environment variable.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rayattack/supersql"
+	"github.com/rayattack/supersql/diff"
+	"github.com/rayattack/supersql/introspect"
+)
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] != "diff" {
+		fmt.Fprintln(os.Stderr, "usage: supersql diff [flags]")
+		os.Exit(diff.Failed)
+	}
+	os.Exit(run(os.Args[2:]))
+}
+
+func run(args []string) int {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	dsn := flags.String("dsn", os.Getenv("DATABASE_URL"), "connection string of the database to check")
+	reference := flags.String("reference", os.Getenv("REFERENCE_URL"), "connection string of the database holding the expected schema")
+	schema := flags.String("schema", "public", "schema to compare")
+	prune := flags.Bool("prune", false, "drop tables the reference does not have")
+	ignore := flags.String("ignore", "", "comma separated tables to leave alone when pruning")
+	timeout := flags.Duration("timeout", 30*time.Second, "time allowed for inspecting both databases")
+	flags.Parse(args)
+
+	if *dsn == "" || *reference == "" {
+		fmt.Fprintln(os.Stderr, "supersql diff: set -dsn or DATABASE_URL and -reference or REFERENCE_URL")
+		return diff.Failed
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	tables, err := expected(ctx, *reference, *schema)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "supersql diff: reading the reference:", err)
+		return diff.Failed
+	}
+	q, err := supersql.Open(ctx, supersql.Config{DSN: *dsn})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "supersql diff:", err)
+		return diff.Failed
+	}
+	defer q.CLOSE()
+
+	opts := diff.Options{Schema: *schema, Prune: *prune}
+	if *ignore != "" {
+		opts.Ignore = strings.Split(*ignore, ",")
+	}
+	return diff.Check(ctx, q, os.Stdout, opts, tables...)
+}
+
+//The tables of schema in the reference database as declarations
+func expected(ctx context.Context, dsn string, schema string) ([]*supersql.SqlTable, error) {
+	q, err := supersql.Open(ctx, supersql.Config{DSN: dsn})
+	if err != nil {
+		return nil, err
+	}
+	defer q.CLOSE()
+	inspected, err := introspect.Inspect(ctx, q, schema)
+	if err != nil {
+		return nil, err
+	}
+	tables := []*supersql.SqlTable{}
+	for _, table := range inspected.Tables {
+		tables = append(tables, table.SqlTable())
+	}
+	return tables, nil
+}
@@ -0,0 +1,98 @@
+//Command supersqlgen introspects a postgres schema and writes Go structs, table handles and typed
+//column fields for its tables. It is meant to be run from go generate:
+//
+//	//go:generate supersqlgen -package models -out schema_gen.go
+//
+//With -queries it writes typed functions for the named queries of annotated .sql files instead,
+//using the database to derive parameter and result column types:
+//
+//	//go:generate supersqlgen -queries "queries/*.sql" -out queries_gen.go
+//
+//The database is read from -dsn or the DATABASE_URL environment variable.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rayattack/supersql"
+	"github.com/rayattack/supersql/gen"
+	"github.com/rayattack/supersql/introspect"
+	"github.com/rayattack/supersql/sqlfile"
+)
+
+func main() {
+	dsn := flag.String("dsn", os.Getenv("DATABASE_URL"), "connection string of the database to introspect")
+	schema := flag.String("schema", "public", "schema to generate code for")
+	pkg := flag.String("package", "", "package of the generated file, the name of the output directory when empty")
+	out := flag.String("out", "", "file to write, standard output when empty")
+	tables := flag.String("tables", "", "comma separated tables to generate, every table of the schema when empty")
+	queries := flag.String("queries", "", "comma separated globs of .sql files to generate query functions for")
+	timeout := flag.Duration("timeout", 30*time.Second, "time allowed for introspection")
+	flag.Parse()
+
+	if err := run(*dsn, *schema, *pkg, *out, *tables, *queries, *timeout); err != nil {
+		fmt.Fprintln(os.Stderr, "supersqlgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(dsn, schema, pkg, out, tables, queries string, timeout time.Duration) error {
+	if dsn == "" {
+		return fmt.Errorf("no database, set -dsn or DATABASE_URL")
+	}
+	if pkg == "" {
+		pkg = os.Getenv("GOPACKAGE")
+	}
+	if pkg == "" && out != "" {
+		abs, err := filepath.Abs(out)
+		if err != nil {
+			return err
+		}
+		pkg = filepath.Base(filepath.Dir(abs))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	q, err := supersql.Open(ctx, supersql.Config{DSN: dsn})
+	if err != nil {
+		return err
+	}
+	defer q.CLOSE()
+
+	var source []byte
+	opts := gen.Options{Package: pkg}
+	if queries != "" {
+		loaded, err := sqlfile.Load(os.DirFS("."), strings.Split(queries, ",")...)
+		if err != nil {
+			return err
+		}
+		q = q.WithContext(ctx)
+		source, err = gen.Queries(loaded, q.DESCRIBE, opts)
+		if err != nil {
+			return err
+		}
+	} else {
+		inspected, err := introspect.Inspect(ctx, q, schema)
+		if err != nil {
+			return err
+		}
+		if tables != "" {
+			opts.Tables = strings.Split(tables, ",")
+		}
+		source, err = gen.Generate(inspected, opts)
+		if err != nil {
+			return err
+		}
+	}
+	if out == "" {
+		_, err = os.Stdout.Write(source)
+		return err
+	}
+	return os.WriteFile(out, source, 0644)
+}
@@ -1 +1,545 @@
 package supersql
+
+import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgconn/stmtcache"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+//How pgx caches the statements it sends to the server. Poolers in transaction mode i.e. PgBouncer
+//need CacheDescribe or CacheDisabled.
+type StatementCache string
+
+const (
+	CachePrepare  StatementCache = "prepare"
+	CacheDescribe StatementCache = "describe"
+	CacheDisabled StatementCache = "disabled"
+)
+
+//Backoff policy for the initial connection of Open(...). Attempts below 2 disable retries, the delay
+//starts at Backoff and is multiplied by Multiplier (2 when unset) after every failure up to MaxBackoff.
+type Retry struct {
+	Attempts   int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	Multiplier float64
+}
+
+func (r Retry) delay(attempt int) time.Duration {
+	multiplier := r.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+	delay := float64(r.Backoff)
+	for i := 1; i < attempt; i++ {
+		delay *= multiplier
+	}
+	if r.MaxBackoff > 0 && delay > float64(r.MaxBackoff) {
+		return r.MaxBackoff
+	}
+	return time.Duration(delay)
+}
+
+//Connection settings for Open(...). Zero values keep the pgxpool defaults or whatever the DSN says.
+type Config struct {
+	DSN string
+
+	MaxConns          int32
+	MinConns          int32
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+
+	TLSConfig       *tls.Config
+	ApplicationName string
+
+	StatementCache         StatementCache
+	StatementCacheCapacity int
+
+	LazyConnect bool
+	Retry       Retry
+}
+
+func (c Config) pool() (*pgxpool.Config, error) {
+	config, err := pgxpool.ParseConfig(c.DSN)
+	if err != nil {
+		return nil, err
+	}
+	if c.MaxConns > 0 {
+		config.MaxConns = c.MaxConns
+	}
+	if c.MinConns > 0 {
+		config.MinConns = c.MinConns
+	}
+	if c.MaxConnLifetime > 0 {
+		config.MaxConnLifetime = c.MaxConnLifetime
+	}
+	if c.MaxConnIdleTime > 0 {
+		config.MaxConnIdleTime = c.MaxConnIdleTime
+	}
+	if c.HealthCheckPeriod > 0 {
+		config.HealthCheckPeriod = c.HealthCheckPeriod
+	}
+	config.LazyConnect = c.LazyConnect
+
+	if c.TLSConfig != nil {
+		//an explicit TLS configuration must not silently fall back to plain text
+		config.ConnConfig.TLSConfig = c.TLSConfig
+		config.ConnConfig.Fallbacks = nil
+	}
+	if c.ApplicationName != "" {
+		config.ConnConfig.RuntimeParams["application_name"] = c.ApplicationName
+	}
+
+	capacity := c.StatementCacheCapacity
+	if capacity <= 0 {
+		capacity = 512
+	}
+	switch c.StatementCache {
+	case "":
+	case CachePrepare:
+		config.ConnConfig.BuildStatementCache = func(conn *pgconn.PgConn) stmtcache.Cache {
+			return stmtcache.New(conn, stmtcache.ModePrepare, capacity)
+		}
+	case CacheDescribe:
+		config.ConnConfig.BuildStatementCache = func(conn *pgconn.PgConn) stmtcache.Cache {
+			return stmtcache.New(conn, stmtcache.ModeDescribe, capacity)
+		}
+	case CacheDisabled:
+		config.ConnConfig.BuildStatementCache = nil
+	default:
+		return nil, fmt.Errorf("unknown statement cache mode %q", c.StatementCache)
+	}
+	return config, nil
+}
+
+//Connect to a postgres server with the given configuration and return the query root every command
+//is expantiated from. Failures are returned to the caller after the retry policy is exhausted.
+func Open(ctx context.Context, config Config) (*SqlQuery, error) {
+	pc, err := config.pool()
+	if err != nil {
+		return nil, err
+	}
+
+	attempts := config.Retry.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	var pool *pgxpool.Pool
+	for attempt := 1; attempt <= attempts; attempt++ {
+		pool, err = pgxpool.ConnectConfig(ctx, pc.Copy())
+		if err == nil {
+			return root(ctx, pgxExecutor{pool}, Postgres), nil
+		}
+		if attempt == attempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(config.Retry.delay(attempt)):
+		}
+	}
+	return nil, fmt.Errorf("could not connect to database after %d attempt(s): %w", attempts, err)
+}
+
+//Everything supersql needs from a connection to send expantiated commands to the server. Both the
+//pgx and the database/sql backends implement it so results are built the same way for either, and
+//tests can swap in the scriptable fake from the supersqltest package.
+type Executor interface {
+	Exec(ctx context.Context, ssql string, args ...interface{}) (int64, error)
+	Query(ctx context.Context, ssql string, args ...interface{}) (Cursor, error)
+}
+
+//Cursor over the records returned by an Executor
+type Cursor interface {
+	Columns() ([]string, error)
+	Next() bool
+	Values() ([]interface{}, error)
+	Err() error
+	Close() error
+}
+
+//Implemented by executors that can bulk load records faster than a naive SQL INSERT command
+type copier interface {
+	CopyFrom(ctx context.Context, table string, columns []string, rows [][]interface{}) (int64, error)
+}
+
+//Implemented by executors that own their connections and should release them on CLOSE()
+type closer interface {
+	Close() error
+}
+
+//An Executor bound to a database transaction
+type Transaction interface {
+	Executor
+	Commit(ctx context.Context) error
+	Rollback(ctx context.Context) error
+}
+
+//Implemented by executors that can start transactions, transactions themselves may implement it
+//with savepoints
+type beginner interface {
+	Begin(ctx context.Context) (Transaction, error)
+}
+
+//Implemented by executors that spread commands over a pool of connections and can pin one of them
+//i.e. for session level settings and locks. The returned function gives the connection back.
+type sessioner interface {
+	Session(ctx context.Context) (Executor, func(), error)
+}
+
+//Implemented by executors that can report the parameter and result column types of a statement
+//without running it
+type describer interface {
+	Describe(ctx context.Context, ssql string) (*Statement, error)
+}
+
+//The subset of pgxpool.Pool, pgxpool.Conn, pgx.Conn and pgx.Tx used by supersql
+type pgxHandle interface {
+	Exec(ctx context.Context, ssql string, args ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, ssql string, args ...interface{}) (pgx.Rows, error)
+	CopyFrom(ctx context.Context, table pgx.Identifier, columns []string, src pgx.CopyFromSource) (int64, error)
+}
+
+type pgxExecutor struct {
+	handle pgxHandle
+}
+
+func (p pgxExecutor) Exec(ctx context.Context, ssql string, args ...interface{}) (int64, error) {
+	tag, err := p.handle.Exec(ctx, ssql, args...)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (p pgxExecutor) Query(ctx context.Context, ssql string, args ...interface{}) (Cursor, error) {
+	ctrl, err := p.handle.Query(ctx, ssql, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgxRows{ctrl}, nil
+}
+
+func (p pgxExecutor) CopyFrom(ctx context.Context, table string, columns []string, records [][]interface{}) (int64, error) {
+	return p.handle.CopyFrom(ctx, pgx.Identifier(splitIdentifier(table)), columns, pgx.CopyFromRows(records))
+}
+
+func (p pgxExecutor) Begin(ctx context.Context) (Transaction, error) {
+	b, ok := p.handle.(interface {
+		Begin(ctx context.Context) (pgx.Tx, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%T can not start transactions", p.handle)
+	}
+	tx, err := b.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return pgxTx{pgxExecutor{tx}, tx}, nil
+}
+
+func (p pgxExecutor) Session(ctx context.Context) (Executor, func(), error) {
+	pool, ok := p.handle.(*pgxpool.Pool)
+	if !ok {
+		//anything other than a pool already is a single connection
+		return p, func() {}, nil
+	}
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pgxExecutor{conn}, conn.Release, nil
+}
+
+//Prepare ssql as the unnamed statement of a connection and name the types the server infers for its
+//parameters and result columns
+func (p pgxExecutor) Describe(ctx context.Context, ssql string) (*Statement, error) {
+	var conn *pgx.Conn
+	switch h := p.handle.(type) {
+	case *pgxpool.Pool:
+		c, err := h.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer c.Release()
+		conn = c.Conn()
+	case *pgxpool.Conn:
+		conn = h.Conn()
+	case *pgx.Conn:
+		conn = h
+	case pgx.Tx:
+		conn = h.Conn()
+	default:
+		return nil, fmt.Errorf("%T can not describe statements", p.handle)
+	}
+
+	description, err := conn.PgConn().Prepare(ctx, "", ssql, nil)
+	if err != nil {
+		return nil, err
+	}
+	names := map[uint32]string{}
+	typename := func(oid uint32) (string, error) {
+		if name, ok := names[oid]; ok {
+			return name, nil
+		}
+		var name string
+		if err := conn.QueryRow(ctx, "SELECT format_type($1::int8::oid, NULL)", int64(oid)).Scan(&name); err != nil {
+			return "", err
+		}
+		names[oid] = name
+		return name, nil
+	}
+
+	statement := &Statement{}
+	for _, oid := range description.ParamOIDs {
+		name, err := typename(oid)
+		if err != nil {
+			return nil, err
+		}
+		statement.Params = append(statement.Params, name)
+	}
+	for _, field := range description.Fields {
+		name, err := typename(field.DataTypeOID)
+		if err != nil {
+			return nil, err
+		}
+		statement.Columns = append(statement.Columns, StatementColumn{Name: string(field.Name), Type: name})
+	}
+	return statement, nil
+}
+
+func (p pgxExecutor) Batch(ctx context.Context, statements []BatchStatement, each func(i int, cursor Cursor, err error)) error {
+	s, ok := p.handle.(interface {
+		SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	})
+	if !ok {
+		return fmt.Errorf("%T can not send batches", p.handle)
+	}
+	batch := &pgx.Batch{}
+	for _, statement := range statements {
+		batch.Queue(statement.SQL, statement.Args...)
+	}
+	br := s.SendBatch(ctx, batch)
+	for i, statement := range statements {
+		if statement.Exec {
+			_, err := br.Exec()
+			each(i, nil, err)
+			continue
+		}
+		rows, err := br.Query()
+		if err != nil {
+			each(i, nil, err)
+			continue
+		}
+		each(i, pgxRows{rows}, nil)
+		rows.Close()
+	}
+	return br.Close()
+}
+
+func (p pgxExecutor) WaitForNotification(ctx context.Context) (Notification, error) {
+	var conn *pgx.Conn
+	switch h := p.handle.(type) {
+	case *pgxpool.Conn:
+		conn = h.Conn()
+	case *pgx.Conn:
+		conn = h
+	default:
+		return Notification{}, fmt.Errorf("%T is not a single connection that can wait for notifications", p.handle)
+	}
+	n, err := conn.WaitForNotification(ctx)
+	if err != nil {
+		return Notification{}, err
+	}
+	return Notification{Channel: n.Channel, Payload: n.Payload, PID: n.PID}, nil
+}
+
+func (p pgxExecutor) Close() error {
+	if pool, ok := p.handle.(*pgxpool.Pool); ok {
+		pool.Close()
+	}
+	return nil
+}
+
+type pgxTx struct {
+	pgxExecutor
+	tx pgx.Tx
+}
+
+func (t pgxTx) Commit(ctx context.Context) error {
+	return t.tx.Commit(ctx)
+}
+
+func (t pgxTx) Rollback(ctx context.Context) error {
+	return t.tx.Rollback(ctx)
+}
+
+type pgxRows struct {
+	pgx.Rows
+}
+
+func (r pgxRows) Columns() ([]string, error) {
+	var columns []string
+	for _, column := range r.FieldDescriptions() {
+		columns = append(columns, string(column.Name))
+	}
+	return columns, nil
+}
+
+func (r pgxRows) Close() error {
+	r.Rows.Close()
+	return r.Rows.Err()
+}
+
+//The subset of *sql.DB, *sql.Tx and *sql.Conn used by supersql. Anything that can execute and query
+//with a context will do.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+type sqlExecutor struct {
+	handle DBTX
+}
+
+func (s sqlExecutor) Exec(ctx context.Context, ssql string, args ...interface{}) (int64, error) {
+	result, err := s.handle.ExecContext(ctx, ssql, args...)
+	if err != nil {
+		return 0, err
+	}
+	//not every driver reports affected rows and that should not fail a successful command
+	affected, _ := result.RowsAffected()
+	return affected, nil
+}
+
+func (s sqlExecutor) Query(ctx context.Context, ssql string, args ...interface{}) (Cursor, error) {
+	ctrl, err := s.handle.QueryContext(ctx, ssql, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlRows{Rows: ctrl}, nil
+}
+
+func (s sqlExecutor) Begin(ctx context.Context) (Transaction, error) {
+	b, ok := s.handle.(interface {
+		BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%T can not start transactions", s.handle)
+	}
+	tx, err := b.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return sqlTx{sqlExecutor{tx}, tx}, nil
+}
+
+func (s sqlExecutor) Session(ctx context.Context) (Executor, func(), error) {
+	db, ok := s.handle.(*sql.DB)
+	if !ok {
+		return s, func() {}, nil
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sqlExecutor{conn}, func() { conn.Close() }, nil
+}
+
+type sqlTx struct {
+	sqlExecutor
+	tx *sql.Tx
+}
+
+func (t sqlTx) Commit(ctx context.Context) error {
+	return t.tx.Commit()
+}
+
+func (t sqlTx) Rollback(ctx context.Context) error {
+	return t.tx.Rollback()
+}
+
+type sqlRows struct {
+	*sql.Rows
+	binary []bool
+}
+
+//database/sql has no equivalent of pgx.Rows.Values so every column is scanned into an interface{}.
+//Drivers hand text back as []byte which is converted to a string unless the column is binary.
+func (r *sqlRows) Values() ([]interface{}, error) {
+	if r.binary == nil {
+		types, err := r.ColumnTypes()
+		if err != nil {
+			return nil, err
+		}
+		r.binary = make([]bool, len(types))
+		for i, t := range types {
+			r.binary[i] = isBinary(t.DatabaseTypeName())
+		}
+	}
+
+	values := make([]interface{}, len(r.binary))
+	dest := make([]interface{}, len(r.binary))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := r.Scan(dest...); err != nil {
+		return nil, err
+	}
+	for i, value := range values {
+		if b, ok := value.([]byte); ok {
+			//drivers are allowed to reuse the buffer on the next call to Next() so copy it out
+			if r.binary[i] {
+				values[i] = append([]byte(nil), b...)
+			} else {
+				values[i] = string(b)
+			}
+		}
+	}
+	return values, nil
+}
+
+func isBinary(typename string) bool {
+	switch strings.ToUpper(typename) {
+	case "BYTEA", "BLOB", "TINYBLOB", "MEDIUMBLOB", "LONGBLOB", "BINARY", "VARBINARY":
+		return true
+	}
+	return false
+}
+
+//Drain records from an Executor into an SqlResult. This is the one place results are built for
+//every backend.
+func collect(ctrl Cursor) (SqlResult, error) {
+	defer ctrl.Close()
+
+	columns, err := ctrl.Columns()
+	if err != nil {
+		return SqlResult{}, err
+	}
+
+	//Check for streaming flag here and stream (lazy read) as opposed to greedy read
+	records := []Row{}
+	count := 0
+	for ctrl.Next() {
+		values, err := ctrl.Values()
+		if err != nil {
+			return SqlResult{}, err
+		}
+		records = append(records, populateRow(columns, values))
+		count++
+	}
+	if err := ctrl.Err(); err != nil {
+		return SqlResult{}, err
+	}
+	return SqlResult{columns, records, count}, nil
+}
@@ -1 +1,173 @@
 package supersql_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/rayattack/supersql"
+)
+
+//Bare bones database/sql driver that remembers the last statement it was given and answers
+//every query with a single film record
+type stubDriver struct {
+	last string
+	args []driver.NamedValue
+}
+
+func (d *stubDriver) Open(name string) (driver.Conn, error) {
+	return &stubConn{d}, nil
+}
+
+type stubConn struct {
+	driver *stubDriver
+}
+
+func (c *stubConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, driver.ErrSkip
+}
+
+func (c *stubConn) Close() error {
+	return nil
+}
+
+func (c *stubConn) Begin() (driver.Tx, error) {
+	return nil, driver.ErrSkip
+}
+
+func (c *stubConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.driver.last, c.driver.args = query, args
+	return driver.RowsAffected(len(args)), nil
+}
+
+func (c *stubConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.driver.last, c.driver.args = query, args
+	return &stubRows{}, nil
+}
+
+type stubRows struct {
+	done bool
+}
+
+func (r *stubRows) Columns() []string {
+	return []string{"film_id", "title"}
+}
+
+func (r *stubRows) Close() error {
+	return nil
+}
+
+func (r *stubRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0], dest[1] = int64(133), []byte("Chamber Italian")
+	return nil
+}
+
+var stub = &stubDriver{}
+
+func init() {
+	sql.Register("supersql-stub", stub)
+}
+
+func TestQueryDBSelect(t *testing.T) {
+	db, err := sql.Open("supersql-stub", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	q := supersql.QueryDB(context.Background(), db, supersql.SQLite)
+	r, err := q.SELECT("film_id", "title").FROM("film").WHERE("film_id = ?", 133).GO()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stub.last != "SELECT film_id, title FROM film WHERE film_id = ?" || len(stub.args) != 1 {
+		t.Log(stub.last)
+		t.Fail()
+	}
+	if r.Count() != 1 {
+		t.Fail()
+	}
+	if title, err := r.Rows(1).String("title"); err != nil || title != "Chamber Italian" {
+		t.Fail()
+	}
+	if q.CLOSE() != nil || db.Ping() != nil {
+		t.Fail()
+	}
+}
+
+func TestQueryDBInsert(t *testing.T) {
+	db, _ := sql.Open("supersql-stub", "")
+	defer db.Close()
+
+	q := supersql.QueryDB(context.Background(), db, supersql.Postgres)
+	_, err := q.INSERT_INTO("actor", []string{name, age}).VALUES([]interface{}{"ab", 4}, []interface{}{"cd", 5}).GO()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stub.last != "INSERT INTO actor (name, age) VALUES ($1, $2),($3, $4)" || len(stub.args) != 4 {
+		t.Log(stub.last)
+		t.Fail()
+	}
+}
+
+func TestOpenReturnsErrors(t *testing.T) {
+	_, err := supersql.Open(context.Background(), supersql.Config{DSN: "postgres://nobody@127.0.0.1:1/none?connect_timeout=1"})
+	if err == nil {
+		t.Fail()
+	}
+
+	_, err = supersql.Open(context.Background(), supersql.Config{DSN: "::not a dsn::"})
+	if err == nil {
+		t.Fail()
+	}
+
+	_, err = supersql.Open(context.Background(), supersql.Config{DSN: DSN, StatementCache: "sometimes"})
+	if err == nil {
+		t.Fail()
+	}
+}
+
+func TestOpenRetriesWithBackoff(t *testing.T) {
+	config := supersql.Config{
+		DSN:   "postgres://nobody@127.0.0.1:1/none?connect_timeout=1",
+		Retry: supersql.Retry{Attempts: 3, Backoff: 10 * time.Millisecond, MaxBackoff: 15 * time.Millisecond},
+	}
+	start := time.Now()
+	_, err := supersql.Open(context.Background(), config)
+	if err == nil {
+		t.Fail()
+	}
+	//two waits between three attempts i.e. 10ms followed by 20ms capped at 15ms
+	if elapsed := time.Since(start); elapsed < 25*time.Millisecond {
+		t.Logf("retried too quickly: %s", elapsed)
+		t.Fail()
+	}
+}
+
+func TestOpenLazyConnect(t *testing.T) {
+	config := supersql.Config{
+		DSN:               "postgres://nobody@127.0.0.1:1/none",
+		MaxConns:          8,
+		MinConns:          0,
+		MaxConnLifetime:   time.Hour,
+		MaxConnIdleTime:   time.Minute,
+		HealthCheckPeriod: time.Minute,
+		ApplicationName:   "supersql-test",
+		StatementCache:    supersql.CacheDescribe,
+		LazyConnect:       true,
+	}
+	q, err := supersql.Open(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.CLOSE() != nil {
+		t.Fail()
+	}
+}
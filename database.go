@@ -1,13 +1,198 @@
 package supersql
 
+import (
+	"fmt"
+	"strings"
+)
+
+//Declaration of a database. Statements are generated as strings and run with the RUN method of a
+//query root connected to another database i.e. postgres, CREATE DATABASE can not run inside a
+//transaction.
+//
+//	db := supersql.Database("pagila").OWNER("app").TEMPLATE("template0").ENCODING("UTF8")
+//	_, err := admin.RUN(db.CREATE()).GO()
 type SqlDatabase struct {
-	ddl string
+	name     string
+	ddl      string
+	owner    string
+	template string
+	encoding string
+}
+
+//Declare the database called name. The name can be omitted for a handle that only carries DDL.
+func Database(name ...string) *SqlDatabase {
+	d := &SqlDatabase{}
+	if len(name) > 0 {
+		d.name = name[0]
+	}
+	return d
 }
 
 func (d *SqlDatabase) DDL(ddl string) {
 	d.ddl = ddl
 }
 
-func Database() *SqlDatabase {
-	return &SqlDatabase{}
+//Returns the DDL registered with d.DDL(...) or the CREATE DATABASE statement of the database
+func (d *SqlDatabase) GO() string {
+	if d.ddl == "" && d.name != "" {
+		return d.CREATE()
+	}
+	return d.ddl
+}
+
+func (d *SqlDatabase) Name() string {
+	return d.name
+}
+
+//Role owning the database, the role creating it when unset
+func (d *SqlDatabase) OWNER(role string) *SqlDatabase {
+	d.owner = role
+	return d
+}
+
+//Database the new database is copied from, template1 when unset. Use template0 to pick an encoding
+//other than the one of template1.
+func (d *SqlDatabase) TEMPLATE(database string) *SqlDatabase {
+	d.template = database
+	return d
+}
+
+func (d *SqlDatabase) ENCODING(encoding string) *SqlDatabase {
+	d.encoding = encoding
+	return d
+}
+
+//Generate the CREATE DATABASE statement
+func (d *SqlDatabase) CREATE() string {
+	options := []string{}
+	if d.owner != "" {
+		options = append(options, fmt.Sprintf("OWNER = %s", quoteRole(d.owner)))
+	}
+	if d.template != "" {
+		options = append(options, fmt.Sprintf("TEMPLATE = %s", quoteIdentifier(Postgres, d.template)))
+	}
+	if d.encoding != "" {
+		options = append(options, fmt.Sprintf("ENCODING = %s", literal(d.encoding)))
+	}
+	name := quoteIdentifier(Postgres, d.name)
+	if len(options) == 0 {
+		return fmt.Sprintf("CREATE DATABASE %s", name)
+	}
+	return fmt.Sprintf("CREATE DATABASE %s WITH %s", name, strings.Join(options, " "))
+}
+
+func (d *SqlDatabase) DROP() SqlDrop {
+	return SqlDrop{kind: "DATABASE", name: quoteIdentifier(Postgres, d.name)}
+}
+
+//Declaration of a schema
+//
+//	supersql.Schema("billing").IF_NOT_EXISTS().AUTHORIZATION("billing_owner").CREATE()
+type SqlSchema struct {
+	name          string
+	authorization string
+	ifNotExists   bool
+}
+
+func Schema(name string) SqlSchema {
+	return SqlSchema{name: name}
+}
+
+func (s SqlSchema) IF_NOT_EXISTS() SqlSchema {
+	s.ifNotExists = true
+	return s
+}
+
+//Role owning the schema
+func (s SqlSchema) AUTHORIZATION(role string) SqlSchema {
+	s.authorization = role
+	return s
+}
+
+func (s SqlSchema) Name() string {
+	return s.name
+}
+
+//Generate the CREATE SCHEMA statement
+func (s SqlSchema) CREATE() string {
+	parts := []string{"CREATE SCHEMA"}
+	if s.ifNotExists {
+		parts = append(parts, "IF NOT EXISTS")
+	}
+	parts = append(parts, quoteIdentifier(Postgres, s.name))
+	if s.authorization != "" {
+		parts = append(parts, "AUTHORIZATION", quoteRole(s.authorization))
+	}
+	return strings.Join(parts, " ")
+}
+
+func (s SqlSchema) String() string {
+	return s.CREATE()
+}
+
+//Statement dropping the schema, add CASCADE() to drop the objects in it too
+func (s SqlSchema) DROP() SqlDrop {
+	return SqlDrop{kind: "SCHEMA", name: quoteIdentifier(Postgres, s.name)}
+}
+
+//Declaration of an extension. Extensions are always created IF NOT EXISTS as they are shared by
+//everything using the database.
+//
+//	supersql.Extension("pgcrypto").SCHEMA("public").CREATE() // CREATE EXTENSION IF NOT EXISTS pgcrypto WITH SCHEMA public
+type SqlExtension struct {
+	name    string
+	schema  string
+	version string
+	cascade bool
+}
+
+func Extension(name string) SqlExtension {
+	return SqlExtension{name: name}
+}
+
+//Schema the objects of the extension are created in
+func (e SqlExtension) SCHEMA(schema string) SqlExtension {
+	e.schema = schema
+	return e
+}
+
+func (e SqlExtension) VERSION(version string) SqlExtension {
+	e.version = version
+	return e
+}
+
+//Also create the extensions this one depends on
+func (e SqlExtension) CASCADE() SqlExtension {
+	e.cascade = true
+	return e
+}
+
+func (e SqlExtension) Name() string {
+	return e.name
+}
+
+//Generate the CREATE EXTENSION IF NOT EXISTS statement
+func (e SqlExtension) CREATE() string {
+	parts := []string{"CREATE EXTENSION IF NOT EXISTS", quoteIdentifier(Postgres, e.name)}
+	if e.schema != "" || e.version != "" {
+		parts = append(parts, "WITH")
+	}
+	if e.schema != "" {
+		parts = append(parts, "SCHEMA", quoteIdentifier(Postgres, e.schema))
+	}
+	if e.version != "" {
+		parts = append(parts, "VERSION", literal(e.version))
+	}
+	if e.cascade {
+		parts = append(parts, "CASCADE")
+	}
+	return strings.Join(parts, " ")
+}
+
+func (e SqlExtension) String() string {
+	return e.CREATE()
+}
+
+func (e SqlExtension) DROP() SqlDrop {
+	return SqlDrop{kind: "EXTENSION", name: quoteIdentifier(Postgres, e.name)}
 }
@@ -1 +1,68 @@
 package supersql_test
+
+import (
+	"testing"
+
+	"github.com/rayattack/supersql"
+)
+
+func TestDatabase(t *testing.T) {
+	plain := supersql.Database("pagila")
+	if plain.CREATE() != "CREATE DATABASE pagila" || plain.GO() != plain.CREATE() || plain.Name() != "pagila" {
+		t.Fatal(plain.CREATE())
+	}
+	db := supersql.Database("pagila").OWNER("app").TEMPLATE("template0").ENCODING("UTF8")
+	if db.CREATE() != "CREATE DATABASE pagila WITH OWNER = app TEMPLATE = template0 ENCODING = 'UTF8'" {
+		t.Fatal(db.CREATE())
+	}
+	if drop := db.DROP().IF_EXISTS().String(); drop != "DROP DATABASE IF EXISTS pagila" {
+		t.Fatal(drop)
+	}
+
+	mixed := supersql.Database("Reporting").OWNER("Analysts").TEMPLATE("order")
+	if mixed.CREATE() != `CREATE DATABASE "Reporting" WITH OWNER = "Analysts" TEMPLATE = "order"` || mixed.DROP().String() != `DROP DATABASE "Reporting"` {
+		t.Fatal(mixed.CREATE(), mixed.DROP().String())
+	}
+
+	//handles without a name keep carrying raw DDL
+	raw := supersql.Database()
+	raw.DDL("CREATE DATABASE legacy")
+	if raw.GO() != "CREATE DATABASE legacy" {
+		t.Fatal(raw.GO())
+	}
+}
+
+func TestSchemaAndExtension(t *testing.T) {
+	billing := supersql.Schema("billing")
+	if billing.CREATE() != "CREATE SCHEMA billing" {
+		t.Fatal(billing.CREATE())
+	}
+	if owned := billing.IF_NOT_EXISTS().AUTHORIZATION("billing_owner"); owned.String() != "CREATE SCHEMA IF NOT EXISTS billing AUTHORIZATION billing_owner" {
+		t.Fatal(owned.String())
+	}
+	if drop := billing.DROP().CASCADE().String(); drop != "DROP SCHEMA billing CASCADE" {
+		t.Fatal(drop)
+	}
+
+	if audit := supersql.Schema("Audit").AUTHORIZATION("user"); audit.CREATE() != `CREATE SCHEMA "Audit" AUTHORIZATION "user"` {
+		t.Fatal(audit.CREATE())
+	}
+	if current := supersql.Schema("scratch").AUTHORIZATION("current_user"); current.CREATE() != "CREATE SCHEMA scratch AUTHORIZATION CURRENT_USER" {
+		t.Fatal(current.CREATE())
+	}
+
+	crypto := supersql.Extension("pgcrypto")
+	if crypto.CREATE() != "CREATE EXTENSION IF NOT EXISTS pgcrypto" {
+		t.Fatal(crypto.CREATE())
+	}
+	earth := supersql.Extension("earthdistance").SCHEMA("geo").VERSION("1.1").CASCADE()
+	if earth.String() != "CREATE EXTENSION IF NOT EXISTS earthdistance WITH SCHEMA geo VERSION '1.1' CASCADE" {
+		t.Fatal(earth.String())
+	}
+	if drop := earth.DROP().IF_EXISTS().String(); drop != "DROP EXTENSION IF EXISTS earthdistance" {
+		t.Fatal(drop)
+	}
+	if uuid := supersql.Extension("uuid-ossp").SCHEMA("Shared"); uuid.CREATE() != `CREATE EXTENSION IF NOT EXISTS "uuid-ossp" WITH SCHEMA "Shared"` {
+		t.Fatal(uuid.CREATE())
+	}
+}
@@ -0,0 +1,489 @@
+package supersql
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/jackc/pgconn"
+)
+
+//Malformed DDL found by ValidateDDL or VALIDATE. Positions count characters from 1 like the
+//positions reported by postgres.
+type DDLError struct {
+	//Statement of the DDL the error is in counting from 1
+	Statement int
+	//Character of the DDL the error starts at and its line and column
+	Position int
+	Line     int
+	Column   int
+	Message  string
+	//How the statement might be fixed, empty when there is no suggestion
+	Hint string
+}
+
+func (e *DDLError) Error() string {
+	msg := fmt.Sprintf("supersql: ddl statement %d at line %d column %d: %s", e.Statement, e.Line, e.Column, e.Message)
+	if e.Hint != "" {
+		msg = fmt.Sprintf("%s (%s)", msg, e.Hint)
+	}
+	return msg
+}
+
+type tokenKind int
+
+const (
+	wordToken tokenKind = iota
+	quotedToken
+	literalToken
+	numberToken
+	symbolToken
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	start int
+}
+
+//Keyword value of word tokens, empty for every other token
+func (t token) keyword() string {
+	if t.kind != wordToken {
+		return ""
+	}
+	return strings.ToUpper(t.text)
+}
+
+type statement struct {
+	index  int
+	start  int
+	end    int
+	tokens []token
+}
+
+type ddlChecker struct {
+	ddl []rune
+}
+
+func (c *ddlChecker) fail(index, position int, hint, message string, args ...interface{}) *DDLError {
+	line, column := 1, 1
+	for _, r := range c.ddl[:position] {
+		if r == '\n' {
+			line, column = line+1, 1
+		} else {
+			column++
+		}
+	}
+	return &DDLError{
+		Statement: index, Position: position + 1, Line: line, Column: column,
+		Message: fmt.Sprintf(message, args...), Hint: hint,
+	}
+}
+
+//Split the DDL into the tokens of its statements. Comments and whitespace are dropped and literals,
+//quoted identifiers and dollar quoted bodies become single tokens.
+func (c *ddlChecker) statements() ([]statement, *DDLError) {
+	ddl := c.ddl
+	statements := []statement{}
+	current := statement{index: 1}
+	until := func(i int, closing string, escape bool) int {
+		delimiter := []rune(closing)
+		for j := i; j+len(delimiter) <= len(ddl); j++ {
+			if escape && ddl[j] == '\\' {
+				j++
+				continue
+			}
+			if string(ddl[j:j+len(delimiter)]) != closing {
+				continue
+			}
+			//doubled quotes are escaped quotes
+			if len(delimiter) == 1 && j+1 < len(ddl) && ddl[j+1] == delimiter[0] {
+				j++
+				continue
+			}
+			return j + len(delimiter)
+		}
+		return -1
+	}
+
+	for i := 0; i < len(ddl); {
+		r := ddl[i]
+		var next rune
+		if i+1 < len(ddl) {
+			next = ddl[i+1]
+		}
+		emit := func(kind tokenKind, end int) {
+			if len(current.tokens) == 0 {
+				current.start = i
+			}
+			current.tokens = append(current.tokens, token{kind: kind, text: string(ddl[i:end]), start: i})
+			current.end = end
+			i = end
+		}
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '-' && next == '-':
+			for i < len(ddl) && ddl[i] != '\n' {
+				i++
+			}
+		case r == '/' && next == '*':
+			depth, j := 0, i
+			for ; j < len(ddl); j++ {
+				if ddl[j] == '/' && j+1 < len(ddl) && ddl[j+1] == '*' {
+					depth, j = depth+1, j+1
+				} else if ddl[j] == '*' && j+1 < len(ddl) && ddl[j+1] == '/' {
+					depth, j = depth-1, j+1
+					if depth == 0 {
+						break
+					}
+				}
+			}
+			if depth > 0 {
+				return nil, c.fail(current.index, i, "close it with */", "unterminated comment")
+			}
+			i = j + 1
+		case (r == 'E' || r == 'e') && next == '\'':
+			end := until(i+2, "'", true)
+			if end < 0 {
+				return nil, c.fail(current.index, i, "close it with '", "unterminated string literal")
+			}
+			emit(literalToken, end)
+		case r == '\'' || r == '"':
+			end := until(i+1, string(r), false)
+			if end < 0 {
+				kind := "string literal"
+				if r == '"' {
+					kind = "quoted identifier"
+				}
+				return nil, c.fail(current.index, i, fmt.Sprintf("close it with %c", r), "unterminated %s", kind)
+			}
+			kind := literalToken
+			if r == '"' {
+				kind = quotedToken
+			}
+			emit(kind, end)
+		case r == '$' && !unicode.IsDigit(next):
+			tag := i + 1
+			for tag < len(ddl) && (ddl[tag] == '_' || unicode.IsLetter(ddl[tag]) || unicode.IsDigit(ddl[tag])) {
+				tag++
+			}
+			if tag == len(ddl) || ddl[tag] != '$' {
+				emit(symbolToken, i+1)
+				continue
+			}
+			delimiter := string(ddl[i : tag+1])
+			end := until(tag+1, delimiter, false)
+			if end < 0 {
+				return nil, c.fail(current.index, i, "close it with "+delimiter, "unterminated dollar quoted string")
+			}
+			emit(literalToken, end)
+		case r == '_' || unicode.IsLetter(r):
+			end := i + 1
+			for end < len(ddl) && (ddl[end] == '_' || ddl[end] == '$' || unicode.IsLetter(ddl[end]) || unicode.IsDigit(ddl[end])) {
+				end++
+			}
+			emit(wordToken, end)
+		case unicode.IsDigit(r):
+			end := i + 1
+			for end < len(ddl) && (unicode.IsDigit(ddl[end]) || ddl[end] == '.') {
+				end++
+			}
+			emit(numberToken, end)
+		case r == ';':
+			if len(current.tokens) > 0 {
+				statements = append(statements, current)
+			}
+			current = statement{index: len(statements) + 1}
+			i++
+		default:
+			emit(symbolToken, i+1)
+		}
+	}
+	if len(current.tokens) > 0 {
+		statements = append(statements, current)
+	}
+	return statements, nil
+}
+
+var (
+	//First words of the statements DDL can hold
+	verbs = []string{
+		"ALTER", "ANALYZE", "BEGIN", "CALL", "CLUSTER", "COMMENT", "COMMIT", "COPY", "CREATE", "DELETE", "DO",
+		"DROP", "END", "GRANT", "IMPORT", "INSERT", "LOCK", "REASSIGN", "REFRESH", "REINDEX", "RESET",
+		"REVOKE", "ROLLBACK", "SAVEPOINT", "SECURITY", "SELECT", "SET", "START", "TRUNCATE", "UPDATE",
+		"VACUUM", "VALUES", "WITH",
+	}
+	//Words between CREATE, ALTER or DROP and the kind of object
+	modifiers = map[string]bool{
+		"OR": true, "REPLACE": true, "TEMP": true, "TEMPORARY": true, "UNLOGGED": true, "GLOBAL": true,
+		"LOCAL": true, "UNIQUE": true, "MATERIALIZED": true, "RECURSIVE": true, "TRUSTED": true,
+		"PROCEDURAL": true, "CONSTRAINT": true,
+	}
+	objects = []string{
+		"ACCESS", "AGGREGATE", "CAST", "COLLATION", "CONVERSION", "DATABASE", "DEFAULT", "DOMAIN", "EVENT",
+		"EXTENSION", "FOREIGN", "FUNCTION", "GROUP", "INDEX", "LANGUAGE", "LARGE", "OPERATOR", "OWNED",
+		"POLICY", "PROCEDURE", "PUBLICATION", "ROLE", "ROUTINE", "RULE", "SCHEMA", "SEQUENCE", "SERVER",
+		"STATISTICS", "SUBSCRIPTION", "SYSTEM", "TABLE", "TABLESPACE", "TEXT", "TRANSFORM", "TRIGGER", "TYPE",
+		"USER", "VIEW",
+	}
+	//Words starting a table constraint in the element list of CREATE TABLE
+	tableConstraints = map[string]bool{
+		"CONSTRAINT": true, "PRIMARY": true, "UNIQUE": true, "CHECK": true, "FOREIGN": true, "EXCLUDE": true,
+		"LIKE": true,
+	}
+)
+
+//Check DDL for the mistakes that can be found without a server: unterminated literals, quoted
+//identifiers and comments, unbalanced parentheses, unknown statements and object types, a missing
+//semicolon between statements and malformed column lists of CREATE TABLE. Statements are not
+//checked against the postgres grammar, use VALIDATE for that.
+func ValidateDDL(ddl string) error {
+	c := &ddlChecker{ddl: []rune(ddl)}
+	statements, err := c.statements()
+	if err != nil {
+		return err
+	}
+	for _, s := range statements {
+		if err := c.check(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *ddlChecker) check(s statement) *DDLError {
+	//parentheses
+	open := []token{}
+	for _, t := range s.tokens {
+		switch {
+		case t.kind == symbolToken && t.text == "(":
+			open = append(open, t)
+		case t.kind == symbolToken && t.text == ")" && len(open) == 0:
+			return c.fail(s.index, t.start, "remove it or add the missing (", "unexpected )")
+		case t.kind == symbolToken && t.text == ")":
+			open = open[:len(open)-1]
+		}
+	}
+	if len(open) > 0 {
+		return c.fail(s.index, open[len(open)-1].start, "add the missing )", "unclosed (")
+	}
+
+	first := s.tokens[0]
+	verb := first.keyword()
+	if !contains(verbs, verb) {
+		return c.fail(s.index, first.start, suggest(verb, verbs), "unknown statement %s", first.text)
+	}
+	if verb != "CREATE" && verb != "ALTER" && verb != "DROP" {
+		return nil
+	}
+
+	i := 1
+	for i < len(s.tokens) && modifiers[s.tokens[i].keyword()] {
+		i++
+	}
+	if i == len(s.tokens) {
+		return c.fail(s.index, s.tokens[i-1].start, "name the kind of object i.e. TABLE", "incomplete %s statement", verb)
+	}
+	object := s.tokens[i].keyword()
+	if !contains(objects, object) {
+		return c.fail(s.index, s.tokens[i].start, suggest(object, objects), "unknown object type %s after %s", s.tokens[i].text, verb)
+	}
+
+	//CREATE SCHEMA can hold CREATE statements for the objects in it and ALTER DEFAULT PRIVILEGES
+	//grants CREATE
+	if !(verb == "CREATE" && object == "SCHEMA") && object != "DEFAULT" {
+		depth := 0
+		for _, t := range s.tokens[i:] {
+			switch {
+			case t.kind == symbolToken && t.text == "(":
+				depth++
+			case t.kind == symbolToken && t.text == ")":
+				depth--
+			case depth == 0 && t.keyword() == "CREATE":
+				return c.fail(s.index, t.start, "end the previous statement with ;", "missing ; before CREATE")
+			}
+		}
+	}
+	if verb == "CREATE" && object == "TABLE" {
+		return c.columns(s, i+1)
+	}
+	return nil
+}
+
+//Check the element list of CREATE TABLE starting after the TABLE keyword at i
+func (c *ddlChecker) columns(s statement, i int) *DDLError {
+	tokens := s.tokens
+	if i+2 < len(tokens) && tokens[i].keyword() == "IF" && tokens[i+1].keyword() == "NOT" && tokens[i+2].keyword() == "EXISTS" {
+		i += 3
+	}
+	if i == len(tokens) || (tokens[i].kind != wordToken && tokens[i].kind != quotedToken) {
+		at := tokens[len(tokens)-1].start
+		if i < len(tokens) {
+			at = tokens[i].start
+		}
+		return c.fail(s.index, at, "name the table", "CREATE TABLE without a table name")
+	}
+	//schema qualified names
+	for i+2 < len(tokens) && tokens[i+1].text == "." {
+		i += 2
+	}
+	i++
+	if i == len(tokens) {
+		return c.fail(s.index, tokens[i-1].start, "declare the columns i.e. (id bigint PRIMARY KEY)", "CREATE TABLE without columns")
+	}
+	if tokens[i].text != "(" {
+		return nil
+	}
+
+	element := []token{}
+	depth := 0
+	for _, t := range tokens[i+1:] {
+		if t.kind == symbolToken && t.text == "(" {
+			depth++
+		}
+		if t.kind == symbolToken && t.text == ")" {
+			if depth == 0 {
+				if len(element) == 0 && t.start != tokens[i+1].start {
+					return c.fail(s.index, t.start, "remove the , before )", "unexpected ) after ,")
+				}
+				return c.element(s, element)
+			}
+			depth--
+		}
+		if depth == 0 && t.kind == symbolToken && t.text == "," {
+			if len(element) == 0 {
+				return c.fail(s.index, t.start, "remove the extra ,", "empty column definition")
+			}
+			if err := c.element(s, element); err != nil {
+				return err
+			}
+			element = element[:0]
+			continue
+		}
+		element = append(element, t)
+	}
+	return nil
+}
+
+func (c *ddlChecker) element(s statement, element []token) *DDLError {
+	if len(element) == 0 || tableConstraints[element[0].keyword()] {
+		return nil
+	}
+	name := element[0]
+	if name.kind != wordToken && name.kind != quotedToken {
+		return c.fail(s.index, name.start, "start column definitions with the column name", "unexpected %s in column list", name.text)
+	}
+	if len(element) == 1 || (element[1].kind != wordToken && element[1].kind != quotedToken) {
+		return c.fail(s.index, name.start, fmt.Sprintf("declare a type i.e. %s text", name.text), "column %s has no type", name.text)
+	}
+	return nil
+}
+
+//Hint naming the candidate closest to word when it looks like a typo of it
+func suggest(word string, candidates []string) string {
+	best, distance := "", 3
+	for _, candidate := range candidates {
+		if d := levenshtein(word, candidate); d < distance {
+			best, distance = candidate, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf("did you mean %s?", best)
+}
+
+func levenshtein(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = smallest(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+func smallest(values ...int) int {
+	least := values[0]
+	for _, v := range values[1:] {
+		if v < least {
+			least = v
+		}
+	}
+	return least
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+//Statements postgres refuses to run inside a transaction block
+func transactional(s statement) bool {
+	words := []string{}
+	for _, t := range s.tokens {
+		words = append(words, t.keyword())
+	}
+	joined := strings.Join(words, " ")
+	for _, prefix := range []string{"CREATE DATABASE", "DROP DATABASE", "CREATE TABLESPACE", "DROP TABLESPACE", "ALTER SYSTEM", "VACUUM", "REINDEX"} {
+		if strings.HasPrefix(joined, prefix) {
+			return false
+		}
+	}
+	return !strings.Contains(joined, " CONCURRENTLY")
+}
+
+//Errors returned by TRANSACTION callbacks to roll the transaction back
+var errRollback = errors.New("supersql: rolled back")
+
+//Validate DDL with ValidateDDL and then by running its statements against the server in a
+//transaction that is always rolled back, catching everything postgres would reject i.e. syntax
+//errors, unknown types and references to missing tables. Statements that can not run in a
+//transaction such as CREATE INDEX CONCURRENTLY are only checked with ValidateDDL.
+func (q *SqlQuery) VALIDATE(ddl string) error {
+	if err := ValidateDDL(ddl); err != nil {
+		return err
+	}
+	c := &ddlChecker{ddl: []rune(ddl)}
+	statements, _ := c.statements()
+	err := q.TRANSACTION(func(tx *SqlQuery) error {
+		for _, s := range statements {
+			if !transactional(s) {
+				continue
+			}
+			if _, err := tx.RUN(string(c.ddl[s.start:s.end])).GO(); err != nil {
+				var pgerr *pgconn.PgError
+				if !errors.As(err, &pgerr) {
+					return err
+				}
+				position := s.start
+				if pgerr.Position > 0 {
+					position += int(pgerr.Position) - 1
+				}
+				return c.fail(s.index, position, pgerr.Hint, "%s", pgerr.Message)
+			}
+		}
+		return errRollback
+	})
+	if errors.Is(err, errRollback) {
+		return nil
+	}
+	return err
+}
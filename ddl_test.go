@@ -0,0 +1,133 @@
+package supersql_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgconn"
+	"github.com/rayattack/supersql"
+	"github.com/rayattack/supersql/supersqltest"
+)
+
+func ddlError(t *testing.T, err error) *supersql.DDLError {
+	t.Helper()
+	var derr *supersql.DDLError
+	if !errors.As(err, &derr) {
+		t.Fatalf("%v is not a *DDLError", err)
+	}
+	return derr
+}
+
+func TestValidateDDLAcceptsValidDDL(t *testing.T) {
+	valid := []string{
+		"CREATE TABLE film (film_id integer PRIMARY KEY, title varchar(255) NOT NULL, CONSTRAINT title_key UNIQUE (title))",
+		"CREATE TABLE IF NOT EXISTS public.\"Film\" (\"Title\" text, rating numeric(4, 2) DEFAULT 4.99); CREATE INDEX film_title ON film (title);",
+		"CREATE TABLE empty (); CREATE TABLE copy AS SELECT * FROM film; CREATE TABLE events_2024 PARTITION OF events FOR VALUES FROM ('2024-01-01') TO ('2025-01-01')",
+		"-- films\nCREATE TABLE film (title text /* the (title */, note text DEFAULT 'it''s; fine', raw text DEFAULT E'\\'')",
+		"CREATE OR REPLACE FUNCTION touch() RETURNS trigger AS $body$ BEGIN NEW.updated = now(); CREATE; RETURN NEW; END $body$ LANGUAGE plpgsql",
+		"CREATE UNIQUE INDEX CONCURRENTLY film_title ON film (lower(title)); ALTER TABLE film ADD COLUMN length smallint; DROP MATERIALIZED VIEW IF EXISTS sales",
+		"CREATE SCHEMA billing CREATE TABLE invoice (id bigint) CREATE VIEW open AS SELECT * FROM invoice",
+		"ALTER DEFAULT PRIVILEGES IN SCHEMA billing GRANT CREATE ON SCHEMAS TO app; GRANT CREATE ON SCHEMA billing TO app",
+		"INSERT INTO language (name) VALUES ('English');;",
+		"",
+	}
+	for _, ddl := range valid {
+		if err := supersql.ValidateDDL(ddl); err != nil {
+			t.Errorf("%s: %v", ddl, err)
+		}
+	}
+}
+
+func TestValidateDDLFindsMistakes(t *testing.T) {
+	cases := []struct {
+		ddl       string
+		statement int
+		line      int
+		column    int
+		message   string
+		hint      string
+	}{
+		{"CRATE TABLE film (title text)", 1, 1, 1, "unknown statement CRATE", "did you mean CREATE?"},
+		{"CREATE TABEL film (title text)", 1, 1, 8, "unknown object type TABEL after CREATE", "did you mean TABLE?"},
+		{"CREATE TABLE a (id int);\nCREATE TABLE film (title text", 2, 2, 19, "unclosed (", "add the missing )"},
+		{"CREATE TABLE film (title text))", 1, 1, 31, "unexpected )", "remove it or add the missing ("},
+		{"CREATE TABLE film (title text,)", 1, 1, 31, "unexpected ) after ,", "remove the , before )"},
+		{"CREATE TABLE film (id int,, title text)", 1, 1, 27, "empty column definition", "remove the extra ,"},
+		{"CREATE TABLE film (id int, title)", 1, 1, 28, "column title has no type", "declare a type i.e. title text"},
+		{"CREATE TABLE film (id int)\nCREATE TABLE actor (id int)", 1, 2, 1, "missing ; before CREATE", "end the previous statement with ;"},
+		{"CREATE TABLE film (title text DEFAULT 'untitled)", 1, 1, 39, "unterminated string literal", "close it with '"},
+		{"CREATE TABLE \"film (title text)", 1, 1, 14, "unterminated quoted identifier", "close it with \""},
+		{"CREATE FUNCTION f() AS $$ SELECT 1", 1, 1, 24, "unterminated dollar quoted string", "close it with $$"},
+		{"CREATE TABLE film (id int) /* comment", 1, 1, 28, "unterminated comment", "close it with */"},
+		{"CREATE TABLE (id int)", 1, 1, 14, "CREATE TABLE without a table name", "name the table"},
+		{"CREATE", 1, 1, 1, "incomplete CREATE statement", "name the kind of object i.e. TABLE"},
+		{"SELECT 1; FROBNICATE", 2, 1, 11, "unknown statement FROBNICATE", ""},
+	}
+	for _, c := range cases {
+		err := ddlError(t, supersql.ValidateDDL(c.ddl))
+		if err.Statement != c.statement || err.Line != c.line || err.Column != c.column || err.Message != c.message || err.Hint != c.hint {
+			t.Errorf("%s: %+v", c.ddl, *err)
+		}
+	}
+
+	err := ddlError(t, supersql.ValidateDDL("CREATE TABLE é (x int);\nCRATE TABLE b (id int)"))
+	if err.Position != 25 || err.Error() != "supersql: ddl statement 2 at line 2 column 1: unknown statement CRATE (did you mean CREATE?)" {
+		t.Fatal(err.Position, err.Error())
+	}
+}
+
+func TestTableDDLIsValidated(t *testing.T) {
+	film := supersql.Table("film")
+	if err := film.DDL("CREATE TABLE film (film_id int PRIMARY KEY)"); err != nil || film.GO() != "CREATE TABLE film (film_id int PRIMARY KEY)" {
+		t.Fatal(err, film.GO())
+	}
+	if err := film.DDL("CREATE TABLE film (film_id int PRIMARY KEY"); err == nil {
+		t.Fatal("malformed ddl should be rejected")
+	}
+	if film.GO() != "CREATE TABLE film (film_id int PRIMARY KEY)" {
+		t.Fatal("malformed ddl should not be registered", film.GO())
+	}
+}
+
+func TestVALIDATE(t *testing.T) {
+	fake := supersqltest.New()
+	root := fake.Root()
+	ddl := "CREATE TABLE film (id int);\nCREATE INDEX CONCURRENTLY film_id ON film (id);\nCREATE TABLE rental (film_id int REFERENCES flim)"
+	fake.Expect("CREATE TABLE rental").Fails(&pgconn.PgError{Message: `relation "flim" does not exist`, Position: 45, Hint: "check the table name"})
+
+	err := ddlError(t, root.VALIDATE(ddl))
+	if err.Statement != 3 || err.Line != 3 || err.Column != 45 || err.Message != `relation "flim" does not exist` || err.Hint != "check the table name" {
+		t.Fatalf("%+v", *err)
+	}
+	calls := []string{}
+	for _, call := range fake.Calls() {
+		calls = append(calls, call.SQL)
+	}
+	want := "BEGIN|CREATE TABLE film (id int)|CREATE TABLE rental (film_id int REFERENCES flim)|ROLLBACK"
+	if strings.Join(calls, "|") != want {
+		t.Fatal(strings.Join(calls, "|"))
+	}
+
+	//valid ddl is rolled back too
+	fake.Reset()
+	if err := root.VALIDATE("CREATE TABLE film (id int)"); err != nil {
+		t.Fatal(err)
+	}
+	if calls := fake.Calls(); calls[len(calls)-1].SQL != "ROLLBACK" {
+		t.Fatal(calls)
+	}
+
+	//local mistakes never reach the server
+	fake.Reset()
+	if err := root.VALIDATE("CREATE TABLE film (id int"); err == nil || len(fake.Calls()) != 0 {
+		t.Fatal(err, fake.Calls())
+	}
+
+	//errors that are not from postgres are passed on
+	fake.Reset()
+	fake.Expect("CREATE TABLE").Fails(errors.New("connection reset"))
+	if err := root.VALIDATE("CREATE TABLE film (id int)"); err == nil || err.Error() != "connection reset" {
+		t.Fatal(err)
+	}
+}
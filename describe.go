@@ -0,0 +1,27 @@
+package supersql
+
+import (
+	"fmt"
+)
+
+//Parameter and result column types the server infers for a statement. Types are named the way
+//format_type(...) names them i.e. integer, character varying or text[].
+type Statement struct {
+	Params  []string
+	Columns []StatementColumn
+}
+
+type StatementColumn struct {
+	Name string
+	Type string
+}
+
+//Ask the server for the types of the parameters and result columns of ssql without running it.
+//Placeholders are written as ? like everywhere else. Only the pgx backend can describe statements.
+func (q *SqlQuery) DESCRIBE(ssql string) (*Statement, error) {
+	d, ok := q.exec.(describer)
+	if !ok {
+		return nil, fmt.Errorf("supersql: %T can not describe statements", q.exec)
+	}
+	return d.Describe(q.context(), replacePlaceholders(ssql, q.dialectOf()))
+}
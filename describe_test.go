@@ -0,0 +1,56 @@
+package supersql_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rayattack/supersql"
+	"github.com/rayattack/supersql/supersqltest"
+)
+
+type describing struct {
+	*supersqltest.Fake
+	described string
+}
+
+func (d *describing) Describe(ctx context.Context, ssql string) (*supersql.Statement, error) {
+	d.described = ssql
+	return &supersql.Statement{Params: []string{"integer"}, Columns: []supersql.StatementColumn{{Name: "title", Type: "text"}}}, nil
+}
+
+func TestDescribe(t *testing.T) {
+	executor := &describing{Fake: supersqltest.New()}
+	q := supersql.QueryWith(context.Background(), executor, supersql.Postgres)
+	statement, err := q.DESCRIBE("SELECT title FROM film WHERE film_id = ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if executor.described != "SELECT title FROM film WHERE film_id = $1" || statement.Columns[0].Name != "title" {
+		t.Fatal(executor.described, statement)
+	}
+	if len(executor.Calls()) != 0 {
+		t.Fatal("describing a statement must not run it")
+	}
+
+	if _, err := supersqltest.New().Root().DESCRIBE("SELECT 1"); err == nil {
+		t.Fail()
+	}
+}
+
+func TestRaw(t *testing.T) {
+	fake := supersqltest.New()
+	fake.Expect("WITH recent").Returns([]string{"title"}, []interface{}{"Chamber Italian"})
+
+	ssql := "WITH recent AS (SELECT * FROM rental WHERE rental_date > ?) SELECT title FROM recent JOIN film USING (film_id) LIMIT ?"
+	r, err := fake.Root().RAW(ssql, "2005-05-25", 10).GO()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Count() != 1 {
+		t.Fail()
+	}
+	call := fake.Calls()[0]
+	if call.Exec || call.SQL != "WITH recent AS (SELECT * FROM rental WHERE rental_date > $1) SELECT title FROM recent JOIN film USING (film_id) LIMIT $2" || len(call.Args) != 2 {
+		t.Fatal(call)
+	}
+}
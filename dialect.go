@@ -0,0 +1,194 @@
+package supersql
+
+import (
+	"fmt"
+	"strings"
+)
+
+//Dialect captures the parts of SQL that differ between database engines. Commands are always
+//expantiated with ? placeholders and the dialect of the query root decides how they, identifiers,
+//LIMIT/OFFSET, RETURNING, upserts and record locks are rendered before the statement leaves supersql.
+type Dialect interface {
+	Name() string
+	Placeholder(position int) string
+	Quote(identifier string) string
+	Limit(count int) string
+	Offset(count int, limited bool) string
+	Returning(columns []string) (string, error)
+	Upsert(conflict []string, update []string) (string, error)
+	Lock(strength string, tables []string) (string, error)
+}
+
+var (
+	Postgres Dialect = postgres{}
+	SQLite   Dialect = sqlite{}
+	MySQL    Dialect = mysql{}
+)
+
+type postgres struct{}
+
+func (postgres) Name() string {
+	return "postgres"
+}
+
+//Postgres parameters are positional i.e. $1, $2 ... and not ? like the rest
+func (postgres) Placeholder(position int) string {
+	return fmt.Sprintf("$%d", position)
+}
+
+func (postgres) Quote(identifier string) string {
+	return quoteWith(identifier, `"`)
+}
+
+func (postgres) Limit(count int) string {
+	return fmt.Sprintf("LIMIT %d", count)
+}
+
+func (postgres) Offset(count int, limited bool) string {
+	return fmt.Sprintf("OFFSET %d", count)
+}
+
+func (postgres) Returning(columns []string) (string, error) {
+	return returning(columns), nil
+}
+
+func (postgres) Upsert(conflict []string, update []string) (string, error) {
+	return onConflict(conflict, update, "EXCLUDED")
+}
+
+func (postgres) Lock(strength string, tables []string) (string, error) {
+	return lock(strength, tables), nil
+}
+
+type sqlite struct{}
+
+func (sqlite) Name() string {
+	return "sqlite"
+}
+
+func (sqlite) Placeholder(position int) string {
+	return "?"
+}
+
+func (sqlite) Quote(identifier string) string {
+	return quoteWith(identifier, `"`)
+}
+
+func (sqlite) Limit(count int) string {
+	return fmt.Sprintf("LIMIT %d", count)
+}
+
+//SQLite only understands OFFSET as part of a LIMIT clause, a negative limit means no limit at all
+func (sqlite) Offset(count int, limited bool) string {
+	if limited {
+		return fmt.Sprintf("OFFSET %d", count)
+	}
+	return fmt.Sprintf("LIMIT -1 OFFSET %d", count)
+}
+
+//RETURNING is available from SQLite 3.35 onwards
+func (sqlite) Returning(columns []string) (string, error) {
+	return returning(columns), nil
+}
+
+func (sqlite) Upsert(conflict []string, update []string) (string, error) {
+	return onConflict(conflict, update, "excluded")
+}
+
+//SQLite locks the whole database file for writing so there are no record locks to ask for
+func (sqlite) Lock(strength string, tables []string) (string, error) {
+	return "", fmt.Errorf("sqlite does not support FOR %s", strength)
+}
+
+type mysql struct{}
+
+func (mysql) Name() string {
+	return "mysql"
+}
+
+func (mysql) Placeholder(position int) string {
+	return "?"
+}
+
+func (mysql) Quote(identifier string) string {
+	return quoteWith(identifier, "`")
+}
+
+func (mysql) Limit(count int) string {
+	return fmt.Sprintf("LIMIT %d", count)
+}
+
+//MySQL has no way of saying OFFSET without LIMIT so the documented workaround of the largest
+//unsigned bigint is used instead
+func (mysql) Offset(count int, limited bool) string {
+	if limited {
+		return fmt.Sprintf("OFFSET %d", count)
+	}
+	return fmt.Sprintf("LIMIT 18446744073709551615 OFFSET %d", count)
+}
+
+func (mysql) Returning(columns []string) (string, error) {
+	return "", fmt.Errorf("mysql does not support RETURNING")
+}
+
+//MySQL resolves conflicts against every unique key on the table so the conflict target is not
+//rendered. A no-op update of the first conflict column is the idiomatic DO NOTHING.
+func (mysql) Upsert(conflict []string, update []string) (string, error) {
+	sets := []string{}
+	for _, col := range update {
+		sets = append(sets, fmt.Sprintf("%s = VALUES(%s)", col, col))
+	}
+	if len(sets) == 0 {
+		if len(conflict) == 0 {
+			return "", fmt.Errorf("mysql needs a conflict column to ignore duplicates")
+		}
+		sets = append(sets, fmt.Sprintf("%s = %s", conflict[0], conflict[0]))
+	}
+	return fmt.Sprintf("ON DUPLICATE KEY UPDATE %s", strings.Join(sets, ", ")), nil
+}
+
+//MySQL 8 has FOR UPDATE and FOR SHARE with OF, NOWAIT and SKIP LOCKED but not the key variants
+func (mysql) Lock(strength string, tables []string) (string, error) {
+	if strength != "UPDATE" && strength != "SHARE" {
+		return "", fmt.Errorf("mysql does not support FOR %s", strength)
+	}
+	return lock(strength, tables), nil
+}
+
+func quoteWith(identifier string, mark string) string {
+	return mark + strings.ReplaceAll(identifier, mark, mark+mark) + mark
+}
+
+func returning(columns []string) string {
+	if len(columns) == 0 {
+		columns = []string{"*"}
+	}
+	return fmt.Sprintf("RETURNING %s", strings.Join(columns, ", "))
+}
+
+//Shared ON CONFLICT renderer for postgres and sqlite which only differ by the case of the
+//excluded pseudo table in their documentation
+func onConflict(conflict []string, update []string, excluded string) (string, error) {
+	target := ""
+	if len(conflict) > 0 {
+		target = fmt.Sprintf(" (%s)", strings.Join(conflict, ", "))
+	}
+	if len(update) == 0 {
+		return fmt.Sprintf("ON CONFLICT%s DO NOTHING", target), nil
+	}
+	if target == "" {
+		return "", fmt.Errorf("ON CONFLICT DO UPDATE requires conflict columns")
+	}
+	sets := []string{}
+	for _, col := range update {
+		sets = append(sets, fmt.Sprintf("%s = %s.%s", col, excluded, col))
+	}
+	return fmt.Sprintf("ON CONFLICT%s DO UPDATE SET %s", target, strings.Join(sets, ", ")), nil
+}
+
+func lock(strength string, tables []string) string {
+	if len(tables) == 0 {
+		return fmt.Sprintf("FOR %s", strength)
+	}
+	return fmt.Sprintf("FOR %s OF %s", strength, strings.Join(tables, ", "))
+}
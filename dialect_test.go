@@ -0,0 +1,144 @@
+package supersql_test
+
+import (
+	"testing"
+
+	"github.com/rayattack/supersql"
+)
+
+func dialectRoot(d supersql.Dialect) *supersql.SqlQuery {
+	return new(supersql.SqlQuery).WithDialect(d)
+}
+
+func TestDialectPlaceholders(t *testing.T) {
+	expectations := map[supersql.Dialect]string{
+		supersql.Postgres: "SELECT title FROM film WHERE film_id = $1 AND rating = $2",
+		supersql.SQLite:   "SELECT title FROM film WHERE film_id = ? AND rating = ?",
+		supersql.MySQL:    "SELECT title FROM film WHERE film_id = ? AND rating = ?",
+	}
+	for dialect, expected := range expectations {
+		q := dialectRoot(dialect).SELECT("title").FROM("film").WHERE("film_id = ? AND rating = ?", 133, "PG")
+		ssql, args, err := q.SQL()
+		if err != nil || ssql != expected || len(args) != 2 {
+			t.Logf("%s: %s %v %v", dialect.Name(), ssql, args, err)
+			t.Fail()
+		}
+	}
+}
+
+func TestDialectOffsetWithoutLimit(t *testing.T) {
+	expectations := map[supersql.Dialect]string{
+		supersql.Postgres: "SELECT * FROM actor OFFSET 4",
+		supersql.SQLite:   "SELECT * FROM actor LIMIT -1 OFFSET 4",
+		supersql.MySQL:    "SELECT * FROM actor LIMIT 18446744073709551615 OFFSET 4",
+	}
+	for dialect, expected := range expectations {
+		q := dialectRoot(dialect).SELECT().FROM("actor").OFFSET(4)
+		if q.PP() != expected {
+			t.Logf("%s: %s", dialect.Name(), q.PP())
+			t.Fail()
+		}
+		q = dialectRoot(dialect).SELECT().FROM("actor").LIMIT(5).OFFSET(4)
+		if q.PP() != "SELECT * FROM actor LIMIT 5 OFFSET 4" {
+			t.Logf("%s: %s", dialect.Name(), q.PP())
+			t.Fail()
+		}
+	}
+}
+
+func TestDialectUpsert(t *testing.T) {
+	insert := func(d supersql.Dialect) supersql.Command {
+		return dialectRoot(d).INSERT_INTO("actor", []string{"actor_id", "name"}).VALUES([]interface{}{1, "ab"})
+	}
+	base := "INSERT INTO actor (actor_id, name) VALUES (?, ?)"
+
+	pg := insert(supersql.Postgres).ON_CONFLICT("actor_id").DO_UPDATE("name")
+	if pg.PP() != base+" ON CONFLICT (actor_id) DO UPDATE SET name = EXCLUDED.name" {
+		t.Log(pg.PP())
+		t.Fail()
+	}
+	lite := insert(supersql.SQLite).ON_CONFLICT("actor_id").DO_NOTHING()
+	if lite.PP() != base+" ON CONFLICT (actor_id) DO NOTHING" {
+		t.Log(lite.PP())
+		t.Fail()
+	}
+	my := insert(supersql.MySQL).ON_CONFLICT("actor_id").DO_UPDATE("name")
+	if my.PP() != base+" ON DUPLICATE KEY UPDATE name = VALUES(name)" {
+		t.Log(my.PP())
+		t.Fail()
+	}
+
+	_, _, err := insert(supersql.Postgres).ON_CONFLICT().DO_UPDATE("name").SQL()
+	if err == nil {
+		t.Fail()
+	}
+}
+
+func TestDialectReturning(t *testing.T) {
+	q := dialectRoot(supersql.Postgres).INSERT_INTO("actor", []string{"name"}).VALUES([]interface{}{"ab"}).RETURNING("actor_id")
+	if q.PP() != "INSERT INTO actor (name) VALUES (?) RETURNING actor_id" {
+		t.Log(q.PP())
+		t.Fail()
+	}
+
+	_, err := dialectRoot(supersql.MySQL).INSERT_INTO("actor", []string{"name"}).RETURNING().GO()
+	if err == nil {
+		t.Fail()
+	}
+}
+
+func TestDialectQuote(t *testing.T) {
+	if supersql.Postgres.Quote(`we"ird`) != `"we""ird"` {
+		t.Fail()
+	}
+	if supersql.MySQL.Quote("order") != "`order`" {
+		t.Fail()
+	}
+}
+
+func TestTableDialect(t *testing.T) {
+	order := supersql.Table("Order", supersql.Integer("id", supersql.PRIMARY_KEY), supersql.Integer("Total")).InDialect(supersql.MySQL)
+	if col := order.Col("id"); col != "`Order`.id" {
+		t.Fatal(col)
+	}
+	if ssql, _, _ := dialectRoot(supersql.MySQL).SELECT(order.Col("id")).FROM(order).SQL(); ssql != "SELECT `Order`.id FROM `Order`" {
+		t.Fatal(ssql)
+	}
+	if ddl := order.CREATE(); ddl != "CREATE TABLE `Order` (id integer PRIMARY KEY, `Total` integer)" {
+		t.Fatal(ddl)
+	}
+	if ref := supersql.REFERENCES(order, "id").String(); ref != "REFERENCES `Order` (id)" {
+		t.Fatal(ref)
+	}
+	if idx := supersql.Index("Order_Total", order, "Total").CREATE(); idx != "CREATE INDEX `Order_Total` ON `Order` (Total)" {
+		t.Fatal(idx)
+	}
+	if grant := supersql.GRANT(supersql.SELECT).ON_TABLE(order).TO("app").String(); grant != "GRANT SELECT ON TABLE `Order` TO app" {
+		t.Fatal(grant)
+	}
+	//tables render for postgres unless told otherwise
+	if col := supersql.Table("Order").Col("id"); col != `"Order".id` {
+		t.Fatal(col)
+	}
+}
+
+func TestDialectLock(t *testing.T) {
+	f := film.AS("f")
+	q := dialectRoot(supersql.Postgres).SELECT("f.title").FROM(f).JOIN("language l").ON("l.language_id = f.language_id").FOR_NO_KEY_UPDATE(f).SKIP_LOCKED()
+	if q.PP() != "SELECT f.title FROM film f JOIN language l ON l.language_id = f.language_id FOR NO KEY UPDATE OF f SKIP LOCKED" {
+		t.Fatal(q.PP())
+	}
+	my := dialectRoot(supersql.MySQL).SELECT().FROM("actor").WHERE("actor_id = ?", 1).FOR_SHARE("actor").NOWAIT()
+	if my.PP() != "SELECT * FROM actor WHERE actor_id = 1 FOR SHARE OF actor NOWAIT" {
+		t.Fatal(my.PP())
+	}
+	if _, _, err := dialectRoot(supersql.MySQL).SELECT().FROM("actor").FOR_KEY_SHARE().SQL(); err == nil {
+		t.Fatal("mysql has no FOR KEY SHARE")
+	}
+	if _, _, err := dialectRoot(supersql.SQLite).SELECT().FROM("actor").FOR_UPDATE().SQL(); err == nil {
+		t.Fatal("sqlite has no record locks")
+	}
+	if _, _, err := dialectRoot(supersql.Postgres).SELECT().FROM("actor").SKIP_LOCKED().SQL(); err == nil {
+		t.Fatal("SKIP LOCKED needs a lock")
+	}
+}
@@ -0,0 +1,299 @@
+//Package diff compares supersql table declarations with the schema of a live database and produces
+//the DDL that brings the database in line with the declarations. Changes that can lose data are
+//flagged as destructive and left out of scripts unless explicitly allowed.
+//
+//	plan, err := diff.Compute(ctx, q, diff.Options{}, film, language)
+//	script, err := plan.Script(false) // ALTER TABLE film ADD COLUMN ...
+package diff
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rayattack/supersql"
+	"github.com/rayattack/supersql/introspect"
+)
+
+//Statements are ordered by phase so constraints are dropped before the columns they cover change
+//and foreign keys are added after the tables and columns they reference exist
+const (
+	dropForeignKeys = iota
+	dropConstraints
+	dropIndexes
+	createTables
+	addColumns
+	alterColumns
+	addConstraints
+	addForeignKeys
+	createIndexes
+	dropColumns
+	dropTables
+)
+
+//A single DDL statement of a plan
+type Change struct {
+	Table string
+	SQL   string
+	//The statement drops data or can lose information held by existing records
+	Destructive bool
+	phase       int
+}
+
+//Changes in the order they must be applied
+type Plan []Change
+
+//Secondary index the database should have. Method defaults to btree.
+type Index struct {
+	Table   string
+	Name    string
+	Columns []string
+	Unique  bool
+	Method  string
+	Where   string
+}
+
+func (i Index) CREATE() string {
+	ssql := "CREATE INDEX"
+	if i.Unique {
+		ssql = "CREATE UNIQUE INDEX"
+	}
+	ssql = fmt.Sprintf("%s %s ON %s", ssql, i.Name, i.Table)
+	if i.Method != "" && i.Method != "btree" {
+		ssql = fmt.Sprintf("%s USING %s", ssql, i.Method)
+	}
+	ssql = fmt.Sprintf("%s (%s)", ssql, strings.Join(i.Columns, ", "))
+	if i.Where != "" {
+		ssql = fmt.Sprintf("%s WHERE %s", ssql, i.Where)
+	}
+	return ssql
+}
+
+type Options struct {
+	//Schema the declared tables live in, public when empty
+	Schema string
+	//The declared tables are the whole schema, tables of the schema that were not declared are
+	//dropped. The migrate tracking table and tables in Ignore are always kept.
+	Prune  bool
+	Ignore []string
+	//Every secondary index the declared tables should have. Indexes are left alone when nil, when
+	//set indexes of declared tables that are not listed are dropped.
+	Indexes []Index
+}
+
+//Inspect the database behind q and compare it with the declared tables
+func Compute(ctx context.Context, q *supersql.SqlQuery, opts Options, tables ...*supersql.SqlTable) (Plan, error) {
+	actual, err := introspect.Inspect(ctx, q, opts.Schema)
+	if err != nil {
+		return nil, err
+	}
+	return Compare(actual, opts, tables...), nil
+}
+
+//Changes that turn the actual schema into the one described by tables
+func Compare(actual *introspect.Schema, opts Options, tables ...*supersql.SqlTable) Plan {
+	plan := Plan{}
+	declared := map[string]bool{}
+	missing := []*supersql.SqlTable{}
+	for _, t := range tables {
+		name := local(t.Name(), actual.Name)
+		declared[name] = true
+		if existing := actual.Table(name); existing != nil {
+			plan = append(plan, table(t, existing)...)
+		} else {
+			missing = append(missing, t)
+		}
+	}
+	for _, t := range referenced(missing) {
+		plan = append(plan, Change{Table: t.Name(), SQL: t.CREATE(), phase: createTables})
+	}
+
+	if opts.Indexes != nil {
+		plan = append(plan, indexes(actual, declared, opts.Indexes)...)
+	}
+	if opts.Prune {
+		ignored := map[string]bool{"supersql_migrations": true}
+		for _, name := range opts.Ignore {
+			ignored[local(name, actual.Name)] = true
+		}
+		for _, t := range actual.Tables {
+			if !declared[t.Name] && !ignored[t.Name] {
+				plan = append(plan, Change{Table: t.Name, SQL: t.SqlTable().DROP().String(), Destructive: true, phase: dropTables})
+			}
+		}
+	}
+
+	sort.SliceStable(plan, func(i, j int) bool { return plan[i].phase < plan[j].phase })
+	return plan
+}
+
+//The changes that need explicit opt-in
+func (p Plan) Destructive() Plan {
+	destructive := Plan{}
+	for _, c := range p {
+		if c.Destructive {
+			destructive = append(destructive, c)
+		}
+	}
+	return destructive
+}
+
+//The plan as an SQL script for review with destructive statements marked
+func (p Plan) String() string {
+	statements := []string{}
+	for _, c := range p {
+		if c.Destructive {
+			statements = append(statements, fmt.Sprintf("-- destructive\n%s;", c.SQL))
+		} else {
+			statements = append(statements, c.SQL+";")
+		}
+	}
+	return strings.Join(statements, "\n")
+}
+
+//The plan as an SQL script ready to be saved as a migration. Plans with destructive changes are
+//rejected unless allowDestructive is set.
+func (p Plan) Script(allowDestructive bool) (string, error) {
+	if err := p.allowed(allowDestructive); err != nil {
+		return "", err
+	}
+	statements := []string{}
+	for _, c := range p {
+		statements = append(statements, c.SQL+";")
+	}
+	return strings.Join(statements, "\n"), nil
+}
+
+//Apply every change in a single transaction
+func (p Plan) Apply(ctx context.Context, q *supersql.SqlQuery, allowDestructive bool) error {
+	if err := p.allowed(allowDestructive); err != nil {
+		return err
+	}
+	return q.WithContext(ctx).TRANSACTION(func(tx *supersql.SqlQuery) error {
+		for _, c := range p {
+			if _, err := tx.RUN(c.SQL).GO(); err != nil {
+				return fmt.Errorf("diff: %s: %w", c.SQL, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (p Plan) allowed(allowDestructive bool) error {
+	destructive := p.Destructive()
+	if allowDestructive || len(destructive) == 0 {
+		return nil
+	}
+	statements := []string{}
+	for _, c := range destructive {
+		statements = append(statements, c.SQL)
+	}
+	return fmt.Errorf("diff: %d destructive changes need to be allowed explicitly: %s", len(destructive), strings.Join(statements, "; "))
+}
+
+//Quoted name of an index or other object of schema. Indexes live in the schema of their table so
+//dropping one by its bare name misses it when that schema is not on the search path.
+func qualified(schema string, name string) string {
+	if schema == "" {
+		return supersql.QuoteIdentifier(name)
+	}
+	return supersql.QuoteIdentifier(schema) + "." + supersql.QuoteIdentifier(name)
+}
+
+//Name of a declared table without the schema prefix when it is the compared schema
+func local(name string, schema string) string {
+	return strings.TrimPrefix(name, schema+".")
+}
+
+//Order new tables so tables come after the tables they reference. Tables in a reference cycle keep
+//their declaration order.
+func referenced(tables []*supersql.SqlTable) []*supersql.SqlTable {
+	ordered := []*supersql.SqlTable{}
+	placed := map[*supersql.SqlTable]bool{}
+	names := map[string]*supersql.SqlTable{}
+	for _, t := range tables {
+		names[t.Name()] = t
+	}
+
+	var place func(t *supersql.SqlTable, visiting map[*supersql.SqlTable]bool)
+	place = func(t *supersql.SqlTable, visiting map[*supersql.SqlTable]bool) {
+		if placed[t] || visiting[t] {
+			return
+		}
+		visiting[t] = true
+		for _, f := range t.Fields() {
+			for _, c := range f.Constraints() {
+				if c.Kind() != "REFERENCES" {
+					continue
+				}
+				if dependency, ok := names[strings.Fields(c.Expr())[0]]; ok {
+					place(dependency, visiting)
+				}
+			}
+		}
+		placed[t] = true
+		ordered = append(ordered, t)
+	}
+	for _, t := range tables {
+		place(t, map[*supersql.SqlTable]bool{})
+	}
+	return ordered
+}
+
+func indexes(actual *introspect.Schema, declared map[string]bool, wanted []Index) Plan {
+	plan := Plan{}
+	kept := map[string]bool{}
+	for _, want := range wanted {
+		want.Table = local(want.Table, actual.Name)
+		existing := actual.Table(want.Table)
+		var have *introspect.Index
+		if existing != nil {
+			for i := range existing.Indexes {
+				if existing.Indexes[i].Name == want.Name {
+					have = &existing.Indexes[i]
+				}
+			}
+		}
+		kept[want.Name] = true
+		if have != nil && same(want, *have) {
+			continue
+		}
+		if have != nil {
+			plan = append(plan, Change{Table: want.Table, SQL: "DROP INDEX " + qualified(actual.Name, want.Name), phase: dropIndexes})
+		}
+		plan = append(plan, Change{Table: want.Table, SQL: want.CREATE(), phase: createIndexes})
+	}
+
+	for _, t := range actual.Tables {
+		if !declared[t.Name] {
+			continue
+		}
+		backing := map[string]bool{}
+		for _, c := range t.Constraints {
+			backing[c.Name] = true
+		}
+		for _, index := range t.Indexes {
+			if !index.Primary && !backing[index.Name] && !kept[index.Name] {
+				plan = append(plan, Change{Table: t.Name, SQL: "DROP INDEX " + qualified(actual.Name, index.Name), phase: dropIndexes})
+			}
+		}
+	}
+	return plan
+}
+
+func same(want Index, have introspect.Index) bool {
+	method := want.Method
+	if method == "" {
+		method = "btree"
+	}
+	if want.Unique != have.Unique || method != have.Method || len(want.Columns) != len(have.Columns) {
+		return false
+	}
+	for i := range want.Columns {
+		if expression(want.Columns[i]) != expression(have.Columns[i]) {
+			return false
+		}
+	}
+	return expression(want.Where) == expression(have.Predicate)
+}
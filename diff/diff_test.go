@@ -0,0 +1,287 @@
+package diff_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rayattack/supersql"
+	"github.com/rayattack/supersql/diff"
+	"github.com/rayattack/supersql/introspect"
+	"github.com/rayattack/supersql/supersqltest"
+)
+
+func actual() *introspect.Schema {
+	return &introspect.Schema{Name: "public", Tables: []*introspect.Table{
+		{
+			Schema: "public",
+			Name:   "film",
+			Columns: []introspect.Column{
+				{Name: "film_id", Type: "integer", Default: "nextval('film_film_id_seq'::regclass)"},
+				{Name: "title", Type: "character varying(100)", Nullable: true},
+				{Name: "rental_rate", Type: "numeric(4,2)", Default: "4.99"},
+				{Name: "length", Type: "integer", Nullable: true},
+				{Name: "language_id", Type: "smallint"},
+				{Name: "status", Type: "text", Default: "'draft'::text"},
+			},
+			Constraints: []introspect.Constraint{
+				{Name: "film_pkey", Type: "PRIMARY KEY", Columns: []string{"film_id"}},
+				{Name: "film_rental_rate_check", Type: "CHECK", Columns: []string{"rental_rate"}, Definition: "CHECK (rental_rate >= 0::numeric)"},
+				{Name: "film_length_check", Type: "CHECK", Columns: []string{"length"}, Definition: "CHECK (length > 0)"},
+				{Name: "film_language_id_fkey", Type: "FOREIGN KEY", Columns: []string{"language_id"}},
+			},
+			ForeignKeys: []introspect.ForeignKey{
+				{Name: "film_language_id_fkey", Columns: []string{"language_id"}, RefSchema: "public", RefTable: "language", RefColumns: []string{"language_id"}, OnDelete: supersql.NO_ACTION, OnUpdate: supersql.NO_ACTION},
+			},
+			Indexes: []introspect.Index{
+				{Name: "film_pkey", Columns: []string{"film_id"}, Unique: true, Primary: true, Method: "btree"},
+				{Name: "film_title", Columns: []string{"title"}, Method: "btree"},
+				{Name: "film_obsolete", Columns: []string{"length"}, Method: "btree"},
+			},
+		},
+		{
+			Schema:      "public",
+			Name:        "language",
+			Columns:     []introspect.Column{{Name: "language_id", Type: "smallint"}},
+			Constraints: []introspect.Constraint{{Name: "language_pkey", Type: "PRIMARY KEY", Columns: []string{"language_id"}}},
+		},
+		{Schema: "public", Name: "supersql_migrations"},
+	}}
+}
+
+func declared() []*supersql.SqlTable {
+	language := supersql.Table("language", supersql.SmallInt("language_id", supersql.PRIMARY_KEY))
+	film := supersql.Table("film",
+		supersql.Serial("film_id", supersql.PRIMARY_KEY),
+		supersql.Text("title", supersql.NOT_NULL),
+		supersql.Numeric("rental_rate", 4, 2, supersql.NOT_NULL, supersql.DEFAULT(4.99), supersql.CHECK("rental_rate >= 0")),
+		supersql.SmallInt("language_id", supersql.NOT_NULL, supersql.REFERENCES("language", "language_id").ON_DELETE(supersql.CASCADE)),
+		supersql.Text("status", supersql.NOT_NULL, supersql.DEFAULT("'draft'")),
+		supersql.Timestamptz("released", supersql.DEFAULT("now()")),
+	)
+	category := supersql.Table("film_category",
+		supersql.Integer("film_id", supersql.REFERENCES("film")),
+		supersql.SmallInt("category_id", supersql.REFERENCES("category")),
+	)
+	categories := supersql.Table("category", supersql.Serial("category_id", supersql.PRIMARY_KEY))
+	return []*supersql.SqlTable{language, film, category, categories}
+}
+
+func statements(plan diff.Plan) []string {
+	sqls := []string{}
+	for _, c := range plan {
+		sqls = append(sqls, c.SQL)
+	}
+	return sqls
+}
+
+func TestCompare(t *testing.T) {
+	plan := diff.Compare(actual(), diff.Options{}, declared()...)
+	expected := []string{
+		"ALTER TABLE film DROP CONSTRAINT film_language_id_fkey",
+		"CREATE TABLE category (category_id serial PRIMARY KEY)",
+		"CREATE TABLE film_category (film_id integer REFERENCES film, category_id smallint REFERENCES category)",
+		"ALTER TABLE film ADD COLUMN released timestamptz DEFAULT now()",
+		"ALTER TABLE film ALTER COLUMN title TYPE text USING title::text",
+		"ALTER TABLE film ALTER COLUMN title SET NOT NULL",
+		"ALTER TABLE film ADD FOREIGN KEY (language_id) REFERENCES language (language_id) ON DELETE CASCADE",
+		"ALTER TABLE film DROP COLUMN length",
+	}
+	if strings.Join(statements(plan), "\n") != strings.Join(expected, "\n") {
+		t.Fatal(statements(plan))
+	}
+
+	destructive := plan.Destructive()
+	if len(destructive) != 1 || destructive[0].SQL != "ALTER TABLE film DROP COLUMN length" {
+		t.Fatal(destructive)
+	}
+	if _, err := plan.Script(false); err == nil || !strings.Contains(err.Error(), "DROP COLUMN length") {
+		t.Fatal(err)
+	}
+	script, err := plan.Script(true)
+	if err != nil || !strings.HasSuffix(script, "DROP COLUMN length;") {
+		t.Fatal(script, err)
+	}
+	if !strings.Contains(plan.String(), "-- destructive\nALTER TABLE film DROP COLUMN length;") {
+		t.Fatal(plan.String())
+	}
+}
+
+func TestCompareInSync(t *testing.T) {
+	film := supersql.Table("film",
+		supersql.Serial("film_id", supersql.PRIMARY_KEY),
+		supersql.Varchar("title", 100),
+		supersql.Numeric("rental_rate", 4, 2, supersql.NOT_NULL, supersql.DEFAULT(4.99), supersql.CHECK("rental_rate >= 0")),
+		supersql.Integer("length", supersql.CHECK("(length > 0)")),
+		supersql.SmallInt("language_id", supersql.NOT_NULL, supersql.REFERENCES("language", "language_id")),
+		supersql.Text("status", supersql.NOT_NULL, supersql.DEFAULT("'draft'")),
+	)
+	if plan := diff.Compare(actual(), diff.Options{}, film); len(plan) != 0 {
+		t.Fatal(statements(plan))
+	}
+}
+
+func TestCompareTypeChanges(t *testing.T) {
+	film := supersql.Table("film",
+		supersql.Serial("film_id", supersql.PRIMARY_KEY),
+		supersql.Varchar("title", 50),
+		supersql.Numeric("rental_rate", 6, 2, supersql.NOT_NULL, supersql.DEFAULT(4.99), supersql.CHECK("rental_rate >= 0")),
+		supersql.BigInt("length", supersql.CHECK("length > 0")),
+		supersql.SmallInt("language_id", supersql.NOT_NULL, supersql.REFERENCES("language", "language_id")),
+		supersql.Text("status", supersql.NOT_NULL),
+	)
+	plan := diff.Compare(actual(), diff.Options{}, film)
+	expected := []string{
+		"ALTER TABLE film ALTER COLUMN title TYPE varchar(50) USING title::varchar(50)",
+		"ALTER TABLE film ALTER COLUMN rental_rate TYPE numeric(6, 2) USING rental_rate::numeric(6, 2)",
+		"ALTER TABLE film ALTER COLUMN length TYPE bigint USING length::bigint",
+		"ALTER TABLE film ALTER COLUMN status DROP DEFAULT",
+	}
+	if strings.Join(statements(plan), "\n") != strings.Join(expected, "\n") {
+		t.Fatal(statements(plan))
+	}
+	//only narrowing the varchar can lose data
+	if destructive := plan.Destructive(); len(destructive) != 1 || destructive[0].SQL != expected[0] {
+		t.Fatal(destructive)
+	}
+}
+
+func TestCompareQuotesColumnNames(t *testing.T) {
+	existing := &introspect.Schema{Name: "public", Tables: []*introspect.Table{{
+		Schema: "public",
+		Name:   "review",
+		Columns: []introspect.Column{
+			{Name: "Title", Type: "character varying(100)", Nullable: true},
+			{Name: "user", Type: "integer"},
+			{Name: "Draft", Type: "boolean"},
+		},
+		Constraints: []introspect.Constraint{{Name: "Review_Draft_check", Type: "CHECK", Columns: []string{"Draft"}, Definition: "CHECK (\"Draft\")"}},
+	}}}
+	review := supersql.Table("review",
+		supersql.Varchar("Title", 50, supersql.UNIQUE),
+		supersql.BigInt("user", supersql.PRIMARY_KEY, supersql.REFERENCES("users")),
+		supersql.Boolean("Draft"),
+	)
+	expected := []string{
+		`ALTER TABLE review DROP CONSTRAINT "Review_Draft_check"`,
+		`ALTER TABLE review ALTER COLUMN "Title" TYPE varchar(50) USING "Title"::varchar(50)`,
+		`ALTER TABLE review ALTER COLUMN "user" TYPE bigint USING "user"::bigint`,
+		`ALTER TABLE review ALTER COLUMN "Draft" DROP NOT NULL`,
+		`ALTER TABLE review ADD PRIMARY KEY ("user")`,
+		`ALTER TABLE review ADD UNIQUE ("Title")`,
+		`ALTER TABLE review ADD FOREIGN KEY ("user") REFERENCES users`,
+	}
+	if plan := diff.Compare(existing, diff.Options{}, review); strings.Join(statements(plan), "\n") != strings.Join(expected, "\n") {
+		t.Fatal(statements(plan))
+	}
+
+	dropped := supersql.Table("review", supersql.Varchar("Title", 100), supersql.Integer("user", supersql.NOT_NULL))
+	if plan := diff.Compare(existing, diff.Options{}, dropped); statements(plan)[len(plan)-1] != `ALTER TABLE review DROP COLUMN "Draft"` {
+		t.Fatal(statements(plan))
+	}
+}
+
+func TestCompareExpressions(t *testing.T) {
+	existing := &introspect.Schema{Name: "public", Tables: []*introspect.Table{{
+		Schema: "public",
+		Name:   "payment",
+		Columns: []introspect.Column{
+			{Name: "amount", Type: "numeric(10,2)"},
+			{Name: "fee", Type: "numeric(10,2)"},
+			{Name: "total", Type: "numeric(10,2)", Nullable: true, Generated: true, Default: "(amount + fee)"},
+		},
+		Constraints: []introspect.Constraint{
+			{Name: "payment_amount_check", Type: "CHECK", Columns: []string{"amount"}, Definition: "CHECK (((amount > (0)::numeric) AND (fee >= (0)::numeric)))"},
+			{Name: "payment_fee_check", Type: "CHECK", Columns: []string{"fee"}, Definition: "CHECK ((((amount > (0)::numeric) OR (fee > (0)::numeric)) AND (amount < (1000)::numeric)))"},
+		},
+	}}}
+	payment := func(feeCheck string, total string) *supersql.SqlTable {
+		return supersql.Table("payment",
+			supersql.Numeric("amount", 10, 2, supersql.NOT_NULL, supersql.CHECK("amount > 0 AND fee >= 0")),
+			supersql.Numeric("fee", 10, 2, supersql.NOT_NULL, supersql.CHECK(feeCheck)),
+			supersql.Numeric("total", 10, 2, supersql.GENERATED_ALWAYS_AS(total)),
+		)
+	}
+
+	if plan := diff.Compare(existing, diff.Options{}, payment("(amount > 0 OR fee > 0) AND amount < 1000", "amount + fee")); len(plan) != 0 {
+		t.Fatal("parentheses postgres adds do not matter", statements(plan))
+	}
+	plan := diff.Compare(existing, diff.Options{}, payment("amount > 0 OR (fee > 0 AND amount < 1000)", "(amount + fee) * 2"))
+	expected := []string{
+		"ALTER TABLE payment DROP CONSTRAINT payment_fee_check",
+		"ALTER TABLE payment ADD CHECK (amount > 0 OR (fee > 0 AND amount < 1000))",
+		"ALTER TABLE payment DROP COLUMN total",
+		"ALTER TABLE payment ADD COLUMN total numeric(10, 2) GENERATED ALWAYS AS ((amount + fee) * 2) STORED",
+	}
+	if strings.Join(statements(plan), "\n") != strings.Join(expected, "\n") {
+		t.Fatal(strings.Join(statements(plan), "\n"))
+	}
+}
+
+func TestCompareIndexesAndPrune(t *testing.T) {
+	film := supersql.Table("film",
+		supersql.Serial("film_id", supersql.PRIMARY_KEY),
+		supersql.Varchar("title", 100),
+		supersql.Numeric("rental_rate", 4, 2, supersql.NOT_NULL, supersql.DEFAULT(4.99), supersql.CHECK("rental_rate >= 0")),
+		supersql.Integer("length", supersql.CHECK("length > 0")),
+		supersql.SmallInt("language_id", supersql.NOT_NULL, supersql.REFERENCES("language", "language_id")),
+		supersql.Text("status", supersql.NOT_NULL, supersql.DEFAULT("'draft'")),
+	)
+	opts := diff.Options{
+		Prune: true,
+		Indexes: []diff.Index{
+			{Table: "film", Name: "film_title", Columns: []string{"title"}},
+			{Table: "film", Name: "film_status", Columns: []string{"status"}, Method: "hash", Where: "status <> 'draft'"},
+		},
+	}
+	plan := diff.Compare(actual(), opts, film)
+	expected := []string{
+		"DROP INDEX public.film_obsolete",
+		"CREATE INDEX film_status ON film USING hash (status) WHERE status <> 'draft'",
+		"DROP TABLE language",
+	}
+	if strings.Join(statements(plan), "\n") != strings.Join(expected, "\n") {
+		t.Fatal(statements(plan))
+	}
+	if len(plan.Destructive()) != 1 {
+		t.Fatal(plan.Destructive())
+	}
+}
+
+func TestCheck(t *testing.T) {
+	fake := supersqltest.New()
+	w := &bytes.Buffer{}
+	if code := diff.Check(context.Background(), fake.Root(), w, diff.Options{}, declared()...); code != diff.Pending {
+		t.Fatal(code, w.String())
+	}
+	if !strings.HasPrefix(w.String(), "-- 4 pending changes, 0 destructive\nCREATE TABLE language") {
+		t.Fatal(w.String())
+	}
+
+	w.Reset()
+	if code := diff.Check(context.Background(), fake.Root(), w, diff.Options{}); code != diff.InSync {
+		t.Fatal(code, w.String())
+	}
+
+	fake.Expect("pg_class").Fails(errors.New("connection refused"))
+	if code := diff.Check(context.Background(), fake.Root(), w, diff.Options{}, declared()...); code != diff.Failed {
+		t.Fatal(code)
+	}
+}
+
+func TestApply(t *testing.T) {
+	fake := supersqltest.New()
+	plan := diff.Compare(actual(), diff.Options{}, declared()...)
+	if err := plan.Apply(context.Background(), fake.Root(), false); err == nil || len(fake.Calls()) != 0 {
+		t.Fatal(err)
+	}
+	if err := plan.Apply(context.Background(), fake.Root(), true); err != nil {
+		t.Fatal(err)
+	}
+	calls := fake.Calls()
+	if len(calls) != len(plan)+2 || calls[0].SQL != "BEGIN" || calls[len(calls)-1].SQL != "COMMIT" {
+		t.Fatal(calls)
+	}
+}
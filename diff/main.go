@@ -0,0 +1,46 @@
+package diff
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rayattack/supersql"
+)
+
+//Exit codes of Check and Main
+const (
+	InSync  = 0
+	Pending = 1
+	Failed  = 2
+)
+
+//Compare the declared tables with the database and write the pending changes to w. Returns InSync
+//when there is nothing to change, Pending when a migration is missing and Failed when the database
+//could not be inspected.
+func Check(ctx context.Context, q *supersql.SqlQuery, w io.Writer, opts Options, tables ...*supersql.SqlTable) int {
+	plan, err := Compute(ctx, q, opts, tables...)
+	if err != nil {
+		fmt.Fprintln(w, err)
+		return Failed
+	}
+	if len(plan) == 0 {
+		fmt.Fprintln(w, "-- schema is up to date")
+		return InSync
+	}
+	fmt.Fprintf(w, "-- %d pending changes, %d destructive\n%s\n", len(plan), len(plan.Destructive()), plan)
+	return Pending
+}
+
+//Entry point for a schema check command run in CI. Exits with a non zero status when the database
+//differs from the declared tables i.e. a migration was not written or not applied. The supersql
+//diff command does the same against the schema of a reference database instead of declarations.
+//
+//	func main() {
+//		q, _ := supersql.Query(context.Background(), os.Getenv("DATABASE_URL"))
+//		diff.Main(q, diff.Options{}, schema.Film, schema.Language)
+//	}
+func Main(q *supersql.SqlQuery, opts Options, tables ...*supersql.SqlTable) {
+	os.Exit(Check(context.Background(), q, os.Stdout, opts, tables...))
+}
@@ -0,0 +1,251 @@
+package diff
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/rayattack/supersql"
+)
+
+//Name of a type as rendered by the supersql field constructors so declared and introspected types
+//compare equal i.e. character varying(45) and varchar(45)
+func canonical(sqltype string) string {
+	return supersql.Typed("", sqltype).Type()
+}
+
+var (
+	casts = regexp.MustCompile(`::(?:character varying|double precision|timestamp (?:with|without) time zone|` +
+		`time (?:with|without) time zone|"?[a-z_][a-z0-9_]*"?)(?:\(\d+(?:,\s*\d+)?\))?(?:\[\])?`)
+	sizes = regexp.MustCompile(`^([a-z]+)\((\d+)(?:, (\d+))?\)$`)
+	ranks = map[string]int{"smallint": 1, "integer": 2, "bigint": 3}
+)
+
+//Reduce an expression to a form where the rewrites postgres applies when storing defaults, checks and
+//index predicates do not matter i.e. (rental_rate >= (0)::numeric) and rental_rate >= 0 are the same.
+//Casts and the parentheses postgres puts around every operator are dropped where precedence makes
+//them redundant, the ones that group differently i.e. (a OR b) AND c are kept. Keywords and
+//identifiers are folded to lower case, text inside quotes is left alone.
+func expression(expr string) string {
+	folded := []rune{}
+	quoted := false
+	for _, r := range expr {
+		if r == '\'' {
+			quoted = !quoted
+		}
+		if !quoted {
+			r = []rune(strings.ToLower(string(r)))[0]
+		}
+		folded = append(folded, r)
+	}
+	tokens := tokenize(casts.ReplaceAllString(string(folded), ""))
+	tree, _ := group(tokens, 0)
+	return strings.Join(render(tree), " ")
+}
+
+//A token or a parenthesized group of them
+type node struct {
+	token string
+	group []node
+	paren bool
+}
+
+func tokenize(expr string) []string {
+	isWord := func(c byte) bool {
+		return c == '_' || c == '$' || c == '.' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+	}
+	tokens := []string{}
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		start := i
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+			continue
+		case c == '\'' || c == '"':
+			for i++; i < len(expr); i++ {
+				if expr[i] == c {
+					if i+1 < len(expr) && expr[i+1] == c {
+						i++
+						continue
+					}
+					break
+				}
+			}
+			i++
+		case isWord(c):
+			for i++; i < len(expr) && isWord(expr[i]); i++ {
+			}
+		case strings.IndexByte("+-*/<>=~!@#%^&|?", c) >= 0:
+			for i++; i < len(expr) && strings.IndexByte("+-*/<>=~!@#%^&|?", expr[i]) >= 0; i++ {
+			}
+		default:
+			i++
+		}
+		if i > len(expr) {
+			i = len(expr)
+		}
+		tokens = append(tokens, expr[start:i])
+	}
+	return tokens
+}
+
+//Nest the tokens from i on up to the closing parenthesis of the group they are in
+func group(tokens []string, i int) ([]node, int) {
+	nodes := []node{}
+	for i < len(tokens) {
+		switch tokens[i] {
+		case "(":
+			inner, next := group(tokens, i+1)
+			nodes = append(nodes, node{group: inner, paren: true})
+			i = next
+		case ")":
+			return nodes, i + 1
+		default:
+			nodes = append(nodes, node{token: tokens[i]})
+			i++
+		}
+	}
+	return nodes, i
+}
+
+//Precedence of the binary operators from loosest to tightest binding
+var precedence = map[string]int{
+	"or": 1, "and": 2, "not": 3, "is": 4, "isnull": 4, "notnull": 4,
+	"<": 5, ">": 5, "=": 5, "<=": 5, ">=": 5, "<>": 5, "!=": 5,
+	"between": 6, "in": 6, "like": 6, "ilike": 6, "similar": 6,
+	"+": 8, "-": 8, "*": 9, "/": 9, "%": 9, "^": 10,
+}
+
+//Words after which parentheses hold a list or a subquery rather than an expression
+var lists = map[string]bool{"in": true, "any": true, "all": true, "some": true, "exists": true, "values": true}
+
+//Words that separate expressions without binding to them
+var keywords = map[string]bool{
+	"case": true, "when": true, "then": true, "else": true, "end": true, "where": true, "check": true,
+	"default": true, "using": true, "with": true,
+}
+
+//Binding of the token as an operator, 0 for operands and separators
+func binding(n node) int {
+	if n.paren || n.token == "" {
+		return 0
+	}
+	if p, ok := precedence[n.token]; ok {
+		return p
+	}
+	if strings.Trim(n.token, "+-*/<>=~!@#%^&|?") == "" {
+		//any other operator
+		return 7
+	}
+	return 0
+}
+
+//Loosest operator at the top level of a group, the group holds a list when it has a comma
+func loosest(nodes []node) (int, bool) {
+	min := 100
+	for i, n := range nodes {
+		if n.token == "," {
+			return 0, true
+		}
+		p := binding(n)
+		if p == 0 || (i == 0 && (n.token == "-" || n.token == "+")) {
+			continue
+		}
+		if p < min {
+			min = p
+		}
+	}
+	return min, false
+}
+
+func render(nodes []node) []string {
+	out := []string{}
+	for i, n := range nodes {
+		if !n.paren {
+			out = append(out, n.token)
+			continue
+		}
+		inner := render(n.group)
+		left, right := node{}, node{}
+		if i > 0 {
+			left = nodes[i-1]
+		}
+		if i+1 < len(nodes) {
+			right = nodes[i+1]
+		}
+		if !redundant(n.group, left, right) {
+			inner = append(append([]string{"("}, inner...), ")")
+		}
+		out = append(out, inner...)
+	}
+	return out
+}
+
+//Whether the parentheses around a group can go without changing what the expression means
+func redundant(group []node, left node, right node) bool {
+	if !left.paren && left.token != "" && binding(left) == 0 && !keywords[left.token] && left.token != "," {
+		//arguments of a function call or a list i.e. lower(title) or IN (1, 2)
+		return false
+	}
+	if lists[left.token] {
+		return false
+	}
+	inner, list := loosest(group)
+	if list {
+		return false
+	}
+	outer, l := binding(right), binding(left)
+	if l > outer {
+		outer = l
+	}
+	switch {
+	case inner > outer:
+		return true
+	case inner == outer && (inner == 1 || inner == 2):
+		//AND and OR associate
+		return true
+	case inner == outer:
+		//operators associate to the left so a group on the left of an equal one can go
+		return l < inner
+	}
+	return false
+}
+
+//Changing a column from one type to the other can not fail or lose information
+func widens(from string, to string) bool {
+	if ranks[from] > 0 && ranks[to] > 0 {
+		return ranks[to] >= ranks[from]
+	}
+	if from == "real" && to == "double precision" {
+		return true
+	}
+
+	base, n, scale := size(from)
+	switch {
+	case to == "text":
+		return base == "varchar" || base == "char" || from == "varchar"
+	case to == "varchar":
+		return base == "varchar"
+	case to == "numeric":
+		return base == "numeric"
+	}
+	tbase, tn, tscale := size(to)
+	switch {
+	case base == "" || base != tbase:
+		return false
+	case base == "numeric":
+		return tscale >= scale && tn-tscale >= n-scale
+	}
+	return base == "varchar" && tn >= n
+}
+
+func size(sqltype string) (string, int, int) {
+	match := sizes.FindStringSubmatch(sqltype)
+	if match == nil {
+		return "", 0, 0
+	}
+	n, _ := strconv.Atoi(match[2])
+	scale, _ := strconv.Atoi(match[3])
+	return match[1], n, scale
+}
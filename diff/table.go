@@ -0,0 +1,248 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rayattack/supersql"
+	"github.com/rayattack/supersql/introspect"
+)
+
+//What a declared field asks of its column
+type column struct {
+	field     supersql.Field
+	sqltype   string
+	notNull   bool
+	primary   bool
+	unique    bool
+	serial    bool
+	def       string
+	identity  string
+	generated string
+	checks    []string
+	refs      []supersql.Constraint
+}
+
+func declare(f supersql.Field) column {
+	c := column{field: f, sqltype: canonical(f.Type())}
+	switch c.sqltype {
+	case "serial":
+		c.sqltype, c.serial, c.notNull = "integer", true, true
+	case "bigserial":
+		c.sqltype, c.serial, c.notNull = "bigint", true, true
+	}
+	for _, constraint := range f.Constraints() {
+		switch constraint.Kind() {
+		case "NOT NULL":
+			c.notNull = true
+		case "PRIMARY KEY":
+			c.primary, c.notNull = true, true
+		case "UNIQUE":
+			c.unique = true
+		case "DEFAULT":
+			c.def = constraint.Expr()
+		case "GENERATED ALWAYS AS IDENTITY":
+			c.identity, c.notNull = "ALWAYS", true
+		case "GENERATED BY DEFAULT AS IDENTITY":
+			c.identity, c.notNull = "BY DEFAULT", true
+		case "GENERATED ALWAYS AS":
+			c.generated = constraint.Expr()
+		case "CHECK":
+			c.checks = append(c.checks, constraint.Expr())
+		case "REFERENCES":
+			c.refs = append(c.refs, constraint)
+		}
+	}
+	return c
+}
+
+//Changes of a table that exists in the database
+func table(t *supersql.SqlTable, existing *introspect.Table) Plan {
+	plan := Plan{}
+	name := t.Name()
+	change := func(phase int, destructive bool, format string, args ...interface{}) {
+		ssql := fmt.Sprintf("ALTER TABLE %s %s", t.Identifier(), fmt.Sprintf(format, args...))
+		plan = append(plan, Change{Table: name, SQL: ssql, Destructive: destructive, phase: phase})
+	}
+
+	wanted := []column{}
+	for _, f := range t.Fields() {
+		want := declare(f)
+		have, ok := existing.Column(f.Name())
+		if !ok {
+			change(addColumns, false, "ADD COLUMN %s", f.DDL())
+			continue
+		}
+		regenerate := want.generated != "" && expression(want.generated) != expression(have.Default)
+		if (want.generated != "") != have.Generated || regenerate {
+			//a plain column can not become a generated one or the other way round in place and the
+			//expression of a generated column can only be changed in place from postgres 17 on
+			change(dropColumns, true, "DROP COLUMN %s", supersql.QuoteIdentifier(f.Name()))
+			change(dropColumns, true, "ADD COLUMN %s", f.DDL())
+			continue
+		}
+		wanted = append(wanted, want)
+		plan = append(plan, alter(t, want, have, existing)...)
+	}
+	for _, have := range existing.Columns {
+		if _, ok := t.Field(have.Name); !ok {
+			change(dropColumns, true, "DROP COLUMN %s", supersql.QuoteIdentifier(have.Name))
+		}
+	}
+
+	//primary key
+	keys, pk := []string{}, existing.PrimaryKey()
+	for _, want := range wanted {
+		if want.primary {
+			keys = append(keys, supersql.QuoteIdentifier(want.field.Name()))
+		}
+	}
+	quoted := []string{}
+	for _, key := range pk {
+		quoted = append(quoted, supersql.QuoteIdentifier(key))
+	}
+	if strings.Join(keys, ",") != strings.Join(quoted, ",") {
+		for _, c := range existing.Constraints {
+			if c.Type == "PRIMARY KEY" {
+				change(dropConstraints, false, "DROP CONSTRAINT %s", supersql.QuoteIdentifier(c.Name))
+			}
+		}
+		if len(keys) > 0 {
+			change(addConstraints, false, "ADD PRIMARY KEY (%s)", strings.Join(keys, ", "))
+		}
+	}
+
+	//unique, check and foreign key constraints are matched by column and expression as their names are generated
+	matched := map[string]bool{}
+	for _, want := range wanted {
+		col := want.field.Name()
+		if want.unique {
+			if c := single(existing, "UNIQUE", col, ""); c != nil {
+				matched[c.Name] = true
+			} else {
+				change(addConstraints, false, "ADD UNIQUE (%s)", supersql.QuoteIdentifier(col))
+			}
+		}
+		for _, check := range want.checks {
+			if c := single(existing, "CHECK", "", check); c != nil {
+				matched[c.Name] = true
+			} else {
+				change(addConstraints, false, "ADD CHECK (%s)", check)
+			}
+		}
+		for _, ref := range want.refs {
+			if fk := foreign(existing, col, ref); fk != nil {
+				matched[fk.Name] = true
+			} else {
+				change(addForeignKeys, false, "ADD FOREIGN KEY (%s) %s", supersql.QuoteIdentifier(col), ref.String())
+			}
+		}
+	}
+	for _, c := range existing.Constraints {
+		if matched[c.Name] || len(c.Columns) != 1 {
+			continue
+		}
+		if _, ok := t.Field(c.Columns[0]); !ok {
+			//dropped together with its column
+			continue
+		}
+		switch c.Type {
+		case "UNIQUE", "CHECK":
+			change(dropConstraints, false, "DROP CONSTRAINT %s", supersql.QuoteIdentifier(c.Name))
+		case "FOREIGN KEY":
+			change(dropForeignKeys, false, "DROP CONSTRAINT %s", supersql.QuoteIdentifier(c.Name))
+		}
+	}
+	return plan
+}
+
+//Changes of a column that exists in the database
+func alter(t *supersql.SqlTable, want column, have introspect.Column, existing *introspect.Table) Plan {
+	plan := Plan{}
+	name := want.field.Name()
+	change := func(destructive bool, format string, args ...interface{}) {
+		ssql := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s", t.Identifier(), supersql.QuoteIdentifier(name), fmt.Sprintf(format, args...))
+		plan = append(plan, Change{Table: t.Name(), SQL: ssql, Destructive: destructive, phase: alterColumns})
+	}
+
+	from := canonical(have.Type)
+	if from != want.sqltype {
+		change(!widens(from, want.sqltype), "TYPE %s USING %s::%s", want.sqltype, supersql.QuoteIdentifier(name), want.sqltype)
+	}
+
+	primary := false
+	for _, key := range existing.PrimaryKey() {
+		primary = primary || key == name
+	}
+	switch {
+	case want.notNull && have.Nullable:
+		change(false, "SET NOT NULL")
+	case !want.notNull && !have.Nullable && !primary:
+		change(false, "DROP NOT NULL")
+	}
+
+	if want.identity != have.Identity {
+		switch {
+		case want.identity == "":
+			change(false, "DROP IDENTITY")
+		case have.Identity == "":
+			change(false, "ADD GENERATED %s AS IDENTITY", want.identity)
+		default:
+			change(false, "SET GENERATED %s", want.identity)
+		}
+	}
+
+	switch {
+	case want.generated != "":
+	case want.identity != "":
+	case want.serial && strings.HasPrefix(have.Default, "nextval("):
+	case expression(want.def) == expression(have.Default):
+	case want.def == "":
+		change(false, "DROP DEFAULT")
+	default:
+		change(false, "SET DEFAULT %s", want.def)
+	}
+	return plan
+}
+
+//Single column constraint of kind on column or with the given check expression
+func single(t *introspect.Table, kind string, column string, check string) *introspect.Constraint {
+	for i, c := range t.Constraints {
+		if c.Type != kind {
+			continue
+		}
+		if kind == "CHECK" && expression(strings.TrimPrefix(c.Definition, "CHECK")) == expression(check) {
+			return &t.Constraints[i]
+		}
+		if kind != "CHECK" && len(c.Columns) == 1 && c.Columns[0] == column {
+			return &t.Constraints[i]
+		}
+	}
+	return nil
+}
+
+//Foreign key of column that matches a declared REFERENCES constraint
+func foreign(t *introspect.Table, column string, ref supersql.Constraint) *introspect.ForeignKey {
+	for i, fk := range t.ForeignKeys {
+		if len(fk.Columns) != 1 || fk.Columns[0] != column {
+			continue
+		}
+		target := fk.RefTable
+		if fk.RefSchema != "" && fk.RefSchema != t.Schema {
+			target = fmt.Sprintf("%s.%s", fk.RefSchema, fk.RefTable)
+		}
+		candidates := []supersql.Constraint{supersql.REFERENCES(target), supersql.REFERENCES(target, fk.RefColumns...)}
+		for _, candidate := range candidates {
+			if fk.OnDelete != "" && fk.OnDelete != supersql.NO_ACTION {
+				candidate = candidate.ON_DELETE(fk.OnDelete)
+			}
+			if fk.OnUpdate != "" && fk.OnUpdate != supersql.NO_ACTION {
+				candidate = candidate.ON_UPDATE(fk.OnUpdate)
+			}
+			if expression(candidate.String()) == expression(ref.String()) {
+				return &t.ForeignKeys[i]
+			}
+		}
+	}
+	return nil
+}
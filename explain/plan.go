@@ -0,0 +1,83 @@
+//Package explain parses the output of postgres EXPLAIN (FORMAT JSON) into a tree of plan nodes and
+//flags patterns that tend to become slow as tables grow i.e. sequential scans over large relations.
+package explain
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+//A single step of a query plan. Actual* fields are only populated by EXPLAIN ANALYZE.
+type Node struct {
+	NodeType     string  `json:"Node Type"`
+	RelationName string  `json:"Relation Name"`
+	Schema       string  `json:"Schema"`
+	Alias        string  `json:"Alias"`
+	IndexName    string  `json:"Index Name"`
+	JoinType     string  `json:"Join Type"`
+	Filter       string  `json:"Filter"`
+	StartupCost  float64 `json:"Startup Cost"`
+	TotalCost    float64 `json:"Total Cost"`
+	PlanRows     float64 `json:"Plan Rows"`
+	PlanWidth    int     `json:"Plan Width"`
+
+	ActualStartupTime   float64 `json:"Actual Startup Time"`
+	ActualTotalTime     float64 `json:"Actual Total Time"`
+	ActualRows          float64 `json:"Actual Rows"`
+	ActualLoops         float64 `json:"Actual Loops"`
+	RowsRemovedByFilter float64 `json:"Rows Removed by Filter"`
+
+	Plans []*Node `json:"Plans"`
+}
+
+//Best known number of rows the node had to read. Analyzed plans report what actually happened
+//across every loop including rows thrown away by a filter, otherwise the planner estimate is used.
+func (n *Node) Rows() float64 {
+	if n.ActualLoops > 0 {
+		return (n.ActualRows + n.RowsRemovedByFilter) * n.ActualLoops
+	}
+	return n.PlanRows
+}
+
+type Plan struct {
+	Root          *Node   `json:"Plan"`
+	PlanningTime  float64 `json:"Planning Time"`
+	ExecutionTime float64 `json:"Execution Time"`
+}
+
+//Parse the document returned by EXPLAIN (FORMAT JSON) which is an array holding a single plan
+func Parse(data []byte) (*Plan, error) {
+	var plans []*Plan
+	if err := json.Unmarshal(data, &plans); err != nil {
+		return nil, fmt.Errorf("explain: %w", err)
+	}
+	if len(plans) == 0 || plans[0].Root == nil {
+		return nil, fmt.Errorf("explain: document holds no plan")
+	}
+	return plans[0], nil
+}
+
+//Visit every node of the plan depth first starting from the root
+func (p *Plan) Walk(visit func(node *Node, depth int)) {
+	var walk func(node *Node, depth int)
+	walk = func(node *Node, depth int) {
+		visit(node, depth)
+		for _, child := range node.Plans {
+			walk(child, depth+1)
+		}
+	}
+	if p.Root != nil {
+		walk(p.Root, 0)
+	}
+}
+
+//Sequential scans that read at least minRows rows
+func (p *Plan) SeqScans(minRows float64) []*Node {
+	scans := []*Node{}
+	p.Walk(func(node *Node, depth int) {
+		if node.NodeType == "Seq Scan" && node.Rows() >= minRows {
+			scans = append(scans, node)
+		}
+	})
+	return scans
+}
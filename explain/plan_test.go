@@ -0,0 +1,69 @@
+package explain_test
+
+import (
+	"testing"
+
+	"github.com/rayattack/supersql/explain"
+)
+
+const analyzed = `[{
+	"Plan": {
+		"Node Type": "Hash Join", "Join Type": "Inner", "Total Cost": 510.99, "Plan Rows": 16044,
+		"Actual Rows": 16044, "Actual Loops": 1,
+		"Plans": [
+			{"Node Type": "Seq Scan", "Relation Name": "rental", "Alias": "r", "Plan Rows": 16044,
+			 "Actual Rows": 16044, "Actual Loops": 1},
+			{"Node Type": "Hash", "Plan Rows": 599, "Actual Rows": 599, "Actual Loops": 1,
+			 "Plans": [
+				{"Node Type": "Seq Scan", "Relation Name": "customer", "Alias": "c", "Plan Rows": 599,
+				 "Filter": "(active = 1)", "Actual Rows": 584, "Actual Loops": 1, "Rows Removed by Filter": 15}
+			 ]}
+		]
+	},
+	"Planning Time": 0.31,
+	"Execution Time": 12.5
+}]`
+
+func TestParse(t *testing.T) {
+	plan, err := explain.Parse([]byte(analyzed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Root.NodeType != "Hash Join" || len(plan.Root.Plans) != 2 || plan.ExecutionTime != 12.5 {
+		t.Fail()
+	}
+
+	depths := map[string]int{}
+	plan.Walk(func(node *explain.Node, depth int) {
+		if node.RelationName != "" {
+			depths[node.RelationName] = depth
+		}
+	})
+	if depths["rental"] != 1 || depths["customer"] != 2 {
+		t.Log(depths)
+		t.Fail()
+	}
+}
+
+func TestSeqScans(t *testing.T) {
+	plan, _ := explain.Parse([]byte(analyzed))
+
+	scans := plan.SeqScans(10000)
+	if len(scans) != 1 || scans[0].RelationName != "rental" {
+		t.Log(scans)
+		t.Fail()
+	}
+	//filtered rows count towards what a scan had to read
+	if len(plan.SeqScans(599)) != 2 {
+		t.Fail()
+	}
+}
+
+func TestParseRejectsGarbage(t *testing.T) {
+	if _, err := explain.Parse([]byte(`{"Plan": {}}`)); err == nil {
+		t.Fail()
+	}
+	if _, err := explain.Parse([]byte(`[]`)); err == nil {
+		t.Fail()
+	}
+}
@@ -1,15 +1,145 @@
 package supersql
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type Field struct {
-	name   string
-	ddl    string
-	quoted bool
+	name        string
+	ddl         string
+	quoted      bool
+	constraints []Constraint
+	bind        binder
+	err         error
 }
 
-func colmaker(name string, options ...interface{}) string {
-	return ""
+//What happens to referencing records when the referenced record is deleted or updated
+type Action string
+
+const (
+	CASCADE     Action = "CASCADE"
+	RESTRICT    Action = "RESTRICT"
+	SET_NULL    Action = "SET NULL"
+	SET_DEFAULT Action = "SET DEFAULT"
+	NO_ACTION   Action = "NO ACTION"
+)
+
+//Column constraint passed as an option to the Field constructors i.e.
+//Integer("film_id", PRIMARY_KEY) or Varchar("title", NOT_NULL, UNIQUE)
+type Constraint struct {
+	kind     string
+	expr     string
+	onDelete Action
+	onUpdate Action
+}
+
+var (
+	PRIMARY_KEY = Constraint{kind: "PRIMARY KEY"}
+	NOT_NULL    = Constraint{kind: "NOT NULL"}
+	UNIQUE      = Constraint{kind: "UNIQUE"}
+
+	GENERATED_ALWAYS_AS_IDENTITY     = Constraint{kind: "GENERATED ALWAYS AS IDENTITY"}
+	GENERATED_BY_DEFAULT_AS_IDENTITY = Constraint{kind: "GENERATED BY DEFAULT AS IDENTITY"}
+)
+
+//Default value of a column. Strings are used verbatim as SQL expressions so text literals need their
+//own quotes i.e. DEFAULT("'active'") or DEFAULT("now()"), everything else is formatted as is.
+func DEFAULT(value interface{}) Constraint {
+	var expr string
+	switch v := value.(type) {
+	case string:
+		expr = v
+	case bool:
+		expr = strings.ToUpper(fmt.Sprint(v))
+	default:
+		expr = fmt.Sprint(v)
+	}
+	return Constraint{kind: "DEFAULT", expr: expr}
+}
+
+//Boolean expression every record must satisfy i.e. CHECK("rental_rate >= 0")
+func CHECK(expr string) Constraint {
+	return Constraint{kind: "CHECK", expr: expr}
+}
+
+//Stored column computed from other columns of the same record i.e.
+//GENERATED_ALWAYS_AS("price * quantity")
+func GENERATED_ALWAYS_AS(expr string) Constraint {
+	return Constraint{kind: "GENERATED ALWAYS AS", expr: expr}
+}
+
+//Foreign key to column of table, the primary key of table when column is omitted
+func REFERENCES(table interface{}, column ...string) Constraint {
+	expr := coerceToString(table)
+	if len(column) > 0 {
+		expr = fmt.Sprintf("%s (%s)", expr, strings.Join(column, ", "))
+	}
+	return Constraint{kind: "REFERENCES", expr: expr}
+}
+
+func (c Constraint) ON_DELETE(action Action) Constraint {
+	c.onDelete = action
+	return c
+}
+
+func (c Constraint) ON_UPDATE(action Action) Constraint {
+	c.onUpdate = action
+	return c
+}
+
+//Kind of the constraint i.e. PRIMARY KEY, DEFAULT or REFERENCES
+func (c Constraint) Kind() string {
+	return c.kind
+}
+
+//Expression of DEFAULT, CHECK, GENERATED ALWAYS AS and REFERENCES constraints, empty for the rest
+func (c Constraint) Expr() string {
+	return c.expr
+}
+
+func (c Constraint) String() string {
+	switch c.kind {
+	case "DEFAULT":
+		return fmt.Sprintf("DEFAULT %s", c.expr)
+	case "CHECK":
+		return fmt.Sprintf("CHECK (%s)", c.expr)
+	case "GENERATED ALWAYS AS":
+		return fmt.Sprintf("GENERATED ALWAYS AS (%s) STORED", c.expr)
+	case "REFERENCES":
+		ref := fmt.Sprintf("REFERENCES %s", c.expr)
+		if c.onDelete != "" {
+			ref = fmt.Sprintf("%s ON DELETE %s", ref, c.onDelete)
+		}
+		if c.onUpdate != "" {
+			ref = fmt.Sprintf("%s ON UPDATE %s", ref, c.onUpdate)
+		}
+		return ref
+	}
+	return c.kind
+}
+
+//Build a field of the given SQL type from the options passed to a Field constructor. Options that
+//are not constraints are left out and recorded as the error of the field, commands expantiated
+//against a table declared with it report that error.
+func colmaker(name string, sqltype string, quoted bool, options ...interface{}) Field {
+	field := Field{name: name, ddl: sqltype, quoted: quoted}
+	for _, option := range options {
+		switch o := option.(type) {
+		case Constraint:
+			field.constraints = append(field.constraints, o)
+		default:
+			if field.err == nil {
+				field.err = fmt.Errorf("supersql: %T is not a valid option for column %s", option, name)
+			}
+		}
+	}
+	return field
+}
+
+//Error in the declaration of the field i.e. an option that is not a constraint
+func (f Field) Err() error {
+	return f.err
 }
 
 func operator(f Field, op string, val interface{}) string {
@@ -26,10 +156,29 @@ func (f Field) Eq(val interface{}) string {
 	return operator(f, "=", val)
 }
 
-func Integer(name string, options ...interface{}) Field {
-	return Field{name, colmaker("integer"), false}
+func (f Field) Name() string {
+	return f.name
 }
 
-func Varchar(name string) Field {
-	return Field{name, fmt.Sprintf("%s  varchar", name), true}
+//The SQL type of the field i.e. integer or varchar(45)
+func (f Field) Type() string {
+	return f.ddl
+}
+
+func (f Field) Constraints() []Constraint {
+	return f.constraints
+}
+
+//Column definition as it appears in CREATE TABLE
+func (f Field) DDL() string {
+	return f.definition(Postgres)
+}
+
+//Column definition with the column name quoted for dialect
+func (f Field) definition(dialect Dialect) string {
+	parts := []string{quoteIdentifier(dialect, f.name), f.ddl}
+	for _, c := range f.constraints {
+		parts = append(parts, c.String())
+	}
+	return strings.Join(parts, " ")
 }
@@ -0,0 +1,310 @@
+//Package gen turns an introspected schema into Go source declaring a struct, a table handle and
+//typed column fields for every table so queries refer to columns through identifiers the compiler
+//checks instead of strings.
+//
+//	schema, _ := introspect.Inspect(ctx, q, "public")
+//	source, _ := gen.Generate(schema, gen.Options{Package: "models"})
+//
+//generates for the film table
+//
+//	type Film struct {
+//		FilmID int32  `db:"film_id" json:"film_id"`
+//		Title  string `db:"title" json:"title"`
+//	}
+//
+//	var FilmColumns = struct{ FilmID, Title supersql.Field }{...}
+//	var FilmTable = supersql.Table("film", FilmColumns.FilmID, FilmColumns.Title)
+//
+//	// ScanFilm reads a record into a Film
+//	func ScanFilm(record supersql.Row) (Film, error) {...}
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/rayattack/supersql"
+	"github.com/rayattack/supersql/introspect"
+)
+
+type Options struct {
+	//Package clause of the generated file
+	Package string
+	//Only generate these tables, every table of the schema when empty
+	Tables []string
+	//Name of the command recorded in the generated code header, supersqlgen when empty
+	Generator string
+}
+
+//Go source for the tables and enumerated types of schema, formatted with gofmt
+func Generate(schema *introspect.Schema, opts Options) ([]byte, error) {
+	if opts.Package == "" {
+		return nil, fmt.Errorf("gen: a package name is required")
+	}
+	if opts.Generator == "" {
+		opts.Generator = "supersqlgen"
+	}
+	tables := []*introspect.Table{}
+	for _, t := range schema.Tables {
+		if len(opts.Tables) == 0 || contains(opts.Tables, t.Name) {
+			tables = append(tables, t)
+		}
+	}
+	if len(tables) == 0 {
+		return nil, fmt.Errorf("gen: schema %s has no tables to generate", schema.Name)
+	}
+
+	g := &generator{schema: schema, imports: map[string]bool{"github.com/rayattack/supersql": true}}
+	body := &bytes.Buffer{}
+	for _, e := range schema.Enums {
+		g.enum(body, e)
+	}
+	for _, t := range tables {
+		g.table(body, t)
+	}
+
+	return g.file(opts, body.Bytes())
+}
+
+type generator struct {
+	schema  *introspect.Schema
+	imports map[string]bool
+}
+
+//Complete gofmt formatted Go file with the standard library imports grouped before the others
+func (g *generator) file(opts Options, body []byte) ([]byte, error) {
+	source := &bytes.Buffer{}
+	fmt.Fprintf(source, "// Code generated by %s. DO NOT EDIT.\n\npackage %s\n\nimport (\n", opts.Generator, opts.Package)
+	std, others := []string{}, []string{}
+	for path := range g.imports {
+		if strings.Contains(strings.Split(path, "/")[0], ".") {
+			others = append(others, path)
+		} else {
+			std = append(std, path)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(others)
+	for _, path := range std {
+		fmt.Fprintf(source, "\t%q\n", path)
+	}
+	source.WriteString("\n")
+	for _, path := range others {
+		fmt.Fprintf(source, "\t%q\n", path)
+	}
+	source.WriteString(")\n")
+	source.Write(body)
+
+	formatted, err := format.Source(source.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("gen: generated invalid Go source: %w", err)
+	}
+	return formatted, nil
+}
+
+func (g *generator) enum(w *bytes.Buffer, e introspect.Enum) {
+	name := identifier(e.Name)
+	values := []string{fmt.Sprintf("%q", e.Name)}
+	for _, value := range e.Values {
+		values = append(values, fmt.Sprintf("%q", value))
+	}
+	fmt.Fprintf(w, "\n// %s is the %s enumerated type\n", name, e.Name)
+	fmt.Fprintf(w, "var %s = supersql.EnumType(%s)\n", name, strings.Join(values, ", "))
+
+	fmt.Fprintf(w, "\nconst (\n")
+	for _, value := range e.Values {
+		fmt.Fprintf(w, "\t%s%s = %q\n", name, identifier(value), value)
+	}
+	fmt.Fprintf(w, ")\n")
+}
+
+func (g *generator) table(w *bytes.Buffer, t *introspect.Table) {
+	name := identifier(t.Name)
+	fields := t.SqlTable().Fields()
+
+	fmt.Fprintf(w, "\n// %s is a record of the %s table", name, t.Name)
+	if t.Comment != "" {
+		fmt.Fprintf(w, ". %s", strings.ReplaceAll(t.Comment, "\n", " "))
+	}
+	fmt.Fprintf(w, "\ntype %s struct {\n", name)
+	for i, f := range fields {
+		fmt.Fprintf(w, "\t%s %s `db:\"%s\" json:\"%s\"`\n", identifier(f.Name()), g.gotype(f.Type(), t.Columns[i].Nullable), f.Name(), f.Name())
+	}
+	fmt.Fprintf(w, "}\n")
+
+	names := []string{}
+	for _, f := range fields {
+		names = append(names, f.Name())
+	}
+	g.scan(w, "Scan"+name, name, names)
+
+	fmt.Fprintf(w, "\n// %sColumns holds a field for every column of the %s table\n", name, t.Name)
+	fmt.Fprintf(w, "var %sColumns = struct {\n", name)
+	for _, f := range fields {
+		fmt.Fprintf(w, "\t%s supersql.Field\n", identifier(f.Name()))
+	}
+	fmt.Fprintf(w, "}{\n")
+	for _, f := range fields {
+		fmt.Fprintf(w, "\t%s: %s,\n", identifier(f.Name()), g.constructor(f))
+	}
+	fmt.Fprintf(w, "}\n")
+
+	columns := []string{fmt.Sprintf("%q", t.SqlTable().Name())}
+	for _, f := range fields {
+		columns = append(columns, fmt.Sprintf("%sColumns.%s", name, identifier(f.Name())))
+	}
+	fmt.Fprintf(w, "\n// %sTable is the declaration of the %s table\n", name, t.Name)
+	fmt.Fprintf(w, "var %sTable = supersql.Table(%s)\n", name, strings.Join(columns, ", "))
+}
+
+var (
+	sized   = regexp.MustCompile(`^(varchar|char)\((\d+)\)$`)
+	numeric = regexp.MustCompile(`^numeric\((\d+), (\d+)\)$`)
+
+	constructors = map[string]string{
+		"smallint": "SmallInt", "integer": "Integer", "bigint": "BigInt", "serial": "Serial", "bigserial": "BigSerial",
+		"boolean": "Boolean", "text": "Text", "varchar": "Varchar", "char": "Char", "numeric": "Numeric", "real": "Real",
+		"double precision": "Double", "date": "Date", "time": "Time", "timestamp": "Timestamp",
+		"timestamptz": "Timestamptz", "interval": "Interval", "uuid": "UUID", "json": "JSON", "jsonb": "JSONB",
+		"bytea": "Bytea", "inet": "Inet", "cidr": "Cidr",
+	}
+	gotypes = map[string]string{
+		"smallint": "int16", "integer": "int32", "bigint": "int64", "serial": "int32", "bigserial": "int64",
+		"boolean": "bool", "text": "string", "varchar": "string", "char": "string", "numeric": "float64",
+		"real": "float32", "double precision": "float64", "date": "time.Time", "time": "time.Time",
+		"timestamp": "time.Time", "timestamptz": "time.Time", "interval": "time.Duration", "uuid": "string",
+		"json": "json.RawMessage", "jsonb": "json.RawMessage", "bytea": "[]byte", "inet": "string", "cidr": "string",
+	}
+)
+
+//Go expression constructing field with the most specific supersql constructor
+func (g *generator) constructor(f supersql.Field) string {
+	options := []string{}
+	for _, c := range f.Constraints() {
+		options = append(options, option(c))
+	}
+	return g.typed(f.Name(), f.Type(), options)
+}
+
+func (g *generator) typed(name string, sqltype string, options []string) string {
+	args := []string{fmt.Sprintf("%q", name)}
+	call := func(constructor string, extra ...string) string {
+		return fmt.Sprintf("supersql.%s(%s)", constructor, strings.Join(append(append(args, extra...), options...), ", "))
+	}
+
+	if element := strings.TrimSuffix(sqltype, "[]"); element != sqltype {
+		return fmt.Sprintf("supersql.Array(%s)", strings.Join(append([]string{g.typed(name, element, nil)}, options...), ", "))
+	}
+	if match := sized.FindStringSubmatch(sqltype); match != nil {
+		return call(constructors[match[1]], match[2])
+	}
+	if match := numeric.FindStringSubmatch(sqltype); match != nil {
+		return call("Numeric", match[1], match[2])
+	}
+	if sqltype == "numeric" {
+		return call("Numeric", "0", "0")
+	}
+	if constructor, ok := constructors[sqltype]; ok {
+		return call(constructor)
+	}
+	if g.schema.Enum(sqltype) != nil {
+		return call("Enum", identifier(sqltype))
+	}
+	return call("Typed", fmt.Sprintf("%q", sqltype))
+}
+
+//Go type of a column, nullable scalars are pointers so NULL can be told apart from the zero value
+func (g *generator) gotype(sqltype string, nullable bool) string {
+	if element := strings.TrimSuffix(sqltype, "[]"); element != sqltype {
+		return "[]" + g.gotype(element, false)
+	}
+	base := sqltype
+	if match := sized.FindStringSubmatch(sqltype); match != nil {
+		base = match[1]
+	} else if strings.HasPrefix(sqltype, "numeric") {
+		base = "numeric"
+	}
+
+	gotype, ok := gotypes[base]
+	if !ok {
+		//enumerated types and anything else the catalog does not know travel as text
+		gotype = "string"
+	}
+	switch {
+	case strings.HasPrefix(gotype, "time."):
+		g.imports["time"] = true
+	case strings.HasPrefix(gotype, "json."):
+		g.imports["encoding/json"] = true
+	}
+	if nullable && !strings.HasPrefix(gotype, "[]") && gotype != "json.RawMessage" {
+		return "*" + gotype
+	}
+	return gotype
+}
+
+var actions = regexp.MustCompile(`ON (DELETE|UPDATE) (SET NULL|SET DEFAULT|NO ACTION|CASCADE|RESTRICT)`)
+
+//Go expression of a column constraint
+func option(c supersql.Constraint) string {
+	switch c.Kind() {
+	case "DEFAULT":
+		return fmt.Sprintf("supersql.DEFAULT(%q)", c.Expr())
+	case "CHECK":
+		return fmt.Sprintf("supersql.CHECK(%q)", c.Expr())
+	case "GENERATED ALWAYS AS":
+		return fmt.Sprintf("supersql.GENERATED_ALWAYS_AS(%q)", c.Expr())
+	case "REFERENCES":
+		target := strings.TrimSuffix(c.Expr(), ")")
+		parts := strings.SplitN(target, " (", 2)
+		args := []string{fmt.Sprintf("%q", parts[0])}
+		if len(parts) == 2 {
+			for _, column := range strings.Split(parts[1], ", ") {
+				args = append(args, fmt.Sprintf("%q", column))
+			}
+		}
+		expr := fmt.Sprintf("supersql.REFERENCES(%s)", strings.Join(args, ", "))
+		for _, match := range actions.FindAllStringSubmatch(strings.TrimPrefix(c.String(), "REFERENCES "+c.Expr()), -1) {
+			expr = fmt.Sprintf("%s.ON_%s(supersql.%s)", expr, match[1], strings.ReplaceAll(match[2], " ", "_"))
+		}
+		return expr
+	}
+	return "supersql." + strings.ReplaceAll(c.Kind(), " ", "_")
+}
+
+var initialisms = map[string]bool{
+	"ID": true, "URL": true, "URI": true, "UUID": true, "JSON": true, "IP": true, "HTTP": true, "API": true,
+	"SQL": true, "HTML": true, "XML": true, "UTC": true,
+}
+
+//Exported Go identifier for an SQL name i.e. FilmID for film_id and Pg13 for PG-13
+func identifier(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	ident := ""
+	for _, word := range words {
+		if initialisms[strings.ToUpper(word)] {
+			ident += strings.ToUpper(word)
+			continue
+		}
+		runes := []rune(strings.ToLower(word))
+		runes[0] = unicode.ToUpper(runes[0])
+		ident += string(runes)
+	}
+	if ident == "" || unicode.IsDigit([]rune(ident)[0]) {
+		ident = "X" + ident
+	}
+	return ident
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package gen_test
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/rayattack/supersql"
+	"github.com/rayattack/supersql/gen"
+	"github.com/rayattack/supersql/introspect"
+)
+
+func schema() *introspect.Schema {
+	return &introspect.Schema{
+		Name:  "public",
+		Enums: []introspect.Enum{{Name: "mpaa_rating", Values: []string{"G", "PG-13"}}},
+		Tables: []*introspect.Table{
+			{
+				Schema:  "public",
+				Name:    "film",
+				Comment: "Films in the catalogue",
+				Columns: []introspect.Column{
+					{Name: "film_id", Type: "integer", Identity: "ALWAYS"},
+					{Name: "title", Type: "character varying(255)"},
+					{Name: "rental_rate", Type: "numeric(4,2)", Default: "4.99"},
+					{Name: "language_id", Type: "smallint", Nullable: true},
+					{Name: "special_features", Type: "text[]", Nullable: true},
+					{Name: "rating", Type: "mpaa_rating", Nullable: true},
+					{Name: "last_update", Type: "timestamp with time zone", Default: "now()"},
+					{Name: "fulltext", Type: "tsvector", Nullable: true},
+				},
+				Constraints: []introspect.Constraint{
+					{Name: "film_pkey", Type: "PRIMARY KEY", Columns: []string{"film_id"}},
+					{Name: "film_language_id_fkey", Type: "FOREIGN KEY", Columns: []string{"language_id"}},
+				},
+				ForeignKeys: []introspect.ForeignKey{
+					{Name: "film_language_id_fkey", Columns: []string{"language_id"}, RefTable: "language", RefColumns: []string{"language_id"}, OnDelete: supersql.SET_NULL, OnUpdate: supersql.CASCADE},
+				},
+			},
+			{Schema: "public", Name: "language", Columns: []introspect.Column{{Name: "name", Type: "character(20)"}}},
+		},
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	source, err := gen.Generate(schema(), gen.Options{Package: "models"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "schema_gen.go", source, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	code := string(source)
+	fragments := []string{
+		"// Code generated by supersqlgen. DO NOT EDIT.\n\npackage models",
+		`"time"`,
+		`var MpaaRating = supersql.EnumType("mpaa_rating", "G", "PG-13")`,
+		`MpaaRatingPg13 = "PG-13"`,
+		"// Film is a record of the film table. Films in the catalogue",
+		"FilmID          int32     `db:\"film_id\" json:\"film_id\"`",
+		"LanguageID      *int16    `db:\"language_id\" json:\"language_id\"`",
+		"SpecialFeatures []string  `db:\"special_features\" json:\"special_features\"`",
+		"Rating          *string   `db:\"rating\" json:\"rating\"`",
+		"LastUpdate      time.Time `db:\"last_update\" json:\"last_update\"`",
+		`FilmID:          supersql.Integer("film_id", supersql.PRIMARY_KEY, supersql.GENERATED_ALWAYS_AS_IDENTITY),`,
+		`Title:           supersql.Varchar("title", 255, supersql.NOT_NULL),`,
+		`RentalRate:      supersql.Numeric("rental_rate", 4, 2, supersql.NOT_NULL, supersql.DEFAULT("4.99")),`,
+		`LanguageID:      supersql.SmallInt("language_id", supersql.REFERENCES("language", "language_id").ON_DELETE(supersql.SET_NULL).ON_UPDATE(supersql.CASCADE)),`,
+		`SpecialFeatures: supersql.Array(supersql.Text("special_features")),`,
+		`Rating:          supersql.Enum("rating", MpaaRating),`,
+		`Fulltext:        supersql.Typed("fulltext", "tsvector"),`,
+		`var FilmTable = supersql.Table("film", FilmColumns.FilmID, FilmColumns.Title,`,
+		`Name: supersql.Char("name", 20, supersql.NOT_NULL),`,
+		"func ScanFilm(record supersql.Row) (Film, error) {",
+		`if err := supersql.Assign(&row.RentalRate, record.Column("rental_rate")); err != nil {`,
+	}
+	for _, fragment := range fragments {
+		if !strings.Contains(code, fragment) {
+			t.Fatalf("missing %s in\n%s", fragment, code)
+		}
+	}
+}
+
+func TestGenerateSelectedTables(t *testing.T) {
+	source, err := gen.Generate(schema(), gen.Options{Package: "models", Tables: []string{"language"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(source), "type Film struct") || strings.Contains(string(source), `"time"`) {
+		t.Fatal(string(source))
+	}
+
+	if _, err := gen.Generate(schema(), gen.Options{}); err == nil {
+		t.Fail()
+	}
+	if _, err := gen.Generate(schema(), gen.Options{Package: "models", Tables: []string{"actor"}}); err == nil {
+		t.Fail()
+	}
+}
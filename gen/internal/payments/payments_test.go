@@ -0,0 +1,64 @@
+package payments_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgtype"
+	"github.com/rayattack/supersql/gen/internal/payments"
+	"github.com/rayattack/supersql/supersqltest"
+)
+
+var columns = []string{"payment_id", "amount", "grace_period"}
+
+//Values in the form pgx hands them over for uuid, numeric and interval columns
+func payment(id [16]byte, amount string, period pgtype.Interval) []interface{} {
+	numeric := pgtype.Numeric{}
+	if err := numeric.Set(amount); err != nil {
+		panic(err)
+	}
+	return []interface{}{id, numeric, period}
+}
+
+func TestGetPayment(t *testing.T) {
+	fake := supersqltest.New()
+	id := [16]byte{0x8f, 0x0c, 0x1a, 0x2b, 0x3c, 0x4d, 0x4e, 0x5f, 0x80, 0x91, 0xa2, 0xb3, 0xc4, 0xd5, 0xe6, 0xf7}
+	fake.Expect("WHERE payment_id = $1").Returns(columns, payment(id, "12.50", pgtype.Interval{Days: 1, Microseconds: 1800000000, Status: pgtype.Present}))
+
+	row, err := payments.GetPayment(fake.Root(), "8f0c1a2b-3c4d-4e5f-8091-a2b3c4d5e6f7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if row.PaymentID != "8f0c1a2b-3c4d-4e5f-8091-a2b3c4d5e6f7" || row.Amount != 12.5 || row.GracePeriod != 24*time.Hour+30*time.Minute {
+		t.Fatal(row)
+	}
+}
+
+func TestListPayments(t *testing.T) {
+	fake := supersqltest.New()
+	none := pgtype.Interval{Status: pgtype.Null}
+	fake.Expect("ORDER BY amount DESC").Returns(columns,
+		payment([16]byte{1}, "99.99", none),
+		payment([16]byte{2}, "0.01", none),
+	)
+
+	rows, err := payments.ListPayments(fake.Root())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].PaymentID != "01000000-0000-0000-0000-000000000000" || rows[0].Amount != 99.99 || rows[1].Amount != 0.01 {
+		t.Fatal(rows)
+	}
+	if rows[1].GracePeriod != 0 {
+		t.Fatal(rows[1].GracePeriod)
+	}
+
+	fake.Expect("ORDER BY amount DESC").Returns(columns, []interface{}{"not a uuid", 1, nil})
+	if _, err := payments.ListPayments(fake.Root()); err != nil {
+		t.Fatal("strings are read into string fields", err)
+	}
+	fake.Expect("ORDER BY amount DESC").Returns(columns, []interface{}{[16]byte{3}, "many", nil})
+	if _, err := payments.ListPayments(fake.Root()); err == nil {
+		t.Fatal("text was read into the numeric amount")
+	}
+}
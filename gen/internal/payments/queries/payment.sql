@@ -0,0 +1,5 @@
+-- name: GetPayment :one
+SELECT payment_id, amount, grace_period FROM payment WHERE payment_id = :payment_id;
+
+-- name: ListPayments :many
+SELECT payment_id, amount, grace_period FROM payment ORDER BY amount DESC;
@@ -0,0 +1,92 @@
+// Code generated by supersqlgen. DO NOT EDIT.
+
+package payments
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/rayattack/supersql"
+)
+
+const getPaymentSQL = "SELECT payment_id, amount, grace_period FROM payment WHERE payment_id = ?"
+
+// GetPaymentRow is a record returned by GetPayment
+type GetPaymentRow struct {
+	PaymentID   string        `db:"payment_id" json:"payment_id"`
+	Amount      float64       `db:"amount" json:"amount"`
+	GracePeriod time.Duration `db:"grace_period" json:"grace_period"`
+}
+
+// scanGetPaymentRow reads a record into a GetPaymentRow
+func scanGetPaymentRow(record supersql.Row) (GetPaymentRow, error) {
+	var row GetPaymentRow
+	if err := supersql.Assign(&row.PaymentID, record.Column("payment_id")); err != nil {
+		return row, fmt.Errorf("payment_id: %w", err)
+	}
+	if err := supersql.Assign(&row.Amount, record.Column("amount")); err != nil {
+		return row, fmt.Errorf("amount: %w", err)
+	}
+	if err := supersql.Assign(&row.GracePeriod, record.Column("grace_period")); err != nil {
+		return row, fmt.Errorf("grace_period: %w", err)
+	}
+	return row, nil
+}
+
+// GetPayment runs the GetPayment query of queries/payment.sql
+func GetPayment(q *supersql.SqlQuery, paymentID string) (*GetPaymentRow, error) {
+	r, err := q.RAW(getPaymentSQL, paymentID).GO()
+	if err != nil {
+		return nil, err
+	}
+	if r.Count() == 0 {
+		return nil, sql.ErrNoRows
+	}
+	row, err := scanGetPaymentRow(r.Rows(1))
+	if err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+const listPaymentsSQL = "SELECT payment_id, amount, grace_period FROM payment ORDER BY amount DESC"
+
+// ListPaymentsRow is a record returned by ListPayments
+type ListPaymentsRow struct {
+	PaymentID   string        `db:"payment_id" json:"payment_id"`
+	Amount      float64       `db:"amount" json:"amount"`
+	GracePeriod time.Duration `db:"grace_period" json:"grace_period"`
+}
+
+// scanListPaymentsRow reads a record into a ListPaymentsRow
+func scanListPaymentsRow(record supersql.Row) (ListPaymentsRow, error) {
+	var row ListPaymentsRow
+	if err := supersql.Assign(&row.PaymentID, record.Column("payment_id")); err != nil {
+		return row, fmt.Errorf("payment_id: %w", err)
+	}
+	if err := supersql.Assign(&row.Amount, record.Column("amount")); err != nil {
+		return row, fmt.Errorf("amount: %w", err)
+	}
+	if err := supersql.Assign(&row.GracePeriod, record.Column("grace_period")); err != nil {
+		return row, fmt.Errorf("grace_period: %w", err)
+	}
+	return row, nil
+}
+
+// ListPayments runs the ListPayments query of queries/payment.sql
+func ListPayments(q *supersql.SqlQuery) ([]ListPaymentsRow, error) {
+	r, err := q.RAW(listPaymentsSQL).GO()
+	if err != nil {
+		return nil, err
+	}
+	rows := []ListPaymentsRow{}
+	for _, record := range r.All() {
+		row, err := scanListPaymentsRow(record)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
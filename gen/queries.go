@@ -0,0 +1,166 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"strings"
+	"unicode"
+
+	"github.com/rayattack/supersql"
+	"github.com/rayattack/supersql/sqlfile"
+)
+
+//Names the generated functions use for themselves
+var locals = map[string]bool{"q": true, "r": true, "err": true, "row": true, "rows": true, "record": true, "sql": true, "fmt": true}
+
+//Looks up the parameter and result column types of a statement, usually the DESCRIBE method of a
+//query root connected to a development database
+type Describer func(ssql string) (*supersql.Statement, error)
+
+//Go source declaring a typed function for every query. Each statement is described by the server so
+//a query that no longer matches the schema fails generation and a changed column type changes the
+//signature of the functions using it.
+func Queries(queries sqlfile.Queries, describe Describer, opts Options) ([]byte, error) {
+	if opts.Package == "" {
+		return nil, fmt.Errorf("gen: a package name is required")
+	}
+	if opts.Generator == "" {
+		opts.Generator = "supersqlgen"
+	}
+
+	g := &generator{imports: map[string]bool{"github.com/rayattack/supersql": true}}
+	body := &bytes.Buffer{}
+	for _, q := range queries {
+		statement, err := describe(q.SQL)
+		if err != nil {
+			return nil, fmt.Errorf("gen: %s:%d: %s: %w", q.File, q.Line, q.Name, err)
+		}
+		if err := g.query(body, q, statement); err != nil {
+			return nil, fmt.Errorf("gen: %s:%d: %s: %w", q.File, q.Line, q.Name, err)
+		}
+	}
+	return g.file(opts, body.Bytes())
+}
+
+func (g *generator) query(w *bytes.Buffer, q sqlfile.Query, statement *supersql.Statement) error {
+	if len(statement.Params) != q.Arity {
+		return fmt.Errorf("the server counts %d parameters, the file %d", len(statement.Params), q.Arity)
+	}
+	name := q.Name
+	if !token.IsIdentifier(name) || !token.IsExported(name) {
+		name = identifier(name)
+	}
+	constant := unexported(name) + "SQL"
+
+	//parameters of the function, repeated :named placeholders share one
+	params, args, seen := []string{}, []string{}, map[string]bool{}
+	for i, sqltype := range statement.Params {
+		param := fmt.Sprintf("arg%d", i+1)
+		if q.Params != nil {
+			param = unexported(identifier(q.Params[i]))
+		}
+		if token.IsKeyword(param) || locals[param] {
+			param += "_"
+		}
+		args = append(args, param)
+		if !seen[param] {
+			seen[param] = true
+			params = append(params, fmt.Sprintf("%s %s", param, g.gotype(canonical(sqltype), false)))
+		}
+	}
+	call := strings.Join(append([]string{constant}, args...), ", ")
+	signature := strings.Join(append([]string{"q *supersql.SqlQuery"}, params...), ", ")
+
+	fmt.Fprintf(w, "\nconst %s = %q\n", constant, q.SQL)
+
+	if q.Kind == sqlfile.Exec {
+		fmt.Fprintf(w, "\n// %s runs the %s query of %s\n", name, q.Name, q.File)
+		fmt.Fprintf(w, "func %s(%s) error {\n", name, signature)
+		fmt.Fprintf(w, "\t_, err := q.RAW(%s).GO()\n\treturn err\n}\n", call)
+		return nil
+	}
+
+	if len(statement.Columns) == 0 {
+		return fmt.Errorf("%s queries must return columns", q.Kind)
+	}
+	row := name + "Row"
+	fields := map[string]bool{}
+	fmt.Fprintf(w, "\n// %s is a record returned by %s\n", row, name)
+	fmt.Fprintf(w, "type %s struct {\n", row)
+	for _, column := range statement.Columns {
+		field := identifier(column.Name)
+		if fields[field] {
+			return fmt.Errorf("more than one result column is called %s", column.Name)
+		}
+		fields[field] = true
+		fmt.Fprintf(w, "\t%s %s `db:\"%s\" json:\"%s\"`\n", field, g.gotype(canonical(column.Type), false), column.Name, column.Name)
+	}
+	fmt.Fprintf(w, "}\n")
+
+	columns := []string{}
+	for _, column := range statement.Columns {
+		columns = append(columns, column.Name)
+	}
+	scan := "scan" + row
+	g.scan(w, scan, row, columns)
+
+	fmt.Fprintf(w, "\n// %s runs the %s query of %s\n", name, q.Name, q.File)
+	if q.Kind == sqlfile.One {
+		g.imports["database/sql"] = true
+		fmt.Fprintf(w, "func %s(%s) (*%s, error) {\n", name, signature, row)
+		fmt.Fprintf(w, "\tr, err := q.RAW(%s).GO()\n", call)
+		fmt.Fprintf(w, "\tif err != nil {\n\t\treturn nil, err\n\t}\n")
+		fmt.Fprintf(w, "\tif r.Count() == 0 {\n\t\treturn nil, sql.ErrNoRows\n\t}\n")
+		fmt.Fprintf(w, "\trow, err := %s(r.Rows(1))\n", scan)
+		fmt.Fprintf(w, "\tif err != nil {\n\t\treturn nil, err\n\t}\n")
+		fmt.Fprintf(w, "\treturn &row, nil\n}\n")
+		return nil
+	}
+	fmt.Fprintf(w, "func %s(%s) ([]%s, error) {\n", name, signature, row)
+	fmt.Fprintf(w, "\tr, err := q.RAW(%s).GO()\n", call)
+	fmt.Fprintf(w, "\tif err != nil {\n\t\treturn nil, err\n\t}\n")
+	fmt.Fprintf(w, "\trows := []%s{}\n", row)
+	fmt.Fprintf(w, "\tfor _, record := range r.All() {\n")
+	fmt.Fprintf(w, "\t\trow, err := %s(record)\n", scan)
+	fmt.Fprintf(w, "\t\tif err != nil {\n\t\t\treturn nil, err\n\t\t}\n")
+	fmt.Fprintf(w, "\t\trows = append(rows, row)\n\t}\n")
+	fmt.Fprintf(w, "\treturn rows, nil\n}\n")
+	return nil
+}
+
+//Function reading a record into a struct of type typ column by column. supersql.Assign converts the
+//values of the driver to the Go type of each field i.e. a uuid to a string or a numeric to a float64.
+func (g *generator) scan(w *bytes.Buffer, function string, typ string, columns []string) {
+	g.imports["fmt"] = true
+	fmt.Fprintf(w, "\n// %s reads a record into a %s\n", function, typ)
+	fmt.Fprintf(w, "func %s(record supersql.Row) (%s, error) {\n", function, typ)
+	fmt.Fprintf(w, "\tvar row %s\n", typ)
+	for _, column := range columns {
+		fmt.Fprintf(w, "\tif err := supersql.Assign(&row.%s, record.Column(%q)); err != nil {\n", identifier(column), column)
+		fmt.Fprintf(w, "\t\treturn row, fmt.Errorf(%q, err)\n\t}\n", column+": %w")
+	}
+	fmt.Fprintf(w, "\treturn row, nil\n}\n")
+}
+
+//Name of a type as rendered by the supersql field constructors i.e. varchar(45) for character varying(45)
+func canonical(sqltype string) string {
+	return supersql.Typed("", sqltype).Type()
+}
+
+//Unexported form of an identifier i.e. filmID for FilmID and urlPath for URLPath
+func unexported(ident string) string {
+	runes := []rune(ident)
+	upper := 0
+	for upper < len(runes) && unicode.IsUpper(runes[upper]) {
+		upper++
+	}
+	if upper > 1 && upper < len(runes) {
+		//the last capital starts the next word
+		upper--
+	}
+	for i := 0; i < upper; i++ {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
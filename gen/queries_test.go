@@ -0,0 +1,134 @@
+package gen_test
+
+import (
+	"errors"
+	"go/parser"
+	"go/token"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/rayattack/supersql"
+	"github.com/rayattack/supersql/gen"
+	"github.com/rayattack/supersql/sqlfile"
+)
+
+var statements = map[string]*supersql.Statement{
+	"WHERE film_id = ?": {
+		Params: []string{"integer", "mpaa_rating", "integer"},
+		Columns: []supersql.StatementColumn{
+			{Name: "film_id", Type: "integer"},
+			{Name: "title", Type: "character varying(255)"},
+			{Name: "last_update", Type: "timestamp with time zone"},
+		},
+	},
+	"ORDER BY title": {
+		Params:  []string{"text", "bigint"},
+		Columns: []supersql.StatementColumn{{Name: "title", Type: "text"}, {Name: "special_features", Type: "text[]"}},
+	},
+	"DELETE FROM actor": {Params: []string{"integer"}},
+}
+
+func describe(ssql string) (*supersql.Statement, error) {
+	for fragment, statement := range statements {
+		if strings.Contains(ssql, fragment) {
+			return statement, nil
+		}
+	}
+	return nil, errors.New(`relation "missing" does not exist`)
+}
+
+func load(t *testing.T, source string) sqlfile.Queries {
+	queries, err := sqlfile.Load(fstest.MapFS{"queries/film.sql": {Data: []byte(source)}}, "queries/*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return queries
+}
+
+func TestQueries(t *testing.T) {
+	queries := load(t, `-- name: GetFilm :one
+SELECT film_id, title, last_update FROM film WHERE film_id = :film_id AND rating = :type OR :film_id = 0;
+
+-- name: ListFilms :many
+SELECT title, special_features FROM film WHERE rating = ? ORDER BY title LIMIT ?;
+
+-- name: DeleteActor :exec
+DELETE FROM actor WHERE actor_id = ?;
+`)
+	source, err := gen.Queries(queries, describe, gen.Options{Package: "db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "queries_gen.go", source, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	code := string(source)
+	fragments := []string{
+		`"database/sql"`,
+		`"time"`,
+		`const getFilmSQL = "SELECT film_id, title, last_update FROM film WHERE film_id = ? AND rating = ? OR ? = 0"`,
+		"type GetFilmRow struct {",
+		"LastUpdate time.Time `db:\"last_update\" json:\"last_update\"`",
+		"func GetFilm(q *supersql.SqlQuery, filmID int32, type_ string) (*GetFilmRow, error) {",
+		"r, err := q.RAW(getFilmSQL, filmID, type_, filmID).GO()",
+		"return nil, sql.ErrNoRows",
+		"SpecialFeatures []string `db:\"special_features\" json:\"special_features\"`",
+		"func ListFilms(q *supersql.SqlQuery, arg1 string, arg2 int64) ([]ListFilmsRow, error) {",
+		"func DeleteActor(q *supersql.SqlQuery, arg1 int32) error {",
+		"func scanGetFilmRow(record supersql.Row) (GetFilmRow, error) {",
+		`if err := supersql.Assign(&row.LastUpdate, record.Column("last_update")); err != nil {`,
+		`return row, fmt.Errorf("last_update: %w", err)`,
+		"row, err := scanListFilmsRow(record)",
+	}
+	for _, fragment := range fragments {
+		if !strings.Contains(code, fragment) {
+			t.Fatalf("missing %s in\n%s", fragment, code)
+		}
+	}
+}
+
+//The payments package holds the code generated for queries on uuid, numeric and interval columns and
+//tests it against the values the driver hands over for them
+func TestQueriesGeneratedForPayments(t *testing.T) {
+	queries, err := sqlfile.Load(os.DirFS("internal/payments"), "queries/*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	describe := func(ssql string) (*supersql.Statement, error) {
+		statement := &supersql.Statement{Columns: []supersql.StatementColumn{
+			{Name: "payment_id", Type: "uuid"},
+			{Name: "amount", Type: "numeric(5,2)"},
+			{Name: "grace_period", Type: "interval"},
+		}}
+		if strings.Contains(ssql, "WHERE") {
+			statement.Params = []string{"uuid"}
+		}
+		return statement, nil
+	}
+	source, err := gen.Queries(queries, describe, gen.Options{Package: "payments"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	generated, err := os.ReadFile("internal/payments/queries_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(generated) != string(source) {
+		t.Fatalf("internal/payments/queries_gen.go is out of date, generated\n%s", source)
+	}
+}
+
+func TestQueriesFailOnBrokenStatements(t *testing.T) {
+	queries := load(t, "-- name: Broken :many\nSELECT * FROM missing;")
+	if _, err := gen.Queries(queries, describe, gen.Options{Package: "db"}); err == nil || !strings.Contains(err.Error(), "queries/film.sql:1: Broken") {
+		t.Fatal(err)
+	}
+
+	queries = load(t, "-- name: DeleteActor :many\nDELETE FROM actor WHERE actor_id = ?;")
+	if _, err := gen.Queries(queries, describe, gen.Options{Package: "db"}); err == nil {
+		t.Fatal("a :many query without result columns was accepted")
+	}
+}
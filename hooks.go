@@ -0,0 +1,64 @@
+package supersql
+
+import (
+	"context"
+	"time"
+)
+
+//Everything known about a statement sent to the server. Before(...) hooks see the SQL and arguments,
+//After(...) hooks additionally see how long it took, the records affected or returned and the error.
+type QueryEvent struct {
+	SQL      string
+	Args     []interface{}
+	Start    time.Time
+	Duration time.Duration
+	Rows     int64
+	Err      error
+
+	//executor running the statement i.e. the transaction it is part of
+	exec Executor
+}
+
+//Middleware around every statement executed by GO(). The context returned by Before(...) is used
+//to execute the statement and is handed to After(...) i.e. to carry tracing spans.
+type Hook interface {
+	Before(ctx context.Context, event *QueryEvent) context.Context
+	After(ctx context.Context, event *QueryEvent)
+}
+
+//Convenience adapter for hooks that only care about finished statements
+type AfterFunc func(ctx context.Context, event *QueryEvent)
+
+func (f AfterFunc) Before(ctx context.Context, event *QueryEvent) context.Context {
+	return ctx
+}
+
+func (f AfterFunc) After(ctx context.Context, event *QueryEvent) {
+	f(ctx, event)
+}
+
+//Returns a copy of the query root with hooks appended to its chain. Before(...) hooks run in the
+//order they were added and After(...) hooks in reverse i.e. like nested middleware.
+func (q *SqlQuery) Use(hooks ...Hook) *SqlQuery {
+	c := *q
+	c.hooks = append(append([]Hook(nil), q.hooks...), hooks...)
+	return &c
+}
+
+//Run a statement through the hook chain
+func (q SqlQuery) observe(ssql string, args []interface{}, run func(ctx context.Context) (int64, error)) error {
+	ctx := q.context()
+	event := &QueryEvent{SQL: ssql, Args: args, Start: time.Now(), exec: q.exec}
+	//every hook gets the context its own Before(...) returned so each ends the span it started
+	contexts := make([]context.Context, len(q.hooks))
+	for i, hook := range q.hooks {
+		ctx = hook.Before(ctx, event)
+		contexts[i] = ctx
+	}
+	event.Rows, event.Err = run(ctx)
+	event.Duration = time.Since(event.Start)
+	for i := len(q.hooks) - 1; i >= 0; i-- {
+		q.hooks[i].After(contexts[i], event)
+	}
+	return event.Err
+}
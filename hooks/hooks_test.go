@@ -0,0 +1,157 @@
+package hooks_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/rayattack/supersql/hooks"
+	"github.com/rayattack/supersql/supersqltest"
+)
+
+type record struct {
+	level string
+	msg   string
+	attrs map[string]interface{}
+}
+
+type recorder struct {
+	records []record
+}
+
+func (r *recorder) log(level, msg string, args []interface{}) {
+	attrs := map[string]interface{}{}
+	for i := 0; i+1 < len(args); i += 2 {
+		attrs[fmt.Sprint(args[i])] = args[i+1]
+	}
+	r.records = append(r.records, record{level, msg, attrs})
+}
+
+func (r *recorder) InfoContext(ctx context.Context, msg string, args ...interface{}) {
+	r.log("info", msg, args)
+}
+
+func (r *recorder) ErrorContext(ctx context.Context, msg string, args ...interface{}) {
+	r.log("error", msg, args)
+}
+
+func TestLogger(t *testing.T) {
+	fake := supersqltest.New()
+	fake.Expect("FROM film").Returns([]string{"title"}, []interface{}{"Chamber Italian"})
+	fake.Expect("DELETE").Fails(errors.New("permission denied"))
+
+	logs := &recorder{}
+	q := fake.Root().Use(hooks.Logger(logs, hooks.LogOptions{Args: true}))
+	q.SELECT("title").FROM("film").WHERE("film_id = ?", 133).GO()
+	q.RUN("DELETE FROM film").GO()
+
+	if len(logs.records) != 2 {
+		t.Fatal(logs.records)
+	}
+	ok, failed := logs.records[0], logs.records[1]
+	if ok.level != "info" || ok.attrs["sql"] != "SELECT title FROM film WHERE film_id = $1" || ok.attrs["rows"] != int64(1) {
+		t.Log(ok)
+		t.Fail()
+	}
+	if args, _ := ok.attrs["args"].([]interface{}); len(args) != 1 {
+		t.Fail()
+	}
+	if failed.level != "error" || failed.attrs["error"] == nil {
+		t.Log(failed)
+		t.Fail()
+	}
+}
+
+type span struct {
+	name  string
+	attrs map[string]interface{}
+	err   error
+	ended bool
+	ends  int
+	order *[]*span
+}
+
+func (s *span) SetAttribute(key string, value interface{}) {
+	s.attrs[key] = value
+}
+
+func (s *span) RecordError(err error) {
+	s.err = err
+}
+
+func (s *span) End() {
+	s.ended = true
+	s.ends++
+	*s.order = append(*s.order, s)
+}
+
+type tracer struct {
+	spans []*span
+	//spans in the order they ended
+	ended []*span
+}
+
+func (t *tracer) Start(ctx context.Context, name string) (context.Context, hooks.Span) {
+	s := &span{name: name, attrs: map[string]interface{}{}, order: &t.ended}
+	t.spans = append(t.spans, s)
+	return ctx, s
+}
+
+func TestTracing(t *testing.T) {
+	broken := errors.New("deadlock detected")
+	fake := supersqltest.New()
+	fake.Expect("UPDATE").Fails(broken)
+
+	spans := &tracer{}
+	q := fake.Root().Use(hooks.Tracing(spans, "postgresql"))
+	q.RUN("UPDATE film SET rating = 'G'").WHERE("film_id = ?", 133).GO()
+
+	if len(spans.spans) != 1 {
+		t.Fatal(spans.spans)
+	}
+	s := spans.spans[0]
+	if s.name != "UPDATE" || !s.ended || s.err != broken || s.attrs["db.system"] != "postgresql" {
+		t.Log(s)
+		t.Fail()
+	}
+}
+
+func TestNestedTracing(t *testing.T) {
+	fake := supersqltest.New()
+	spans := &tracer{}
+	q := fake.Root().Use(hooks.Tracing(spans, "outer"), hooks.Tracing(spans, "inner"))
+	if _, err := q.SELECT("title").FROM("film").GO(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(spans.spans) != 2 {
+		t.Fatal(spans.spans)
+	}
+	outer, inner := spans.spans[0], spans.spans[1]
+	if outer.attrs["db.system"] != "outer" || inner.attrs["db.system"] != "inner" {
+		t.Fatal(outer.attrs, inner.attrs)
+	}
+	//like nested middleware every hook ends its own span once, the inner one first
+	if outer.ends != 1 || inner.ends != 1 || len(spans.ended) != 2 || spans.ended[0] != inner || spans.ended[1] != outer {
+		t.Fatal(outer.ends, inner.ends, spans.ended)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	fake := supersqltest.New()
+	histogram := hooks.NewHistogram(0.5, 1)
+	q := fake.Root().Use(hooks.Metrics(histogram))
+	for i := 0; i < 3; i++ {
+		q.SELECT().FROM("actor").GO()
+	}
+
+	selects := histogram.Series("SELECT", "ok")
+	if selects.Count != 3 || selects.Buckets[0] != 3 || selects.Buckets[1] != 3 {
+		t.Log(selects)
+		t.Fail()
+	}
+	if histogram.Series("SELECT", "error").Count != 0 {
+		t.Fail()
+	}
+}
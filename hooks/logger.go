@@ -0,0 +1,60 @@
+//Package hooks ships ready made supersql.Hook adapters for structured logging, tracing and metrics.
+//They depend on small interfaces instead of concrete libraries so log/slog, OpenTelemetry and
+//Prometheus can be plugged in with a line or two of glue and everything can be tested in-process.
+package hooks
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/rayattack/supersql"
+)
+
+//The subset of *slog.Logger used for logging statements. Arguments are alternating keys and values.
+type StructuredLogger interface {
+	InfoContext(ctx context.Context, msg string, args ...interface{})
+	ErrorContext(ctx context.Context, msg string, args ...interface{})
+}
+
+//Options for the Logger hook
+type LogOptions struct {
+	//Log the arguments bound to the statement. Off by default as they can contain personal data.
+	Args bool
+	//Only log successful statements slower than this, failures are always logged
+	Threshold time.Duration
+}
+
+//Log every finished statement with its duration, records affected or returned and error
+func Logger(logger StructuredLogger, options ...LogOptions) supersql.Hook {
+	var opts LogOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	return supersql.AfterFunc(func(ctx context.Context, event *supersql.QueryEvent) {
+		attrs := []interface{}{
+			"sql", event.SQL,
+			"duration", event.Duration,
+			"rows", event.Rows,
+		}
+		if opts.Args {
+			attrs = append(attrs, "args", event.Args)
+		}
+		if event.Err != nil {
+			logger.ErrorContext(ctx, "query failed", append(attrs, "error", event.Err)...)
+			return
+		}
+		if event.Duration >= opts.Threshold {
+			logger.InfoContext(ctx, "query", attrs...)
+		}
+	})
+}
+
+//First keyword of a statement i.e. SELECT, INSERT, UPDATE used to label spans and metrics
+func operation(ssql string) string {
+	fields := strings.Fields(ssql)
+	if len(fields) == 0 {
+		return "UNKNOWN"
+	}
+	return strings.ToUpper(fields[0])
+}
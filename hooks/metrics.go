@@ -0,0 +1,103 @@
+package hooks
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rayattack/supersql"
+)
+
+//Receives durations in seconds labelled with the operation and status ("ok" or "error") of a
+//statement. For Prometheus wrap a HistogramVec created with those two labels:
+//
+//	hooks.ObserverFunc(func(seconds float64, labels ...string) {
+//		vec.WithLabelValues(labels...).Observe(seconds)
+//	})
+type Observer interface {
+	Observe(seconds float64, labels ...string)
+}
+
+type ObserverFunc func(seconds float64, labels ...string)
+
+func (f ObserverFunc) Observe(seconds float64, labels ...string) {
+	f(seconds, labels...)
+}
+
+//Observe the duration of every statement
+func Metrics(observer Observer) supersql.Hook {
+	return supersql.AfterFunc(func(ctx context.Context, event *supersql.QueryEvent) {
+		status := "ok"
+		if event.Err != nil {
+			status = "error"
+		}
+		observer.Observe(event.Duration.Seconds(), operation(event.SQL), status)
+	})
+}
+
+//Default buckets in seconds, the same as the Prometheus client defaults
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+//In-process Prometheus style histogram with cumulative buckets per set of labels. Useful in tests
+//and for services that expose their own metrics endpoint.
+type Histogram struct {
+	mu      sync.Mutex
+	buckets []float64
+	series  map[string]*series
+}
+
+type series struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+//Snapshot of one labelled series of a Histogram. Buckets[i] counts observations less than or equal
+//to the i-th upper bound.
+type HistogramSeries struct {
+	Bounds  []float64
+	Buckets []uint64
+	Count   uint64
+	Sum     float64
+}
+
+func NewHistogram(buckets ...float64) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	bounds := append([]float64(nil), buckets...)
+	sort.Float64s(bounds)
+	return &Histogram{buckets: bounds, series: map[string]*series{}}
+}
+
+func (h *Histogram) Observe(seconds float64, labels ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	key := strings.Join(labels, "\x00")
+	s, ok := h.series[key]
+	if !ok {
+		s = &series{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	for i, bound := range h.buckets {
+		if seconds <= bound {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.sum += seconds
+}
+
+//Return the series for labels, the zero value when nothing was observed for them yet
+func (h *Histogram) Series(labels ...string) HistogramSeries {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	snapshot := HistogramSeries{Bounds: append([]float64(nil), h.buckets...), Buckets: make([]uint64, len(h.buckets))}
+	if s, ok := h.series[strings.Join(labels, "\x00")]; ok {
+		copy(snapshot.Buckets, s.counts)
+		snapshot.Count = s.count
+		snapshot.Sum = s.sum
+	}
+	return snapshot
+}
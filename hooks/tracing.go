@@ -0,0 +1,53 @@
+package hooks
+
+import (
+	"context"
+
+	"github.com/rayattack/supersql"
+)
+
+//Minimal span modelled on OpenTelemetry's trace.Span. An OpenTelemetry span is adapted by converting
+//attributes with attribute.String/Int64 and recording the error with span.RecordError.
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+//Starts spans, modelled on OpenTelemetry's trace.Tracer
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+type spanKey struct{}
+
+type tracing struct {
+	tracer Tracer
+	system string
+}
+
+//Wrap every statement in a span named after its operation i.e. "SELECT" using the attribute names
+//of the OpenTelemetry database semantic conventions
+func Tracing(tracer Tracer, system string) supersql.Hook {
+	return tracing{tracer, system}
+}
+
+func (t tracing) Before(ctx context.Context, event *supersql.QueryEvent) context.Context {
+	ctx, span := t.tracer.Start(ctx, operation(event.SQL))
+	span.SetAttribute("db.system", t.system)
+	span.SetAttribute("db.statement", event.SQL)
+	span.SetAttribute("db.operation", operation(event.SQL))
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+func (t tracing) After(ctx context.Context, event *supersql.QueryEvent) {
+	span, ok := ctx.Value(spanKey{}).(Span)
+	if !ok {
+		return
+	}
+	span.SetAttribute("db.rows", event.Rows)
+	if event.Err != nil {
+		span.RecordError(event.Err)
+	}
+	span.End()
+}
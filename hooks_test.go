@@ -0,0 +1,49 @@
+package supersql_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rayattack/supersql"
+	"github.com/rayattack/supersql/supersqltest"
+)
+
+type orderedHook struct {
+	name  string
+	trail *[]string
+}
+
+func (h orderedHook) Before(ctx context.Context, event *supersql.QueryEvent) context.Context {
+	*h.trail = append(*h.trail, "before "+h.name)
+	return ctx
+}
+
+func (h orderedHook) After(ctx context.Context, event *supersql.QueryEvent) {
+	*h.trail = append(*h.trail, "after "+h.name)
+}
+
+func TestHookChain(t *testing.T) {
+	trail := []string{}
+	fake := supersqltest.New()
+	fake.Expect("DELETE").Affects(4)
+
+	var seen *supersql.QueryEvent
+	q := fake.Root().Use(orderedHook{"outer", &trail}, orderedHook{"inner", &trail})
+	q = q.Use(supersql.AfterFunc(func(ctx context.Context, event *supersql.QueryEvent) {
+		seen = event
+	}))
+	q.RUN("DELETE FROM rental WHERE rental_id = 1").GO()
+
+	expected := []string{"before outer", "before inner", "after inner", "after outer"}
+	if len(trail) != len(expected) {
+		t.Fatal(trail)
+	}
+	for i := range expected {
+		if trail[i] != expected[i] {
+			t.Fatal(trail)
+		}
+	}
+	if seen == nil || seen.Rows != 4 || seen.Err != nil || seen.Start.IsZero() {
+		t.Fail()
+	}
+}
@@ -0,0 +1,105 @@
+package supersql
+
+import (
+	"fmt"
+	"strings"
+)
+
+//Words postgres reserves that can only be used as table names when quoted
+var keywords = map[string]bool{
+	"all": true, "analyse": true, "analyze": true, "and": true, "any": true, "array": true, "as": true,
+	"asc": true, "asymmetric": true, "authorization": true, "binary": true, "both": true, "case": true,
+	"cast": true, "check": true, "collate": true, "collation": true, "column": true, "concurrently": true,
+	"constraint": true, "create": true, "cross": true, "current_catalog": true, "current_date": true,
+	"current_role": true, "current_schema": true, "current_time": true, "current_timestamp": true,
+	"current_user": true, "default": true, "deferrable": true, "desc": true, "distinct": true, "do": true,
+	"else": true, "end": true, "except": true, "false": true, "fetch": true, "for": true, "foreign": true,
+	"freeze": true, "from": true, "full": true, "grant": true, "group": true, "having": true, "ilike": true,
+	"in": true, "initially": true, "inner": true, "intersect": true, "into": true, "is": true, "isnull": true,
+	"join": true, "lateral": true, "leading": true, "left": true, "like": true, "limit": true,
+	"localtime": true, "localtimestamp": true, "natural": true, "not": true, "notnull": true, "null": true,
+	"offset": true, "on": true, "only": true, "or": true, "order": true, "outer": true, "overlaps": true,
+	"placing": true, "primary": true, "references": true, "returning": true, "right": true, "select": true,
+	"session_user": true, "similar": true, "some": true, "symmetric": true, "system_user": true,
+	"table": true, "tablesample": true, "then": true, "to": true, "trailing": true, "true": true,
+	"union": true, "unique": true, "user": true, "using": true, "variadic": true, "verbose": true,
+	"when": true, "where": true, "window": true, "with": true,
+}
+
+//Quote identifier with the marks of dialect when it would not survive unquoted i.e. mixed case
+//names, reserved words and names with spaces. Plain lower case names are left alone.
+func quoteIdentifier(dialect Dialect, identifier string) string {
+	plain := identifier != "" && !keywords[identifier]
+	for i, r := range identifier {
+		if !(r == '_' || (r >= 'a' && r <= 'z') || (i > 0 && (r == '$' || (r >= '0' && r <= '9')))) {
+			plain = false
+		}
+	}
+	if plain {
+		return identifier
+	}
+	return dialect.Quote(identifier)
+}
+
+//Split a possibly schema qualified name at the dots outside double quotes and unquote the parts
+//i.e. billing."Invoice" becomes billing and Invoice
+func splitIdentifier(name string) []string {
+	parts := []string{}
+	current := strings.Builder{}
+	quoted := false
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c == '"' && quoted && i+1 < len(name) && name[i+1] == '"':
+			current.WriteByte(c)
+			i++
+		case c == '"':
+			quoted = !quoted
+		case c == '.' && !quoted:
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(c)
+		}
+	}
+	return append(parts, current.String())
+}
+
+//Render the schema qualified name of the table for dialect, the one place table names are quoted
+func (t *SqlTable) identifier(dialect Dialect) string {
+	name := quoteIdentifier(dialect, t.name)
+	if t.schema != "" {
+		name = quoteIdentifier(dialect, t.schema) + "." + name
+	}
+	return name
+}
+
+//The schema qualified name of the table quoted for its dialect where needed, for SQL written by hand
+func (t *SqlTable) Identifier() string {
+	return t.identifier(t.dialectOf())
+}
+
+//A column, index or constraint name quoted for postgres where needed, for SQL written by hand
+func QuoteIdentifier(name string) string {
+	return quoteIdentifier(Postgres, name)
+}
+
+//Table names given as strings are SQL written by hand and used verbatim, declared tables are
+//rendered by the dialect of the query followed by their alias
+func (q SqlQuery) relation(entity interface{}) string {
+	if t, ok := entity.(*SqlTable); ok {
+		if t.alias != "" {
+			return fmt.Sprintf("%s %s", t.identifier(q.dialectOf()), t.alias)
+		}
+		return t.identifier(q.dialectOf())
+	}
+	return coerceToString(entity)
+}
+
+//Tables written to are aliased with AS i.e. INSERT INTO film AS f
+func (q SqlQuery) written(entity interface{}) string {
+	if t, ok := entity.(*SqlTable); ok && t.alias != "" {
+		return fmt.Sprintf("%s AS %s", t.identifier(q.dialectOf()), t.alias)
+	}
+	return q.relation(entity)
+}
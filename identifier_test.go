@@ -0,0 +1,91 @@
+package supersql_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rayattack/supersql"
+	"github.com/rayattack/supersql/supersqltest"
+)
+
+type copying struct {
+	*supersqltest.Fake
+	table   string
+	columns []string
+}
+
+func (c *copying) CopyFrom(ctx context.Context, table string, columns []string, rows [][]interface{}) (int64, error) {
+	c.table, c.columns = table, columns
+	return int64(len(rows)), nil
+}
+
+func TestSchemaQualifiedTables(t *testing.T) {
+	invoice := supersql.Table("billing.invoice", supersql.BigInt("invoice_id"), supersql.Numeric("total", 10, 2))
+	if invoice.Name() != "billing.invoice" || invoice.Schema() != "billing" || invoice.Identifier() != "billing.invoice" {
+		t.Fatal(invoice.Name(), invoice.Schema(), invoice.Identifier())
+	}
+	query := Xql.SELECT("billing.invoice.total", "i.invoice_id").FROM(invoice)
+	if query.PP() != "SELECT billing.invoice.total, i.invoice_id FROM billing.invoice" {
+		t.Fatal(query.PP())
+	}
+	if _, _, err := query.SQL(); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := Xql.SELECT("invoice.tax").FROM(invoice).SQL(); err == nil {
+		t.Fatal("qualified references should be checked against the table")
+	}
+
+	moved := supersql.Table("audit_log").InSchema("Audit")
+	if moved.Identifier() != `"Audit".audit_log` || moved.CREATE() != `CREATE TABLE "Audit".audit_log ()` {
+		t.Fatal(moved.Identifier(), moved.CREATE())
+	}
+}
+
+func TestQuotedTables(t *testing.T) {
+	user := supersql.Table("user", supersql.BigInt("user_id"), supersql.Text("email"))
+	order := supersql.Table(`public."Order"`, supersql.BigInt("order_id"), supersql.BigInt("user_id"))
+	if order.Name() != "public.Order" || order.Identifier() != `public."Order"` {
+		t.Fatal(order.Name(), order.Identifier())
+	}
+
+	query := Xql.SELECT("email").FROM(user).JOIN(order).ON("order_id = user_id")
+	if query.PP() != `SELECT email FROM "user" JOIN public."Order" ON order_id = user_id` {
+		t.Fatal(query.PP())
+	}
+	insert := Xql.INSERT_INTO(user, []string{"user_id", "email"}).VALUES([]interface{}{1, "a@b.c"})
+	if sql, args, _ := insert.SQL(); sql != `INSERT INTO "user" (user_id, email) VALUES ($1, $2)` || len(args) != 2 || args[1] != "a@b.c" {
+		t.Fatal(sql, args)
+	}
+	if user.CREATE() != `CREATE TABLE "user" (user_id bigint, email text)` || user.DROP().String() != `DROP TABLE "user"` {
+		t.Fatal(user.CREATE(), user.DROP().String())
+	}
+	if ref := supersql.BigInt("user_id", supersql.REFERENCES(user)); ref.DDL() != `user_id bigint REFERENCES "user"` {
+		t.Fatal(ref.DDL())
+	}
+	if index := supersql.Index("order_user", order, "user_id"); index.CREATE() != `CREATE INDEX order_user ON public."Order" (user_id)` {
+		t.Fatal(index.CREATE())
+	}
+
+	//the dialect of the query decides the quotes
+	mysql := new(supersql.SqlQuery).WithDialect(supersql.MySQL)
+	if pp := mysql.SELECT().FROM(order).PP(); pp != "SELECT * FROM public.`Order`" {
+		t.Fatal(pp)
+	}
+
+	//names written as strings are used verbatim
+	if pp := Xql.SELECT().FROM("billing.Invoice i").PP(); pp != "SELECT * FROM billing.Invoice i" {
+		t.Fatal(pp)
+	}
+}
+
+func TestCopyIntoQualifiedTable(t *testing.T) {
+	executor := &copying{Fake: supersqltest.New()}
+	q := supersql.QueryWith(context.Background(), executor, supersql.Postgres)
+	invoice := supersql.Table(`billing."Invoice"`, supersql.BigInt("invoice_id"))
+	if _, err := q.INSERT_INTO(invoice, []string{"invoice_id"}).VALUES([]interface{}{1}, []interface{}{2}).GO(); err != nil {
+		t.Fatal(err)
+	}
+	if executor.table != `billing."Invoice"` || executor.columns[0] != "invoice_id" {
+		t.Fatal(executor.table, executor.columns)
+	}
+}
@@ -0,0 +1,131 @@
+package supersql
+
+import (
+	"fmt"
+	"strings"
+)
+
+//Access method of an index
+type IndexMethod string
+
+const (
+	BTREE  IndexMethod = "btree"
+	HASH   IndexMethod = "hash"
+	GIN    IndexMethod = "gin"
+	GIST   IndexMethod = "gist"
+	SPGIST IndexMethod = "spgist"
+	BRIN   IndexMethod = "brin"
+)
+
+//Declaration of an index. Every modifier returns a copy so a declaration can be used as the base of
+//others. Keys are column names or expressions i.e. lower(email).
+//
+//	idx := supersql.Index("film_fulltext", film, "fulltext").USING(supersql.GIN).CONCURRENTLY()
+//	migrate.Migration{Up: idx.CREATE(), Down: idx.DROP().String(), NoTransaction: true, DownNoTransaction: true}
+type SqlIndex struct {
+	name         string
+	table        string
+	schema       string
+	dialect      Dialect
+	keys         []string
+	include      []string
+	method       IndexMethod
+	where        string
+	unique       bool
+	concurrently bool
+	ifNotExists  bool
+}
+
+//Indexes live in the schema of their table which is taken from a declared table or from a schema
+//qualified table name i.e. "billing.invoice"
+func Index(name string, table interface{}, keys ...string) SqlIndex {
+	schema, dialect := "", Postgres
+	switch t := table.(type) {
+	case *SqlTable:
+		schema, dialect = t.Schema(), t.dialectOf()
+	case string:
+		schema = Table(t).Schema()
+	}
+	return SqlIndex{name: name, table: coerceToString(table), schema: schema, dialect: dialect, keys: keys}
+}
+
+func (i SqlIndex) UNIQUE() SqlIndex {
+	i.unique = true
+	return i
+}
+
+//Build the index without locking out writes. Postgres refuses to do this inside a transaction so
+//migrations creating or dropping indexes concurrently need NoTransaction and DownNoTransaction.
+func (i SqlIndex) CONCURRENTLY() SqlIndex {
+	i.concurrently = true
+	return i
+}
+
+func (i SqlIndex) IF_NOT_EXISTS() SqlIndex {
+	i.ifNotExists = true
+	return i
+}
+
+func (i SqlIndex) USING(method IndexMethod) SqlIndex {
+	i.method = method
+	return i
+}
+
+//Only index records matching predicate i.e. WHERE("deleted_at IS NULL")
+func (i SqlIndex) WHERE(predicate string) SqlIndex {
+	i.where = predicate
+	return i
+}
+
+//Store columns in the index that are not part of the key for index only scans
+func (i SqlIndex) INCLUDE(columns ...string) SqlIndex {
+	i.include = append(i.include[:len(i.include):len(i.include)], columns...)
+	return i
+}
+
+func (i SqlIndex) Name() string {
+	return i.name
+}
+
+func (i SqlIndex) Table() string {
+	return i.table
+}
+
+//Generate the CREATE INDEX statement
+func (i SqlIndex) CREATE() string {
+	parts := []string{"CREATE"}
+	if i.unique {
+		parts = append(parts, "UNIQUE")
+	}
+	parts = append(parts, "INDEX")
+	if i.concurrently {
+		parts = append(parts, "CONCURRENTLY")
+	}
+	if i.ifNotExists {
+		parts = append(parts, "IF NOT EXISTS")
+	}
+	//the index is created in the schema of its table, its name can not be qualified here
+	parts = append(parts, quoteIdentifier(i.dialect, i.name), "ON", i.table)
+	if i.method != "" {
+		parts = append(parts, "USING", string(i.method))
+	}
+	parts = append(parts, fmt.Sprintf("(%s)", strings.Join(i.keys, ", ")))
+	if len(i.include) > 0 {
+		parts = append(parts, fmt.Sprintf("INCLUDE (%s)", strings.Join(i.include, ", ")))
+	}
+	if i.where != "" {
+		parts = append(parts, "WHERE", i.where)
+	}
+	return strings.Join(parts, " ")
+}
+
+func (i SqlIndex) String() string {
+	return i.CREATE()
+}
+
+//Statement dropping the index, concurrently when the index is built concurrently. The name is
+//qualified with the schema of the table so indexes outside the search path are found.
+func (i SqlIndex) DROP() SqlDrop {
+	name := (&SqlTable{name: i.name, schema: i.schema}).identifier(i.dialect)
+	return SqlDrop{kind: "INDEX", name: name, concurrently: i.concurrently}
+}
@@ -0,0 +1,47 @@
+package supersql_test
+
+import (
+	"testing"
+
+	"github.com/rayattack/supersql"
+)
+
+func TestIndex(t *testing.T) {
+	film := supersql.Table("film")
+	plain := supersql.Index("film_title", film, "title")
+	if plain.CREATE() != "CREATE INDEX film_title ON film (title)" || plain.Table() != "film" {
+		t.Fatal(plain.CREATE())
+	}
+
+	covering := plain.UNIQUE().INCLUDE("film_id", "rating").WHERE("deleted_at IS NULL").IF_NOT_EXISTS()
+	if covering.CREATE() != "CREATE UNIQUE INDEX IF NOT EXISTS film_title ON film (title) INCLUDE (film_id, rating) WHERE deleted_at IS NULL" {
+		t.Fatal(covering.CREATE())
+	}
+	//modifiers return copies
+	if plain.CREATE() != "CREATE INDEX film_title ON film (title)" {
+		t.Fatal(plain.CREATE())
+	}
+
+	fulltext := supersql.Index("film_fulltext", "film", "to_tsvector('english', title)").USING(supersql.GIN).CONCURRENTLY()
+	if fulltext.String() != "CREATE INDEX CONCURRENTLY film_fulltext ON film USING gin (to_tsvector('english', title))" {
+		t.Fatal(fulltext.String())
+	}
+	if drop := fulltext.DROP().IF_EXISTS().String(); drop != "DROP INDEX CONCURRENTLY IF EXISTS film_fulltext" {
+		t.Fatal(drop)
+	}
+	if drop := plain.DROP().CASCADE().String(); drop != "DROP INDEX film_title CASCADE" {
+		t.Fatal(drop)
+	}
+
+	invoice := supersql.Table("invoice").InSchema("billing")
+	paid := supersql.Index("Invoice_Paid", invoice, "paid_at")
+	if paid.CREATE() != `CREATE INDEX "Invoice_Paid" ON billing.invoice (paid_at)` {
+		t.Fatal(paid.CREATE())
+	}
+	if drop := paid.DROP().String(); drop != `DROP INDEX billing."Invoice_Paid"` {
+		t.Fatal(drop)
+	}
+	if drop := supersql.Index("invoice_due", "billing.invoice", "due_at").DROP().String(); drop != "DROP INDEX billing.invoice_due" {
+		t.Fatal(drop)
+	}
+}
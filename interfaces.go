@@ -1,7 +1,8 @@
 package supersql
 
 type Relation interface {
-	AS(alias ...string) string
+	AS(alias string) *SqlTable
+	Col(column string) string
 	DDL(ddl string) error
 }
 
@@ -10,6 +11,12 @@ type Command interface {
 	ASC(col ...string) Command
 	RUN(ddl string) Command
 	DESC(col ...string) Command
+	DO_NOTHING() Command
+	DO_UPDATE(columns ...string) Command
+	FOR_KEY_SHARE(of ...interface{}) Command
+	FOR_NO_KEY_UPDATE(of ...interface{}) Command
+	FOR_SHARE(of ...interface{}) Command
+	FOR_UPDATE(of ...interface{}) Command
 	FROM(entities ...interface{}) Command
 	GO(prefetch ...int) (Results, error)
 	INSERT(columns ...string) Command
@@ -17,13 +24,20 @@ type Command interface {
 	INTO(entity interface{}) Command
 	JOIN(entity interface{}) Command
 	LIMIT(count int) Command
+	NOWAIT() Command
 	OFFSET(count int) Command
 	ON(statement string, conditions ...interface{}) Command
+	ON_CONFLICT(columns ...string) Command
 	ORDER_BY(ob string) Command
 	PP() string
+	RAW(ssql string, args ...interface{}) Command
+	RETURNING(columns ...string) Command
 	SELECT(columns ...string) Command
+	SKIP_LOCKED() Command
+	SQL() (string, []interface{}, error)
 	VALUES(values ...[]interface{}) Command
 	WHERE(statement string, conditions ...interface{}) Command
+	WINDOW(name string, window SqlWindow) Command
 }
 
 type Db interface {
@@ -46,6 +60,7 @@ type Row interface {
 	Scan(dest ...interface{}) error
 	String(col string) (string, error)
 	Integer(col string) (int, error)
+	Int64(col string) (int64, error)
 	Boolean(col string) (bool, error)
 	Float(col string) (float64, error)
 	Map(col string) (map[string]interface{}, error)
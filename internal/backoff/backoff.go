@@ -0,0 +1,16 @@
+//Package backoff holds the retry delays shared by the packages that reschedule failed work
+package backoff
+
+import "time"
+
+//Exponential backoff from a second doubling with every attempt up to an hour
+func Exponential(attempt int) time.Duration {
+	if attempt > 12 {
+		return time.Hour
+	}
+	delay := time.Second << uint(attempt)
+	if delay > time.Hour {
+		return time.Hour
+	}
+	return delay
+}
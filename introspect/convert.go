@@ -0,0 +1,141 @@
+package introspect
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rayattack/supersql"
+)
+
+//Key columns of the primary key in key order, nil when the table has none
+func (t *Table) PrimaryKey() []string {
+	for _, c := range t.Constraints {
+		if c.Type == "PRIMARY KEY" {
+			return c.Columns
+		}
+	}
+	return nil
+}
+
+func (t *Table) Column(name string) (Column, bool) {
+	for _, c := range t.Columns {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Column{}, false
+}
+
+//The table as a supersql definition. Constraints spanning a single column become options of that
+//column's Field, constraints spanning several columns and indexes have no Field equivalent and are
+//only available on Table.
+func (t *Table) SqlTable() *supersql.SqlTable {
+	fields := []supersql.Field{}
+	for _, c := range t.Columns {
+		fields = append(fields, t.field(c))
+	}
+	name := t.Name
+	if t.Schema != "" && t.Schema != "public" {
+		name = fmt.Sprintf("%s.%s", t.Schema, t.Name)
+	}
+	table := supersql.Table(name, fields...)
+	if strategy, keys, ok := strings.Cut(t.Partitioning, " "); ok {
+		keys := topLevel(strings.TrimSuffix(strings.TrimPrefix(keys, "("), ")"))
+		switch strategy {
+		case "RANGE":
+			table.PARTITION_BY_RANGE(keys...)
+		case "LIST":
+			table.PARTITION_BY_LIST(keys[0])
+		case "HASH":
+			table.PARTITION_BY_HASH(keys...)
+		}
+	}
+	return table
+}
+
+//Split a comma separated list without splitting the arguments of function calls within it
+func topLevel(list string) []string {
+	elements, depth, start := []string{}, 0, 0
+	for i, r := range list {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				elements = append(elements, strings.TrimSpace(list[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(elements, strings.TrimSpace(list[start:]))
+}
+
+func (t *Table) field(c Column) supersql.Field {
+	options := []interface{}{}
+	primary := t.only("PRIMARY KEY", c.Name) != nil
+
+	switch {
+	case primary:
+		options = append(options, supersql.PRIMARY_KEY)
+	case !c.Nullable:
+		options = append(options, supersql.NOT_NULL)
+	}
+
+	switch {
+	case c.Identity == "ALWAYS":
+		options = append(options, supersql.GENERATED_ALWAYS_AS_IDENTITY)
+	case c.Identity == "BY DEFAULT":
+		options = append(options, supersql.GENERATED_BY_DEFAULT_AS_IDENTITY)
+	case c.Generated:
+		options = append(options, supersql.GENERATED_ALWAYS_AS(c.Default))
+	case c.Default != "":
+		options = append(options, supersql.DEFAULT(c.Default))
+	}
+
+	if t.only("UNIQUE", c.Name) != nil {
+		options = append(options, supersql.UNIQUE)
+	}
+	if check := t.only("CHECK", c.Name); check != nil {
+		options = append(options, supersql.CHECK(unwrap(check.Definition)))
+	}
+	for _, fk := range t.ForeignKeys {
+		if len(fk.Columns) != 1 || fk.Columns[0] != c.Name {
+			continue
+		}
+		ref := fk.RefTable
+		if fk.RefSchema != "" && fk.RefSchema != t.Schema {
+			ref = fmt.Sprintf("%s.%s", fk.RefSchema, fk.RefTable)
+		}
+		constraint := supersql.REFERENCES(ref, fk.RefColumns...)
+		if fk.OnDelete != "" && fk.OnDelete != supersql.NO_ACTION {
+			constraint = constraint.ON_DELETE(fk.OnDelete)
+		}
+		if fk.OnUpdate != "" && fk.OnUpdate != supersql.NO_ACTION {
+			constraint = constraint.ON_UPDATE(fk.OnUpdate)
+		}
+		options = append(options, constraint)
+	}
+	return supersql.Typed(c.Name, c.Type, options...)
+}
+
+//Constraint of the given type covering column and nothing else
+func (t *Table) only(kind string, column string) *Constraint {
+	for i, c := range t.Constraints {
+		if c.Type == kind && len(c.Columns) == 1 && c.Columns[0] == column {
+			return &t.Constraints[i]
+		}
+	}
+	return nil
+}
+
+//Expression of a CHECK definition i.e. rental_rate >= 0::numeric for CHECK (rental_rate >= 0::numeric)
+func unwrap(definition string) string {
+	expr := strings.TrimSpace(strings.TrimPrefix(definition, "CHECK"))
+	expr = strings.TrimSpace(strings.TrimSuffix(expr, "NOT VALID"))
+	if strings.HasPrefix(expr, "(") && strings.HasSuffix(expr, ")") {
+		expr = expr[1 : len(expr)-1]
+	}
+	return expr
+}
@@ -0,0 +1,54 @@
+package introspect
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rayattack/supersql"
+)
+
+type Database struct {
+	Name     string
+	Owner    string
+	Encoding string
+	//The database can be used as the TEMPLATE of new databases
+	Template bool
+}
+
+//Read the databases of the server the query root is connected to in the order of their names
+func Databases(ctx context.Context, q *supersql.SqlQuery) ([]Database, error) {
+	r, err := q.WithContext(ctx).SELECT(
+		"d.datname AS name",
+		"pg_get_userbyid(d.datdba) AS owner",
+		"pg_encoding_to_char(d.encoding) AS encoding",
+		"d.datistemplate AS template",
+	).FROM("pg_database d").ORDER_BY("d.datname").GO()
+	if err != nil {
+		return nil, fmt.Errorf("introspect: %w", err)
+	}
+	databases := []Database{}
+	for _, row := range r.All() {
+		databases = append(databases, Database{
+			Name:     text(row, "name"),
+			Owner:    text(row, "owner"),
+			Encoding: text(row, "encoding"),
+			Template: flag(row, "template"),
+		})
+	}
+	return databases, nil
+}
+
+//Names of the schemas of the connected database leaving out the pg_ schemas and information_schema
+func Schemas(ctx context.Context, q *supersql.SqlQuery) ([]string, error) {
+	r, err := q.WithContext(ctx).SELECT("nspname AS name").FROM("pg_namespace").WHERE(
+		"nspname NOT LIKE 'pg\\_%' AND nspname <> 'information_schema'",
+	).ORDER_BY("nspname").GO()
+	if err != nil {
+		return nil, fmt.Errorf("introspect: %w", err)
+	}
+	schemas := []string{}
+	for _, row := range r.All() {
+		schemas = append(schemas, text(row, "name"))
+	}
+	return schemas, nil
+}
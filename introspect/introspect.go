@@ -0,0 +1,418 @@
+//Package introspect reads the structure of a postgres schema from pg_catalog into plain Go structs
+//that can be inspected, compared or converted back into supersql table definitions.
+//
+//	schema, err := introspect.Inspect(ctx, q, "public")
+//	film := schema.Table("film").SqlTable()
+//	film.CREATE() // CREATE TABLE film (film_id integer PRIMARY KEY ...)
+package introspect
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rayattack/supersql"
+)
+
+//Separates the elements of arrays aggregated by the catalog queries. Arrays are flattened into text
+//because drivers decode name[] and text[] into driver specific types.
+const separator = "\x1f"
+
+type Schema struct {
+	Name      string
+	Tables    []*Table
+	Views     []View
+	Sequences []Sequence
+	Enums     []Enum
+}
+
+//Table with the given name or nil if the schema has no such table
+func (s *Schema) Table(name string) *Table {
+	for _, t := range s.Tables {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+//Enumerated type with the given name or nil if the schema has no such type
+func (s *Schema) Enum(name string) *Enum {
+	for i := range s.Enums {
+		if s.Enums[i].Name == name {
+			return &s.Enums[i]
+		}
+	}
+	return nil
+}
+
+type Table struct {
+	Schema  string
+	Name    string
+	Comment string
+	//Partition key of partitioned tables as reported by pg_get_partkeydef i.e. RANGE (created_at)
+	Partitioning string
+	Columns     []Column
+	Constraints []Constraint
+	Indexes     []Index
+	ForeignKeys []ForeignKey
+}
+
+type Column struct {
+	Name string
+	//Type as written in DDL i.e. character varying(45) or integer[]
+	Type     string
+	Nullable bool
+	//Default expression or the generation expression of generated columns, empty when there is none
+	Default string
+	//ALWAYS or BY DEFAULT for identity columns, empty otherwise
+	Identity  string
+	Generated bool
+	Comment   string
+	Position  int
+}
+
+//Table constraint as reported by pg_get_constraintdef i.e. Type UNIQUE with Definition UNIQUE (email).
+//Type is one of PRIMARY KEY, UNIQUE, CHECK, FOREIGN KEY or EXCLUDE.
+type Constraint struct {
+	Name       string
+	Type       string
+	Columns    []string
+	Definition string
+}
+
+type Index struct {
+	Name string
+	//Key columns or expressions in index order, INCLUDE columns are not part of the key
+	Columns []string
+	Unique  bool
+	Primary bool
+	//Access method i.e. btree, gin or brin
+	Method string
+	//WHERE clause of partial indexes, empty otherwise
+	Predicate  string
+	Definition string
+}
+
+type ForeignKey struct {
+	Name       string
+	Columns    []string
+	RefSchema  string
+	RefTable   string
+	RefColumns []string
+	OnDelete   supersql.Action
+	OnUpdate   supersql.Action
+}
+
+type View struct {
+	Name         string
+	Definition   string
+	Materialized bool
+	Comment      string
+}
+
+type Sequence struct {
+	Name      string
+	Type      string
+	Start     int64
+	Increment int64
+	Min       int64
+	Max       int64
+	Cycle     bool
+}
+
+type Enum struct {
+	Name   string
+	Values []string
+}
+
+//The enumerated type as a supersql definition
+func (e Enum) SqlEnum() *supersql.SqlEnum {
+	return supersql.EnumType(e.Name, e.Values...)
+}
+
+//Read tables, columns, constraints, indexes, views, sequences and enumerated types of schema, public
+//when schema is empty
+func Inspect(ctx context.Context, q *supersql.SqlQuery, schema string) (*Schema, error) {
+	if schema == "" {
+		schema = "public"
+	}
+	q = q.WithContext(ctx)
+	s := &Schema{Name: schema}
+
+	steps := []func(*supersql.SqlQuery, *Schema) error{tables, columns, constraints, indexes, views, sequences, enums}
+	for _, step := range steps {
+		if err := step(q, s); err != nil {
+			return nil, fmt.Errorf("introspect: %w", err)
+		}
+	}
+	return s, nil
+}
+
+func tables(q *supersql.SqlQuery, s *Schema) error {
+	r, err := q.SELECT(
+		"c.relname AS name",
+		"COALESCE(obj_description(c.oid, 'pg_class'), '') AS comment",
+		"COALESCE(pg_get_partkeydef(c.oid), '') AS partitioning",
+	).FROM(
+		"pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace",
+	).WHERE("n.nspname = ? AND c.relkind IN ('r', 'p') AND NOT c.relispartition", s.Name).ORDER_BY("c.relname").GO()
+	if err != nil {
+		return err
+	}
+	for _, row := range r.All() {
+		s.Tables = append(s.Tables, &Table{
+			Schema:       s.Name,
+			Name:         text(row, "name"),
+			Comment:      text(row, "comment"),
+			Partitioning: text(row, "partitioning"),
+		})
+	}
+	return nil
+}
+
+func columns(q *supersql.SqlQuery, s *Schema) error {
+	r, err := q.SELECT(
+		"c.relname AS table_name",
+		"a.attname AS name",
+		"format_type(a.atttypid, a.atttypmod) AS type",
+		"NOT a.attnotnull AS nullable",
+		"COALESCE(pg_get_expr(d.adbin, d.adrelid), '') AS default_value",
+		"a.attidentity::text AS identity",
+		"a.attgenerated::text AS generated",
+		"COALESCE(col_description(c.oid, a.attnum), '') AS comment",
+		"a.attnum::int AS position",
+	).FROM(
+		"pg_attribute a JOIN pg_class c ON c.oid = a.attrelid JOIN pg_namespace n ON n.oid = c.relnamespace " +
+			"LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum",
+	).WHERE(
+		"n.nspname = ? AND c.relkind IN ('r', 'p') AND NOT c.relispartition AND a.attnum > 0 AND NOT a.attisdropped", s.Name,
+	).ORDER_BY("c.relname, a.attnum").GO()
+	if err != nil {
+		return err
+	}
+	for _, row := range r.All() {
+		t := s.Table(text(row, "table_name"))
+		if t == nil {
+			continue
+		}
+		identity := ""
+		switch text(row, "identity") {
+		case "a":
+			identity = "ALWAYS"
+		case "d":
+			identity = "BY DEFAULT"
+		}
+		t.Columns = append(t.Columns, Column{
+			Name:      text(row, "name"),
+			Type:      text(row, "type"),
+			Nullable:  flag(row, "nullable"),
+			Default:   text(row, "default_value"),
+			Identity:  identity,
+			Generated: text(row, "generated") == "s",
+			Comment:   text(row, "comment"),
+			Position:  int(number(row, "position")),
+		})
+	}
+	return nil
+}
+
+var constraintTypes = map[string]string{
+	"p": "PRIMARY KEY",
+	"u": "UNIQUE",
+	"c": "CHECK",
+	"f": "FOREIGN KEY",
+	"x": "EXCLUDE",
+}
+
+var actions = map[string]supersql.Action{
+	"a": supersql.NO_ACTION,
+	"r": supersql.RESTRICT,
+	"c": supersql.CASCADE,
+	"n": supersql.SET_NULL,
+	"d": supersql.SET_DEFAULT,
+}
+
+func constraints(q *supersql.SqlQuery, s *Schema) error {
+	attnames := func(rel, keys string) string {
+		return fmt.Sprintf("array_to_string(ARRAY(SELECT a.attname FROM unnest(k.%s) WITH ORDINALITY u(attnum, ord) "+
+			"JOIN pg_attribute a ON a.attrelid = k.%s AND a.attnum = u.attnum ORDER BY u.ord), chr(31))", keys, rel)
+	}
+	r, err := q.SELECT(
+		"c.relname AS table_name",
+		"k.conname AS name",
+		"k.contype::text AS kind",
+		attnames("conrelid", "conkey")+" AS columns",
+		"pg_get_constraintdef(k.oid, true) AS definition",
+		"COALESCE(fn.nspname, '') AS ref_schema",
+		"COALESCE(f.relname, '') AS ref_table",
+		attnames("confrelid", "confkey")+" AS ref_columns",
+		"k.confdeltype::text AS on_delete",
+		"k.confupdtype::text AS on_update",
+	).FROM(
+		"pg_constraint k JOIN pg_class c ON c.oid = k.conrelid JOIN pg_namespace n ON n.oid = c.relnamespace " +
+			"LEFT JOIN pg_class f ON f.oid = k.confrelid LEFT JOIN pg_namespace fn ON fn.oid = f.relnamespace",
+	).WHERE("n.nspname = ?", s.Name).ORDER_BY("c.relname, k.conname").GO()
+	if err != nil {
+		return err
+	}
+	for _, row := range r.All() {
+		t := s.Table(text(row, "table_name"))
+		kind, ok := constraintTypes[text(row, "kind")]
+		if t == nil || !ok {
+			//not null constraints are reported by postgres 18 and above but are already Column.Nullable
+			continue
+		}
+		c := Constraint{Name: text(row, "name"), Type: kind, Columns: split(row, "columns"), Definition: text(row, "definition")}
+		t.Constraints = append(t.Constraints, c)
+		if kind == "FOREIGN KEY" {
+			t.ForeignKeys = append(t.ForeignKeys, ForeignKey{
+				Name:       c.Name,
+				Columns:    c.Columns,
+				RefSchema:  text(row, "ref_schema"),
+				RefTable:   text(row, "ref_table"),
+				RefColumns: split(row, "ref_columns"),
+				OnDelete:   actions[text(row, "on_delete")],
+				OnUpdate:   actions[text(row, "on_update")],
+			})
+		}
+	}
+	return nil
+}
+
+func indexes(q *supersql.SqlQuery, s *Schema) error {
+	r, err := q.SELECT(
+		"t.relname AS table_name",
+		"i.relname AS name",
+		"array_to_string(ARRAY(SELECT pg_get_indexdef(x.indexrelid, k, true) FROM generate_series(1, x.indnkeyatts) AS k), chr(31)) AS columns",
+		"x.indisunique AS is_unique",
+		"x.indisprimary AS is_primary",
+		"am.amname AS method",
+		"COALESCE(pg_get_expr(x.indpred, x.indrelid, true), '') AS predicate",
+		"pg_get_indexdef(x.indexrelid) AS definition",
+	).FROM(
+		"pg_index x JOIN pg_class i ON i.oid = x.indexrelid JOIN pg_class t ON t.oid = x.indrelid " +
+			"JOIN pg_namespace n ON n.oid = t.relnamespace JOIN pg_am am ON am.oid = i.relam",
+	).WHERE("n.nspname = ?", s.Name).ORDER_BY("t.relname, i.relname").GO()
+	if err != nil {
+		return err
+	}
+	for _, row := range r.All() {
+		t := s.Table(text(row, "table_name"))
+		if t == nil {
+			continue
+		}
+		t.Indexes = append(t.Indexes, Index{
+			Name:       text(row, "name"),
+			Columns:    split(row, "columns"),
+			Unique:     flag(row, "is_unique"),
+			Primary:    flag(row, "is_primary"),
+			Method:     text(row, "method"),
+			Predicate:  text(row, "predicate"),
+			Definition: text(row, "definition"),
+		})
+	}
+	return nil
+}
+
+func views(q *supersql.SqlQuery, s *Schema) error {
+	r, err := q.SELECT(
+		"c.relname AS name",
+		"pg_get_viewdef(c.oid, true) AS definition",
+		"c.relkind = 'm' AS materialized",
+		"COALESCE(obj_description(c.oid, 'pg_class'), '') AS comment",
+	).FROM(
+		"pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace",
+	).WHERE("n.nspname = ? AND c.relkind IN ('v', 'm')", s.Name).ORDER_BY("c.relname").GO()
+	if err != nil {
+		return err
+	}
+	for _, row := range r.All() {
+		s.Views = append(s.Views, View{
+			Name:         text(row, "name"),
+			Definition:   strings.TrimSpace(text(row, "definition")),
+			Materialized: flag(row, "materialized"),
+			Comment:      text(row, "comment"),
+		})
+	}
+	return nil
+}
+
+func sequences(q *supersql.SqlQuery, s *Schema) error {
+	r, err := q.SELECT(
+		"sequencename AS name", "data_type::text AS type", "start_value", "increment_by", "min_value", "max_value", "cycle",
+	).FROM("pg_sequences").WHERE("schemaname = ?", s.Name).ORDER_BY("sequencename").GO()
+	if err != nil {
+		return err
+	}
+	for _, row := range r.All() {
+		s.Sequences = append(s.Sequences, Sequence{
+			Name:      text(row, "name"),
+			Type:      text(row, "type"),
+			Start:     number(row, "start_value"),
+			Increment: number(row, "increment_by"),
+			Min:       number(row, "min_value"),
+			Max:       number(row, "max_value"),
+			Cycle:     flag(row, "cycle"),
+		})
+	}
+	return nil
+}
+
+func enums(q *supersql.SqlQuery, s *Schema) error {
+	r, err := q.SELECT(
+		"t.typname AS name",
+		"array_to_string(ARRAY(SELECT e.enumlabel FROM pg_enum e WHERE e.enumtypid = t.oid ORDER BY e.enumsortorder), chr(31)) AS labels",
+	).FROM(
+		"pg_type t JOIN pg_namespace n ON n.oid = t.typnamespace",
+	).WHERE("n.nspname = ? AND t.typtype = 'e'", s.Name).ORDER_BY("t.typname").GO()
+	if err != nil {
+		return err
+	}
+	for _, row := range r.All() {
+		s.Enums = append(s.Enums, Enum{Name: text(row, "name"), Values: split(row, "labels")})
+	}
+	return nil
+}
+
+func text(row supersql.Row, col string) string {
+	switch v := row.Column(col).(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func split(row supersql.Row, col string) []string {
+	value := text(row, col)
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, separator)
+}
+
+func flag(row supersql.Row, col string) bool {
+	v, _ := row.Column(col).(bool)
+	return v
+}
+
+func number(row supersql.Row, col string) int64 {
+	switch v := row.Column(col).(type) {
+	case int64:
+		return v
+	case int32:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int:
+		return int64(v)
+	case float64:
+		return int64(v)
+	}
+	return 0
+}
@@ -0,0 +1,249 @@
+package introspect_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rayattack/supersql"
+	"github.com/rayattack/supersql/introspect"
+	"github.com/rayattack/supersql/supersqltest"
+)
+
+func catalog() *supersqltest.Fake {
+	fake := supersqltest.New()
+	fake.Expect("relkind IN ('r', 'p') AND NOT c.relispartition ORDER BY").Returns(
+		[]string{"name", "comment"},
+		[]interface{}{"film", "Films in the catalogue"},
+		[]interface{}{"language", ""},
+	)
+	fake.Expect("FROM pg_attribute").Returns(
+		[]string{"table_name", "name", "type", "nullable", "default_value", "identity", "generated", "comment", "position"},
+		[]interface{}{"film", "film_id", "integer", false, "", "a", "", "", int32(1)},
+		[]interface{}{"film", "title", "character varying(255)", false, "", "", "", "", int32(2)},
+		[]interface{}{"film", "rental_rate", "numeric(4,2)", false, "4.99", "", "", "", int32(3)},
+		[]interface{}{"film", "language_id", "smallint", true, "", "", "", "", int32(4)},
+		[]interface{}{"film", "special_features", "text[]", true, "", "", "", "", int32(5)},
+		[]interface{}{"film", "rating", "mpaa_rating", true, "'G'::mpaa_rating", "", "", "", int32(6)},
+		[]interface{}{"language", "language_id", "smallint", false, "nextval('language_language_id_seq'::regclass)", "", "", "", int32(1)},
+		[]interface{}{"language", "name", "character(20)", false, "", "", "", "", int32(2)},
+	)
+	fake.Expect("FROM pg_constraint").Returns(
+		[]string{"table_name", "name", "kind", "columns", "definition", "ref_schema", "ref_table", "ref_columns", "on_delete", "on_update"},
+		[]interface{}{"film", "film_language_id_fkey", "f", "language_id", "FOREIGN KEY (language_id) REFERENCES language(language_id) ON DELETE CASCADE", "public", "language", "language_id", "c", "a"},
+		[]interface{}{"film", "film_pkey", "p", "film_id", "PRIMARY KEY (film_id)", "", "", "", " ", " "},
+		[]interface{}{"film", "film_rental_rate_check", "c", "rental_rate", "CHECK (rental_rate >= 0::numeric)", "", "", "", " ", " "},
+		[]interface{}{"film", "film_title_rating_key", "u", "title\x1frating", "UNIQUE (title, rating)", "", "", "", " ", " "},
+		[]interface{}{"film", "film_title_not_null", "n", "title", "NOT NULL title", "", "", "", " ", " "},
+		[]interface{}{"language", "language_pkey", "p", "language_id", "PRIMARY KEY (language_id)", "", "", "", " ", " "},
+	)
+	fake.Expect("FROM pg_index").Returns(
+		[]string{"table_name", "name", "columns", "is_unique", "is_primary", "method", "predicate", "definition"},
+		[]interface{}{"film", "film_pkey", "film_id", true, true, "btree", "", "CREATE UNIQUE INDEX film_pkey ON public.film USING btree (film_id)"},
+		[]interface{}{"film", "film_fulltext", "to_tsvector('english'::regconfig, title::text)", false, false, "gin", "rating <> 'NC-17'::mpaa_rating", "CREATE INDEX film_fulltext ON public.film USING gin (...)"},
+	)
+	fake.Expect("relkind IN ('v', 'm')").Returns(
+		[]string{"name", "definition", "materialized", "comment"},
+		[]interface{}{"film_list", " SELECT film.title\n   FROM film;", false, ""},
+		[]interface{}{"sales_by_store", " SELECT 1;", true, "refreshed nightly"},
+	)
+	fake.Expect("FROM pg_sequences").Returns(
+		[]string{"name", "type", "start_value", "increment_by", "min_value", "max_value", "cycle"},
+		[]interface{}{"language_language_id_seq", "smallint", int64(1), int64(1), int64(1), int64(32767), false},
+	)
+	fake.Expect("FROM pg_type").Returns(
+		[]string{"name", "labels"},
+		[]interface{}{"mpaa_rating", "G\x1fPG\x1fPG-13\x1fR\x1fNC-17"},
+	)
+	return fake
+}
+
+func TestInspect(t *testing.T) {
+	fake := catalog()
+	schema, err := introspect.Inspect(context.Background(), fake.Root(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fake.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	for _, call := range fake.Calls() {
+		if len(call.Args) != 1 || call.Args[0] != "public" || !strings.Contains(call.SQL, "$1") {
+			t.Fatal(call)
+		}
+	}
+
+	film := schema.Table("film")
+	if schema.Name != "public" || len(schema.Tables) != 2 || film == nil || film.Comment != "Films in the catalogue" {
+		t.Fatal(schema)
+	}
+	if len(film.Columns) != 6 || film.Columns[0].Identity != "ALWAYS" || film.Columns[0].Nullable {
+		t.Fatal(film.Columns)
+	}
+	if c, ok := film.Column("special_features"); !ok || c.Type != "text[]" || !c.Nullable || c.Position != 5 {
+		t.Fatal(c)
+	}
+	if pk := film.PrimaryKey(); len(pk) != 1 || pk[0] != "film_id" {
+		t.Fatal(pk)
+	}
+
+	//the postgres 18 not null constraint is skipped
+	if len(film.Constraints) != 4 || len(film.Constraints[3].Columns) != 2 || film.Constraints[3].Columns[1] != "rating" {
+		t.Fatal(film.Constraints)
+	}
+	if len(film.ForeignKeys) != 1 {
+		t.Fatal(film.ForeignKeys)
+	}
+	fk := film.ForeignKeys[0]
+	if fk.RefTable != "language" || fk.RefColumns[0] != "language_id" || fk.OnDelete != supersql.CASCADE || fk.OnUpdate != supersql.NO_ACTION {
+		t.Fatal(fk)
+	}
+
+	if len(film.Indexes) != 2 || !film.Indexes[0].Primary || film.Indexes[1].Method != "gin" || film.Indexes[1].Predicate == "" {
+		t.Fatal(film.Indexes)
+	}
+	if len(schema.Views) != 2 || schema.Views[0].Definition != "SELECT film.title\n   FROM film;" || !schema.Views[1].Materialized {
+		t.Fatal(schema.Views)
+	}
+	if len(schema.Sequences) != 1 || schema.Sequences[0].Max != 32767 {
+		t.Fatal(schema.Sequences)
+	}
+	rating := schema.Enum("mpaa_rating")
+	if rating == nil || len(rating.Values) != 5 || rating.Values[2] != "PG-13" {
+		t.Fatal(schema.Enums)
+	}
+	if rating.SqlEnum().CREATE() != "CREATE TYPE mpaa_rating AS ENUM ('G', 'PG', 'PG-13', 'R', 'NC-17')" {
+		t.Fatal(rating.SqlEnum().CREATE())
+	}
+}
+
+func TestInspectFails(t *testing.T) {
+	fake := supersqltest.New()
+	fake.Expect("FROM pg_attribute").Fails(errors.New("permission denied"))
+	if _, err := introspect.Inspect(context.Background(), fake.Root(), "billing"); err == nil || !strings.HasPrefix(err.Error(), "introspect:") {
+		t.Fatal(err)
+	}
+}
+
+func TestSqlTable(t *testing.T) {
+	schema, err := introspect.Inspect(context.Background(), catalog().Root(), "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	film := schema.Table("film").SqlTable()
+	expected := "CREATE TABLE film (" +
+		"film_id integer PRIMARY KEY GENERATED ALWAYS AS IDENTITY, " +
+		"title varchar(255) NOT NULL, " +
+		"rental_rate numeric(4, 2) NOT NULL DEFAULT 4.99 CHECK (rental_rate >= 0::numeric), " +
+		"language_id smallint REFERENCES language (language_id) ON DELETE CASCADE, " +
+		"special_features text[], " +
+		"rating mpaa_rating DEFAULT 'G'::mpaa_rating)"
+	if film.CREATE() != expected {
+		t.Fatal(film.CREATE())
+	}
+
+	//introspected fields bind values like declared ones
+	title, _ := film.Field("title")
+	if _, err := title.Bind(strings.Repeat("x", 256)); err == nil {
+		t.Fail()
+	}
+	features, _ := film.Field("special_features")
+	if _, err := features.Bind([]interface{}{"Trailers", 1}); err == nil {
+		t.Fail()
+	}
+
+	language := schema.Table("language").SqlTable()
+	if language.CREATE() != "CREATE TABLE language (language_id smallint PRIMARY KEY DEFAULT nextval('language_language_id_seq'::regclass), name char(20) NOT NULL)" {
+		t.Fatal(language.CREATE())
+	}
+}
+
+func TestPartitions(t *testing.T) {
+	fake := supersqltest.New()
+	fake.Expect("FROM pg_inherits").Returns(
+		[]string{"schema", "name", "bound", "partitioned"},
+		[]interface{}{"public", "events_default", "DEFAULT", false},
+		[]interface{}{"public", "events_p2024_01", "FOR VALUES FROM ('2024-01-01 00:00:00+00') TO ('2024-02-01 00:00:00+00')", false},
+		[]interface{}{"public", "events_old", "FOR VALUES FROM (MINVALUE) TO ('2024-01-01 00:00:00+00')", true},
+		[]interface{}{"public", "events_eu", "FOR VALUES IN ('de', 'o''brien')", false},
+		[]interface{}{"public", "events_h0", "FOR VALUES WITH (modulus 4, remainder 3)", false},
+	)
+	partitions, err := introspect.Partitions(context.Background(), fake.Root(), "", "events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(partitions) != 5 {
+		t.Fatal(partitions)
+	}
+	if call := fake.Calls()[0]; call.Args[0] != "public" || call.Args[1] != "events" {
+		t.Fatal(call.Args)
+	}
+	if !partitions[0].Default {
+		t.Fatal(partitions[0])
+	}
+	monthly := partitions[1]
+	if monthly.Parent != "events" || monthly.From[0] != "2024-01-01 00:00:00+00" || monthly.To[0] != "2024-02-01 00:00:00+00" {
+		t.Fatal(monthly)
+	}
+	if old := partitions[2]; old.From[0] != "MINVALUE" || !old.Partitioned {
+		t.Fatal(old)
+	}
+	if list := partitions[3]; len(list.Values) != 2 || list.Values[1] != "o'brien" {
+		t.Fatal(list.Values)
+	}
+	if hash := partitions[4]; hash.Modulus != 4 || hash.Remainder != 3 {
+		t.Fatal(hash)
+	}
+
+	fake.Expect("FROM pg_inherits").Returns([]string{"schema", "name", "bound", "partitioned"}, []interface{}{"public", "x", "FOR SOMETHING", false})
+	if _, err := introspect.Partitions(context.Background(), fake.Root(), "", "events"); err == nil {
+		t.Fatal("bound should not be understood")
+	}
+}
+
+func TestPartitionedTableConversion(t *testing.T) {
+	table := &introspect.Table{
+		Name:         "events",
+		Partitioning: "RANGE (created_at, date_trunc('day', seen_at))",
+		Columns:      []introspect.Column{{Name: "created_at", Type: "timestamp with time zone"}},
+	}
+	strategy, keys := table.SqlTable().Partitioning()
+	if strategy != "RANGE" || len(keys) != 2 || keys[1] != "date_trunc('day', seen_at)" {
+		t.Fatal(strategy, keys)
+	}
+	if !strings.HasSuffix(table.SqlTable().CREATE(), "PARTITION BY RANGE (created_at, date_trunc('day', seen_at))") {
+		t.Fatal(table.SqlTable().CREATE())
+	}
+}
+
+func TestDatabasesAndSchemas(t *testing.T) {
+	fake := supersqltest.New()
+	fake.Expect("FROM pg_database").Returns(
+		[]string{"name", "owner", "encoding", "template"},
+		[]interface{}{"pagila", "app", "UTF8", false},
+		[]interface{}{"template1", "postgres", "UTF8", true},
+	)
+	fake.Expect("FROM pg_namespace").Returns([]string{"name"}, []interface{}{"billing"}, []interface{}{"public"})
+
+	databases, err := introspect.Databases(context.Background(), fake.Root())
+	if err != nil || len(databases) != 2 {
+		t.Fatal(databases, err)
+	}
+	if databases[0] != (introspect.Database{Name: "pagila", Owner: "app", Encoding: "UTF8"}) || !databases[1].Template {
+		t.Fatal(databases)
+	}
+	schemas, err := introspect.Schemas(context.Background(), fake.Root())
+	if err != nil || strings.Join(schemas, ",") != "billing,public" {
+		t.Fatal(schemas, err)
+	}
+	if !strings.Contains(fake.Calls()[1].SQL, "nspname <> 'information_schema'") {
+		t.Fatal(fake.Calls()[1].SQL)
+	}
+
+	fake.Expect("FROM pg_database").Fails(errors.New("connection refused"))
+	if _, err := introspect.Databases(context.Background(), fake.Root()); err == nil || !strings.HasPrefix(err.Error(), "introspect:") {
+		t.Fatal(err)
+	}
+}
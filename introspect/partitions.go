@@ -0,0 +1,117 @@
+package introspect
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/rayattack/supersql"
+)
+
+//Partition of a partitioned table. Bound is the partition bound as reported by pg_get_expr i.e.
+//FOR VALUES FROM ('2024-01-01') TO ('2024-02-01'), its values are also split out with the quotes
+//of literals removed so they can be compared.
+type Partition struct {
+	Schema string
+	Name   string
+	Parent string
+	Bound  string
+	//The DEFAULT partition holding records no other partition holds
+	Default bool
+	//Bounds of range partitions, MINVALUE and MAXVALUE are reported as such
+	From []string
+	To   []string
+	//Values of list partitions
+	Values    []string
+	Modulus   int
+	Remainder int
+	//The partition is itself partitioned
+	Partitioned bool
+}
+
+var (
+	rangeBound = regexp.MustCompile(`^FOR VALUES FROM \((.*)\) TO \((.*)\)$`)
+	listBound  = regexp.MustCompile(`^FOR VALUES IN \((.*)\)$`)
+	hashBound  = regexp.MustCompile(`^FOR VALUES WITH \((?i:modulus) (\d+), (?i:remainder) (\d+)\)$`)
+)
+
+//Read the partitions of a partitioned table in the order of their names, schema is public when empty
+func Partitions(ctx context.Context, q *supersql.SqlQuery, schema string, table string) ([]Partition, error) {
+	if schema == "" {
+		schema = "public"
+	}
+	r, err := q.WithContext(ctx).SELECT(
+		"cn.nspname AS schema",
+		"c.relname AS name",
+		"pg_get_expr(c.relpartbound, c.oid) AS bound",
+		"c.relkind = 'p' AS partitioned",
+	).FROM(
+		"pg_inherits i JOIN pg_class c ON c.oid = i.inhrelid JOIN pg_namespace cn ON cn.oid = c.relnamespace " +
+			"JOIN pg_class p ON p.oid = i.inhparent JOIN pg_namespace n ON n.oid = p.relnamespace",
+	).WHERE("n.nspname = ? AND p.relname = ? AND c.relispartition", schema, table).ORDER_BY("c.relname").GO()
+	if err != nil {
+		return nil, fmt.Errorf("introspect: %w", err)
+	}
+
+	partitions := []Partition{}
+	for _, row := range r.All() {
+		p := Partition{
+			Schema:      text(row, "schema"),
+			Name:        text(row, "name"),
+			Parent:      table,
+			Bound:       text(row, "bound"),
+			Partitioned: flag(row, "partitioned"),
+		}
+		if err := p.parse(); err != nil {
+			return nil, fmt.Errorf("introspect: partition %s: %w", p.Name, err)
+		}
+		partitions = append(partitions, p)
+	}
+	return partitions, nil
+}
+
+func (p *Partition) parse() error {
+	if p.Bound == "DEFAULT" {
+		p.Default = true
+		return nil
+	}
+	if match := rangeBound.FindStringSubmatch(p.Bound); match != nil {
+		p.From, p.To = literals(match[1]), literals(match[2])
+		return nil
+	}
+	if match := listBound.FindStringSubmatch(p.Bound); match != nil {
+		p.Values = literals(match[1])
+		return nil
+	}
+	if match := hashBound.FindStringSubmatch(p.Bound); match != nil {
+		p.Modulus, _ = strconv.Atoi(match[1])
+		p.Remainder, _ = strconv.Atoi(match[2])
+		return nil
+	}
+	return fmt.Errorf("bound %q is not understood", p.Bound)
+}
+
+//Values of a comma separated list of literals with the quotes of string literals removed
+func literals(list string) []string {
+	values := []string{}
+	current := strings.Builder{}
+	quoted := false
+	for i := 0; i < len(list); i++ {
+		c := list[i]
+		switch {
+		case c == '\'' && quoted && i+1 < len(list) && list[i+1] == '\'':
+			current.WriteByte(c)
+			i++
+		case c == '\'':
+			quoted = !quoted
+		case c == ',' && !quoted:
+			values = append(values, strings.TrimSpace(current.String()))
+			current.Reset()
+		default:
+			current.WriteByte(c)
+		}
+	}
+	return append(values, strings.TrimSpace(current.String()))
+}
@@ -0,0 +1,131 @@
+//Package leader elects a single leader among the replicas of a service with a postgres advisory lock
+//i.e. so scheduled jobs run exactly once however many replicas are up. The replica holding the lock
+//is the leader until it stops or loses the connection holding the lock, the others keep trying to
+//take it over.
+//
+//	e := leader.New(q, "cron")
+//	e.OnElected = func(ctx context.Context) { scheduler.Run(ctx) } // ctx ends with the leadership
+//	e.OnDemoted = func() { log.Print("no longer the leader") }
+//	err := e.Run(ctx)
+package leader
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/rayattack/supersql"
+)
+
+//Time between attempts to take the lock and between checks that it is still held when Interval is
+//zero
+const DefaultInterval = 5 * time.Second
+
+type Elector struct {
+	q   *supersql.SqlQuery
+	key int64
+
+	//DefaultInterval when zero
+	Interval time.Duration
+	//Invoked in a goroutine of its own when this replica becomes the leader. The context is cancelled
+	//when the leadership is lost and the lock is only released once OnElected returned.
+	OnElected func(ctx context.Context)
+	//Invoked after OnElected returned when the leadership was lost or given up
+	OnDemoted func()
+	//Invoked with errors that did not stop the election i.e. failing to reach the server
+	OnError func(err error)
+
+	leader int32
+}
+
+//Elect a leader among the replicas contending with the same name
+func New(q *supersql.SqlQuery, name string) *Elector {
+	return &Elector{q: q, key: supersql.AdvisoryKey("supersql:leader:" + name)}
+}
+
+//Elect a leader among the replicas contending for the advisory lock key
+func WithKey(q *supersql.SqlQuery, key int64) *Elector {
+	return &Elector{q: q, key: key}
+}
+
+//Whether this replica currently leads
+func (e *Elector) Leader() bool {
+	return atomic.LoadInt32(&e.leader) == 1
+}
+
+//Take part in the election until ctx is done, leading whenever the lock can be taken. Leadership is
+//given up before returning.
+func (e *Elector) Run(ctx context.Context) error {
+	ticker := time.NewTicker(e.interval())
+	defer ticker.Stop()
+	for {
+		lock, ok, err := e.q.WithContext(ctx).TRY_ADVISORY_LOCK(e.key)
+		if err != nil && ctx.Err() == nil {
+			e.report(err)
+		}
+		if ok {
+			e.lead(ctx, lock, ticker)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+//Hold the leadership until the lock is lost or ctx is done
+func (e *Elector) lead(ctx context.Context, lock *supersql.AdvisoryLock, ticker *time.Ticker) {
+	leading, demote := context.WithCancel(ctx)
+	defer demote()
+	done := make(chan struct{})
+	atomic.StoreInt32(&e.leader, 1)
+	go func() {
+		defer close(done)
+		if e.OnElected != nil {
+			e.OnElected(leading)
+		}
+	}()
+
+	finished := done
+	for leading.Err() == nil {
+		select {
+		case <-leading.Done():
+		case <-finished:
+			//the work of the leader ended early, keep the lock so no other replica repeats it
+			finished = nil
+		case <-ticker.C:
+			if err := lock.Check(ctx); err != nil {
+				if ctx.Err() == nil {
+					e.report(err)
+				}
+				demote()
+			}
+		}
+	}
+	<-done
+	atomic.StoreInt32(&e.leader, 0)
+
+	//the context of the election may be done already so unlocking gets one of its own
+	unlocking, cancel := context.WithTimeout(context.Background(), e.interval())
+	defer cancel()
+	if err := lock.Unlock(unlocking); err != nil {
+		e.report(err)
+	}
+	if e.OnDemoted != nil {
+		e.OnDemoted()
+	}
+}
+
+func (e *Elector) interval() time.Duration {
+	if e.Interval <= 0 {
+		return DefaultInterval
+	}
+	return e.Interval
+}
+
+func (e *Elector) report(err error) {
+	if e.OnError != nil {
+		e.OnError(err)
+	}
+}
@@ -0,0 +1,103 @@
+package leader_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rayattack/supersql/leader"
+	"github.com/rayattack/supersql/supersqltest"
+)
+
+func elector(fake *supersqltest.Fake) *leader.Elector {
+	e := leader.New(fake.Root(), "cron")
+	e.Interval = time.Millisecond
+	return e
+}
+
+func TestElected(t *testing.T) {
+	fake := supersqltest.New()
+	fake.Expect("pg_try_advisory_lock").Returns([]string{"locked"}, []interface{}{true})
+	fake.Expect("pg_advisory_unlock").Returns([]string{"locked"}, []interface{}{true})
+	e := elector(fake)
+	ctx, cancel := context.WithCancel(context.Background())
+	elected, demoted := make(chan bool, 1), make(chan bool, 1)
+	e.OnElected = func(lead context.Context) {
+		elected <- e.Leader()
+		<-lead.Done()
+	}
+	e.OnDemoted = func() { demoted <- e.Leader() }
+	e.OnError = func(err error) { t.Error(err) }
+
+	result := make(chan error)
+	go func() { result <- e.Run(ctx) }()
+	if leading := <-elected; !leading {
+		t.Fatal("the elected replica leads")
+	}
+	cancel()
+	if leading := <-demoted; leading {
+		t.Fatal("the demoted replica does not lead")
+	}
+	if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+	unlocked := false
+	for _, call := range fake.Calls() {
+		unlocked = unlocked || call.SQL == "SELECT pg_advisory_unlock($1) AS locked"
+	}
+	if !unlocked {
+		t.Fatal("leadership is given up on return")
+	}
+}
+
+func TestLostConnectionDemotes(t *testing.T) {
+	fake := supersqltest.New()
+	fake.Expect("pg_try_advisory_lock").Returns([]string{"locked"}, []interface{}{true})
+	fake.Expect("SELECT 1").Fails(errors.New("conn closed"))
+	e := elector(fake)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lost, demoted := make(chan bool, 1), make(chan bool, 1)
+	e.OnElected = func(lead context.Context) {
+		<-lead.Done()
+		lost <- ctx.Err() == nil
+	}
+	e.OnDemoted = func() {
+		select {
+		case demoted <- true:
+		default:
+		}
+	}
+	errs := make(chan error, 10)
+	e.OnError = func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	}
+
+	go e.Run(ctx)
+	if !<-lost {
+		t.Fatal("leadership should end with the connection holding the lock")
+	}
+	<-demoted
+	if err := <-errs; err.Error() != "conn closed" {
+		t.Fatal(err)
+	}
+}
+
+func TestFollower(t *testing.T) {
+	fake := supersqltest.New()
+	fake.Expect("pg_try_advisory_lock").Returns([]string{"locked"}, []interface{}{false}).Always()
+	e := elector(fake)
+	e.OnElected = func(ctx context.Context) { t.Error("the lock is held by another replica") }
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := e.Run(ctx); err != nil || e.Leader() {
+		t.Fatal(err)
+	}
+	if len(fake.Calls()) < 2 {
+		t.Fatal("followers keep trying to take the lock")
+	}
+}
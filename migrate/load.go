@@ -0,0 +1,81 @@
+package migrate
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//Migration files are named <version>_<name>.up.sql and <version>_<name>.down.sql
+var filename = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+//First line of a migration file that must not run inside a transaction, read for the up and the
+//down file separately
+const NoTransactionDirective = "-- supersql:no-transaction"
+
+//Load migrations from the .sql files in dir of fsys, typically an embed.FS:
+//
+//	//go:embed migrations/*.sql
+//	var files embed.FS
+//	migrations, err := migrate.Load(files, "migrations")
+//
+//Files that do not follow the naming convention are ignored and a down file without its up file
+//is an error.
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	found := map[int64]*Migration{}
+	for _, entry := range entries {
+		match := filename.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migrate: %s: %w", entry.Name(), err)
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := found[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			found[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migrate: version %d is used by %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(data)
+			m.NoTransaction = directive(m.Up)
+		} else {
+			m.Down = string(data)
+			m.DownNoTransaction = directive(m.Down)
+		}
+	}
+
+	migrations := []Migration{}
+	for _, m := range found {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migrate: %d %s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func directive(ssql string) bool {
+	scanner := bufio.NewScanner(strings.NewReader(ssql))
+	return scanner.Scan() && strings.TrimSpace(scanner.Text()) == NoTransactionDirective
+}
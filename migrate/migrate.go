@@ -0,0 +1,321 @@
+//Package migrate applies versioned schema migrations with supersql. Migrations are declared in Go or
+//loaded from .sql files, applied versions are recorded with checksums in a tracking table and runs
+//are serialized across processes with a postgres advisory lock.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/rayattack/supersql"
+)
+
+//A single step of the schema history. Up and Down hold SQL, UpFunc and DownFunc run Go code against
+//the query root of the migration instead. Up runs inside a transaction unless NoTransaction is set
+//i.e. for CREATE INDEX CONCURRENTLY and Down unless DownNoTransaction is set i.e. for DROP INDEX
+//CONCURRENTLY.
+type Migration struct {
+	Version           int64
+	Name              string
+	Up                string
+	Down              string
+	UpFunc            func(q *supersql.SqlQuery) error
+	DownFunc          func(q *supersql.SqlQuery) error
+	NoTransaction     bool
+	DownNoTransaction bool
+}
+
+//Fingerprint of the Up SQL used to detect migrations edited after they were applied
+func (m Migration) Checksum() string {
+	sum := sha256.Sum256([]byte(m.Up))
+	return hex.EncodeToString(sum[:])
+}
+
+//Applied state of a migration as reported by Status(...)
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	//The checksum of the migration differs from the one recorded when it was applied
+	Drifted bool
+	//The version is recorded in the tracking table but there is no migration for it
+	Missing bool
+}
+
+const DefaultTable = "supersql_migrations"
+
+type Migrator struct {
+	q          *supersql.SqlQuery
+	migrations []Migration
+
+	//Tracking table, DefaultTable unless changed before the first run
+	Table string
+	//Advisory lock key shared by every process migrating the same database, derived from Table
+	//when zero
+	LockKey int64
+	//When set the SQL of every step is written here instead of being executed. The tracking table
+	//is still read to work out which steps are pending.
+	DryRun io.Writer
+}
+
+type applied struct {
+	name     string
+	checksum string
+	at       time.Time
+}
+
+func New(q *supersql.SqlQuery, migrations ...Migration) (*Migrator, error) {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	for i, m := range sorted {
+		if m.Version <= 0 {
+			return nil, fmt.Errorf("migrate: %s has invalid version %d", m.Name, m.Version)
+		}
+		if i > 0 && sorted[i-1].Version == m.Version {
+			return nil, fmt.Errorf("migrate: version %d is used by %s and %s", m.Version, sorted[i-1].Name, m.Name)
+		}
+		if m.Up == "" && m.UpFunc == nil {
+			return nil, fmt.Errorf("migrate: %d %s has nothing to apply", m.Version, m.Name)
+		}
+	}
+	return &Migrator{q: q, migrations: sorted, Table: DefaultTable}, nil
+}
+
+//Apply every pending migration in version order
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.run(ctx, func(done map[int64]applied) ([]step, error) {
+		return m.pending(done, math.MaxInt64), nil
+	})
+}
+
+//Roll back the n most recently applied migrations
+func (m *Migrator) Down(ctx context.Context, n int) error {
+	if n < 0 {
+		return fmt.Errorf("migrate: can not roll back %d migrations", n)
+	}
+	return m.run(ctx, func(done map[int64]applied) ([]step, error) {
+		steps := m.applied(done, 0)
+		if n < len(steps) {
+			steps = steps[:n]
+		}
+		return steps, nil
+	})
+}
+
+//Migrate up or down until version is the latest applied migration. Goto(ctx, 0) rolls everything back.
+func (m *Migrator) Goto(ctx context.Context, version int64) error {
+	if version != 0 && m.find(version) == nil {
+		return fmt.Errorf("migrate: there is no migration with version %d", version)
+	}
+	return m.run(ctx, func(done map[int64]applied) ([]step, error) {
+		return append(m.applied(done, version), m.pending(done, version)...), nil
+	})
+}
+
+//Report every known migration and every recorded version in version order
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	q := m.q.WithContext(ctx)
+	if err := m.ensure(q); err != nil {
+		return nil, err
+	}
+	done, err := m.read(q)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []Status{}
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if a, ok := done[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = a.at
+			status.Drifted = migration.Up != "" && a.checksum != migration.Checksum()
+		}
+		statuses = append(statuses, status)
+	}
+	for version, a := range done {
+		if m.find(version) == nil {
+			statuses = append(statuses, Status{Version: version, Name: a.name, Applied: true, AppliedAt: a.at, Missing: true})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+type step struct {
+	migration Migration
+	up        bool
+}
+
+//Migrations not applied yet up to and including version in ascending order
+func (m *Migrator) pending(done map[int64]applied, version int64) []step {
+	steps := []step{}
+	for _, migration := range m.migrations {
+		if _, ok := done[migration.Version]; !ok && migration.Version <= version {
+			steps = append(steps, step{migration, true})
+		}
+	}
+	return steps
+}
+
+//Applied migrations newer than version in descending order
+func (m *Migrator) applied(done map[int64]applied, version int64) []step {
+	steps := []step{}
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if _, ok := done[migration.Version]; ok && migration.Version > version {
+			steps = append(steps, step{migration, false})
+		}
+	}
+	return steps
+}
+
+func (m *Migrator) find(version int64) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) key() int64 {
+	if m.LockKey != 0 {
+		return m.LockKey
+	}
+	return supersql.AdvisoryKey("supersql:migrate:" + m.Table)
+}
+
+//Hold the advisory lock on a single connection for the whole run so concurrent deploys queue up
+//instead of applying the same migrations twice
+func (m *Migrator) run(ctx context.Context, plan func(done map[int64]applied) ([]step, error)) (err error) {
+	lock, err := m.q.WithContext(ctx).ADVISORY_LOCK(m.key())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if uerr := lock.Unlock(ctx); uerr != nil && err == nil {
+			err = uerr
+		}
+	}()
+	s := lock.Session()
+
+	if err := m.ensure(s); err != nil {
+		return err
+	}
+	done, err := m.read(s)
+	if err != nil {
+		return err
+	}
+	for _, migration := range m.migrations {
+		if a, ok := done[migration.Version]; ok && migration.Up != "" && a.checksum != migration.Checksum() {
+			return fmt.Errorf("migrate: %d %s was changed after it was applied", migration.Version, migration.Name)
+		}
+	}
+
+	steps, err := plan(done)
+	if err != nil {
+		return err
+	}
+	for _, st := range steps {
+		if err := m.apply(s, st); err != nil {
+			direction := "up"
+			if !st.up {
+				direction = "down"
+			}
+			return fmt.Errorf("migrate: %d %s %s: %w", st.migration.Version, st.migration.Name, direction, err)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) apply(s *supersql.SqlQuery, st step) error {
+	migration := st.migration
+	ssql, fn, transactional := migration.Up, migration.UpFunc, !migration.NoTransaction
+	if !st.up {
+		ssql, fn, transactional = migration.Down, migration.DownFunc, !migration.DownNoTransaction
+		if ssql == "" && fn == nil {
+			return fmt.Errorf("no down migration")
+		}
+	}
+
+	if m.DryRun != nil {
+		direction := "up"
+		if !st.up {
+			direction = "down"
+		}
+		if ssql == "" {
+			ssql = "-- implemented in Go"
+		}
+		_, err := fmt.Fprintf(m.DryRun, "-- %d %s (%s)\n%s\n\n", migration.Version, migration.Name, direction, ssql)
+		return err
+	}
+
+	migrate := func(q *supersql.SqlQuery) error {
+		if fn != nil {
+			if err := fn(q); err != nil {
+				return err
+			}
+		} else if _, err := q.RUN(ssql).GO(); err != nil {
+			return err
+		}
+		if st.up {
+			columns := []string{"version", "name", "checksum"}
+			_, err := q.INSERT_INTO(m.Table, columns).VALUES([]interface{}{migration.Version, migration.Name, migration.Checksum()}).GO()
+			return err
+		}
+		_, err := q.RUN(fmt.Sprintf("DELETE FROM %s", m.Table)).WHERE("version = ?", migration.Version).GO()
+		return err
+	}
+	if !transactional {
+		return migrate(s)
+	}
+	return s.TRANSACTION(migrate)
+}
+
+func (m *Migrator) ensure(q *supersql.SqlQuery) error {
+	if m.DryRun != nil {
+		//a dry run must not write anything, a missing table simply means nothing was applied
+		return nil
+	}
+	ddl := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (version bigint PRIMARY KEY, name text NOT NULL, "+
+		"checksum text NOT NULL, applied_at timestamptz NOT NULL DEFAULT now())", m.Table)
+	_, err := q.RUN(ddl).GO()
+	return err
+}
+
+func (m *Migrator) read(q *supersql.SqlQuery) (map[int64]applied, error) {
+	done := map[int64]applied{}
+	if m.DryRun != nil {
+		r, err := q.SELECT(fmt.Sprintf("to_regclass('%s') IS NOT NULL AS present", m.Table)).GO()
+		if err != nil {
+			return nil, err
+		}
+		if r.Count() == 0 || r.Rows(1).Column("present") != true {
+			return done, nil
+		}
+	}
+
+	r, err := q.SELECT("version", "name", "checksum", "applied_at").FROM(m.Table).ORDER_BY("version").GO()
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range r.All() {
+		version, err := row.Int64("version")
+		if err != nil {
+			return nil, err
+		}
+		name, _ := row.String("name")
+		checksum, _ := row.String("checksum")
+		at, _ := row.Column("applied_at").(time.Time)
+		done[version] = applied{name, checksum, at}
+	}
+	return done, nil
+}
@@ -0,0 +1,199 @@
+package migrate_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/rayattack/supersql/migrate"
+	"github.com/rayattack/supersql/supersqltest"
+)
+
+var files = fstest.MapFS{
+	"migrations/0001_create_actor.up.sql":   {Data: []byte("CREATE TABLE actor (actor_id serial PRIMARY KEY);")},
+	"migrations/0001_create_actor.down.sql": {Data: []byte("DROP TABLE actor;")},
+	"migrations/0002_index_actor.up.sql":    {Data: []byte(migrate.NoTransactionDirective + "\nCREATE INDEX CONCURRENTLY actor_name ON actor (name);")},
+	"migrations/0002_index_actor.down.sql":  {Data: []byte(migrate.NoTransactionDirective + "\nDROP INDEX CONCURRENTLY actor_name;")},
+	"migrations/README.md":                  {Data: []byte("ignored")},
+}
+
+func load(t *testing.T) []migrate.Migration {
+	migrations, err := migrate.Load(files, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return migrations
+}
+
+//Fake database that releases the migration lock
+func database() *supersqltest.Fake {
+	fake := supersqltest.New()
+	fake.Expect("pg_advisory_unlock").Returns([]string{"locked"}, []interface{}{true}).Always()
+	return fake
+}
+
+func executed(fake *supersqltest.Fake) []string {
+	sqls := []string{}
+	for _, call := range fake.Calls() {
+		sqls = append(sqls, call.SQL)
+	}
+	return sqls
+}
+
+func TestLoad(t *testing.T) {
+	migrations := load(t)
+	if len(migrations) != 2 {
+		t.Fatal(migrations)
+	}
+	if migrations[0].Version != 1 || migrations[0].Name != "create_actor" || migrations[0].Down != "DROP TABLE actor;" {
+		t.Fail()
+	}
+	if migrations[0].NoTransaction || !migrations[1].NoTransaction {
+		t.Fail()
+	}
+	if migrations[0].DownNoTransaction || !migrations[1].DownNoTransaction {
+		t.Fail()
+	}
+
+	broken := fstest.MapFS{"m/0003_orphan.down.sql": {Data: []byte("DROP TABLE x;")}}
+	if _, err := migrate.Load(broken, "m"); err == nil {
+		t.Fail()
+	}
+}
+
+func TestUpAppliesPendingMigrations(t *testing.T) {
+	fake := database()
+	migrations := load(t)
+	fake.Expect("FROM supersql_migrations").Returns(
+		[]string{"version", "name", "checksum", "applied_at"},
+		[]interface{}{int64(1), "create_actor", migrations[0].Checksum(), nil},
+	)
+
+	m, _ := migrate.New(fake.Root(), migrations...)
+	if err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	sqls := executed(fake)
+	if sqls[0] != "SELECT pg_advisory_lock($1)" || !strings.HasPrefix(sqls[len(sqls)-1], "SELECT pg_advisory_unlock($1)") {
+		t.Log(sqls)
+		t.Fail()
+	}
+	joined := strings.Join(sqls, "\n")
+	if strings.Contains(joined, "CREATE TABLE actor") || !strings.Contains(joined, "CREATE INDEX CONCURRENTLY") {
+		t.Log(joined)
+		t.Fail()
+	}
+	//concurrent index builds can not run inside a transaction
+	if strings.Contains(joined, "BEGIN") {
+		t.Fail()
+	}
+	if !strings.Contains(joined, "INSERT INTO supersql_migrations (version, name, checksum) VALUES ($1, $2, $3)") {
+		t.Fail()
+	}
+}
+
+func TestDownRollsBackInTransactions(t *testing.T) {
+	fake := database()
+	migrations := load(t)
+	fake.Expect("FROM supersql_migrations").Returns(
+		[]string{"version", "name", "checksum", "applied_at"},
+		[]interface{}{int64(1), "create_actor", migrations[0].Checksum(), nil},
+		[]interface{}{int64(2), "index_actor", migrations[1].Checksum(), nil},
+	).Always()
+
+	//version 2 is recorded but unknown to this build so it can not be rolled back
+	m, _ := migrate.New(fake.Root(), migrations[0])
+	if err := m.Down(context.Background(), 5); err != nil {
+		t.Fatal(err)
+	}
+	sqls := executed(fake)
+	expected := []string{"BEGIN", "DROP TABLE actor;", "DELETE FROM supersql_migrations WHERE version = $1", "COMMIT"}
+	tail := sqls[len(sqls)-5 : len(sqls)-1]
+	for i := range expected {
+		if tail[i] != expected[i] {
+			t.Log(sqls)
+			t.FailNow()
+		}
+	}
+
+	statuses, _ := m.Status(context.Background())
+	if len(statuses) != 2 || !statuses[0].Applied || statuses[0].Missing || !statuses[1].Missing {
+		t.Log(statuses)
+		t.Fail()
+	}
+}
+
+func TestDownOutsideTransactions(t *testing.T) {
+	fake := database()
+	migrations := load(t)
+	fake.Expect("FROM supersql_migrations").Returns(
+		[]string{"version", "name", "checksum", "applied_at"},
+		[]interface{}{int64(1), "create_actor", migrations[0].Checksum(), nil},
+		[]interface{}{int64(2), "index_actor", migrations[1].Checksum(), nil},
+	)
+
+	m, _ := migrate.New(fake.Root(), migrations...)
+	if err := m.Down(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	joined := strings.Join(executed(fake), "\n")
+	//the down file of version 2 asks to run outside a transaction
+	if !strings.Contains(joined, "DROP INDEX CONCURRENTLY actor_name;") || strings.Contains(joined, "BEGIN") || strings.Contains(joined, "DROP TABLE") {
+		t.Fatal(joined)
+	}
+
+	if err := m.Down(context.Background(), -1); err == nil {
+		t.Fatal("a negative step count was accepted")
+	}
+}
+
+func TestChecksumDriftIsRejected(t *testing.T) {
+	fake := database()
+	fake.Expect("FROM supersql_migrations").Returns(
+		[]string{"version", "name", "checksum", "applied_at"},
+		[]interface{}{int64(1), "create_actor", "stale", nil},
+	).Always()
+
+	m, _ := migrate.New(fake.Root(), load(t)...)
+	if err := m.Up(context.Background()); err == nil {
+		t.Fail()
+	}
+	statuses, _ := m.Status(context.Background())
+	if !statuses[0].Drifted || statuses[1].Applied {
+		t.Fail()
+	}
+}
+
+func TestGotoDryRun(t *testing.T) {
+	fake := database()
+	fake.Expect("to_regclass").Returns([]string{"present"}, []interface{}{false})
+
+	out := &bytes.Buffer{}
+	m, _ := migrate.New(fake.Root(), load(t)...)
+	m.DryRun = out
+	if err := m.Goto(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "-- 1 create_actor (up)\nCREATE TABLE actor (actor_id serial PRIMARY KEY);\n\n" {
+		t.Log(out.String())
+		t.Fail()
+	}
+	for _, sql := range executed(fake) {
+		if strings.Contains(sql, "CREATE") || strings.Contains(sql, "INSERT") {
+			t.Fail()
+		}
+	}
+	if m.Goto(context.Background(), 7) == nil {
+		t.Fail()
+	}
+}
+
+func TestNewRejectsDuplicateVersions(t *testing.T) {
+	_, err := migrate.New(nil, migrate.Migration{Version: 1, Up: "x"}, migrate.Migration{Version: 1, Up: "y"})
+	if err == nil {
+		t.Fail()
+	}
+}
@@ -0,0 +1,134 @@
+package supersql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+//A message sent with NOTIFY to a channel the query root listens on
+type Notification struct {
+	Channel string
+	Payload string
+	//Process id of the session that sent the notification
+	PID uint32
+	//Sent by Listen(...) instead of the server after it reconnected. Notifications sent while the
+	//connection was down are lost so state kept in sync with them i.e. caches should be reloaded.
+	Resumed bool
+}
+
+//Unmarshal the JSON payload of the notification into v
+func (n Notification) Decode(v interface{}) error {
+	return json.Unmarshal([]byte(n.Payload), v)
+}
+
+//Implemented by executors bound to a single connection that can wait for notifications
+type waiter interface {
+	WaitForNotification(ctx context.Context) (Notification, error)
+}
+
+//Delay between attempts to reconnect a listener
+var relisten = Retry{Backoff: 100 * time.Millisecond, MaxBackoff: 10 * time.Second}
+
+//Receive the notifications sent to channels on a connection of its own. The connection is
+//reconnected and the channels listened to again whenever it drops, the Go channel is closed once
+//ctx is done. Only roots made from a connection or a pool can listen, not the root handed to
+//TRANSACTION(...).
+//
+//	invalidations, err := q.Listen(ctx, "film_changed")
+//	for n := range invalidations {
+//		var change FilmChanged
+//		if err := n.Decode(&change); err == nil { cache.Delete(change.FilmID) }
+//	}
+func (q *SqlQuery) Listen(ctx context.Context, channels ...string) (<-chan Notification, error) {
+	if len(channels) == 0 {
+		return nil, fmt.Errorf("Listen requires at least one channel")
+	}
+	if _, ok := q.exec.(Transaction); ok {
+		return nil, fmt.Errorf("Listen can not be used inside a TRANSACTION, listen on a connection or pool instead")
+	}
+	root := q.WithContext(ctx)
+	session, release, err := root.listen(channels)
+	if err != nil {
+		return nil, err
+	}
+	notifications := make(chan Notification, 64)
+	go root.receive(ctx, channels, session, release, notifications)
+	return notifications, nil
+}
+
+//Pin a connection and LISTEN on it
+func (q *SqlQuery) listen(channels []string) (*SqlQuery, func(), error) {
+	session, release, err := q.pinned()
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, ok := session.exec.(waiter); !ok {
+		release()
+		return nil, nil, fmt.Errorf("%T can not wait for notifications", session.exec)
+	}
+	for _, channel := range channels {
+		if _, err := session.RUN("LISTEN " + quoteIdentifier(Postgres, channel)).GO(); err != nil {
+			release()
+			return nil, nil, err
+		}
+	}
+	return session, release, nil
+}
+
+func (q *SqlQuery) receive(ctx context.Context, channels []string, session *SqlQuery, release func(), notifications chan<- Notification) {
+	defer close(notifications)
+	for {
+		w := session.exec.(waiter)
+		for {
+			n, err := w.WaitForNotification(ctx)
+			if err != nil {
+				break
+			}
+			select {
+			case notifications <- n:
+			case <-ctx.Done():
+			}
+		}
+		release()
+
+		for attempt := 1; ; attempt++ {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(relisten.delay(attempt)):
+			}
+			var err error
+			if session, release, err = q.listen(channels); err == nil {
+				break
+			}
+		}
+		for _, channel := range channels {
+			select {
+			case notifications <- Notification{Channel: channel, Resumed: true}:
+			case <-ctx.Done():
+			}
+		}
+	}
+}
+
+//Send payload to the listeners of channel. Strings and byte slices are sent as they are, anything
+//else is encoded as JSON. Notifications sent inside a TRANSACTION are delivered when it commits.
+func (q *SqlQuery) Notify(channel string, payload interface{}) error {
+	var message string
+	switch p := payload.(type) {
+	case string:
+		message = p
+	case []byte:
+		message = string(p)
+	default:
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("encoding payload of %s: %w", channel, err)
+		}
+		message = string(data)
+	}
+	_, err := q.RAW("SELECT pg_notify(?, ?)", channel, message).GO()
+	return err
+}
@@ -0,0 +1,101 @@
+package supersql_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rayattack/supersql"
+	"github.com/rayattack/supersql/supersqltest"
+)
+
+type filmChanged struct {
+	FilmID int    `json:"film_id"`
+	Title  string `json:"title"`
+}
+
+func TestListenAndNotify(t *testing.T) {
+	fake := supersqltest.New()
+	q := fake.Root()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	notifications, err := q.Listen(ctx, "film_changed", "Audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls := fake.Calls(); calls[0].SQL != "LISTEN film_changed" || calls[1].SQL != `LISTEN "Audit"` {
+		t.Fatal(calls)
+	}
+
+	if err := q.Notify("film_changed", filmChanged{133, "Chamber Italian"}); err != nil {
+		t.Fatal(err)
+	}
+	n := <-notifications
+	var change filmChanged
+	if err := n.Decode(&change); err != nil || n.Channel != "film_changed" || change.FilmID != 133 || change.Title != "Chamber Italian" {
+		t.Fatal(n, change, err)
+	}
+	if call := fake.Calls()[2]; call.SQL != "SELECT pg_notify($1, $2)" || call.Args[1] != `{"film_id":133,"title":"Chamber Italian"}` {
+		t.Fatal(call)
+	}
+
+	if err := q.Notify("Audit", "film 133"); err != nil {
+		t.Fatal(err)
+	}
+	if n := <-notifications; n.Payload != "film 133" || n.Resumed {
+		t.Fatal(n)
+	}
+
+	cancel()
+	for range notifications {
+	}
+}
+
+func TestListenReconnects(t *testing.T) {
+	fake := supersqltest.New()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	notifications, err := fake.Root().Listen(ctx, "film_changed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.Disconnect(errors.New("conn closed"))
+	if n := <-notifications; !n.Resumed || n.Channel != "film_changed" {
+		t.Fatal("listeners should be told about reconnects", n)
+	}
+	listens := 0
+	for _, call := range fake.Calls() {
+		if call.SQL == "LISTEN film_changed" {
+			listens++
+		}
+	}
+	if listens != 2 {
+		t.Fatal("channels should be listened to again after reconnecting", fake.Calls())
+	}
+	fake.Notify("film_changed", "133")
+	if n := <-notifications; n.Payload != "133" {
+		t.Fatal(n)
+	}
+}
+
+func TestListenFailures(t *testing.T) {
+	fake := supersqltest.New()
+	if _, err := fake.Root().Listen(context.Background()); err == nil {
+		t.Fatal("listening needs a channel")
+	}
+	fake.Expect("LISTEN").Fails(errors.New("permission denied"))
+	if _, err := fake.Root().Listen(context.Background(), "film_changed"); err == nil {
+		t.Fatal("failing to listen is an error")
+	}
+	fake.Reset()
+	err := fake.Root().TRANSACTION(func(tx *supersql.SqlQuery) error {
+		_, err := tx.Listen(context.Background(), "film_changed")
+		return err
+	})
+	if calls := fake.Calls(); err == nil || len(calls) != 2 || calls[1].SQL != "ROLLBACK" {
+		t.Fatal("transactions can not listen", calls, err)
+	}
+	if err := fake.Root().Notify("film_changed", make(chan int)); err == nil {
+		t.Fatal("payloads that can not be encoded are an error")
+	}
+}
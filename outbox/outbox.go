@@ -0,0 +1,268 @@
+//Package outbox implements the transactional outbox pattern. Domain events are written to an outbox
+//table in the transaction of the business writes they describe so they are stored if and only if
+//those writes commit, a relay then hands them to a publisher i.e. a message broker.
+//
+//	box := outbox.New(q)
+//	err := q.TRANSACTION(func(tx *supersql.SqlQuery) error {
+//		if _, err := tx.INSERT_INTO("orders", columns).VALUES(order).GO(); err != nil {
+//			return err
+//		}
+//		_, err := box.Write(tx, "order:42", "order.placed", OrderPlaced{ID: 42})
+//		return err
+//	})
+//	go box.Relay(publisher).Run(ctx)
+//
+//Events of the same aggregate are published one at a time in the order they were written, an event
+//that fails to publish holds back the later events of its aggregate until a retry succeeds. Events
+//are published at least once since the relay can stop between publishing and recording it.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rayattack/supersql"
+	"github.com/rayattack/supersql/internal/backoff"
+)
+
+const (
+	DefaultTable = "supersql_outbox"
+	//Events leased by a pass of the relay when Relay.BatchSize is zero. Kept small since a pass holds
+	//its transaction open while it publishes every event it leased.
+	DefaultBatchSize = 10
+	//Pause of the relay after a pass that published nothing when Relay.Interval is zero
+	DefaultInterval = time.Second
+)
+
+//A domain event waiting in the outbox
+type Event struct {
+	ID int64
+	//Key of the aggregate i.e. the entity the event is about, events sharing it are published in order
+	Aggregate string
+	Topic     string
+	//JSON encoding of the payload given to Write(...)
+	Payload   json.RawMessage
+	Attempts  int
+	CreatedAt time.Time
+	//Error of the previous attempt to publish the event if there was one
+	LastError string
+}
+
+//Unmarshal the payload of the event into v
+func (e Event) Decode(v interface{}) error {
+	return json.Unmarshal(e.Payload, v)
+}
+
+//Delivers events to wherever they are consumed. An error leaves the event in the outbox to be
+//retried after a backoff.
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+//Convenience adapter for publishers that are plain functions
+type PublisherFunc func(ctx context.Context, event Event) error
+
+func (f PublisherFunc) Publish(ctx context.Context, event Event) error {
+	return f(ctx, event)
+}
+
+type Outbox struct {
+	q *supersql.SqlQuery
+
+	//DefaultTable unless changed before Setup(...)
+	Table string
+}
+
+func New(q *supersql.SqlQuery) *Outbox {
+	return &Outbox{q: q, Table: DefaultTable}
+}
+
+//Create the outbox table and its index if they do not exist yet
+func (o *Outbox) Setup(ctx context.Context) error {
+	q := o.q.WithContext(ctx)
+	table := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id bigserial PRIMARY KEY, aggregate text NOT NULL, "+
+		"topic text NOT NULL, payload jsonb NOT NULL, attempts integer NOT NULL DEFAULT 0, "+
+		"retry_at timestamptz NOT NULL DEFAULT now(), last_error text, published_at timestamptz, "+
+		"created_at timestamptz NOT NULL DEFAULT now())", o.Table)
+	if _, err := q.RUN(table).GO(); err != nil {
+		return err
+	}
+	//the index is named after the table without its schema, it is created in the schema of the table
+	t := supersql.Table(o.Table)
+	name := strings.TrimPrefix(t.Name(), t.Schema()+".")
+	index := supersql.Index(name+"_pending", t, "aggregate", "id").WHERE("published_at IS NULL").IF_NOT_EXISTS()
+	_, err := q.RUN(index.CREATE()).GO()
+	return err
+}
+
+//Add an event about aggregate to the outbox and return its id. Use the query root handed to
+//TRANSACTION(...) so the event is only stored when the business writes of the transaction commit.
+//The payload is stored as JSON.
+func (o *Outbox) Write(tx *supersql.SqlQuery, aggregate string, topic string, payload interface{}) (int64, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return 0, fmt.Errorf("outbox: encoding payload of %s: %w", topic, err)
+	}
+	r, err := tx.INSERT_INTO(o.Table, []string{"aggregate", "topic", "payload"}).
+		VALUES([]interface{}{aggregate, topic, string(data)}).RETURNING("id").GO()
+	if err != nil {
+		return 0, err
+	}
+	if r.Count() == 0 {
+		return 0, fmt.Errorf("outbox: no id returned for the new event")
+	}
+	return r.Rows(1).Int64("id")
+}
+
+//Relay of the events in the outbox to publisher
+func (o *Outbox) Relay(publisher Publisher) *Relay {
+	return &Relay{outbox: o, publisher: publisher}
+}
+
+//Moves events from the outbox to a publisher. Any number of relays can run against the same outbox,
+//they skip the events other relays are publishing.
+type Relay struct {
+	outbox    *Outbox
+	publisher Publisher
+
+	//DefaultBatchSize when zero. A pass keeps its transaction open and its events locked until it
+	//published every one of them so a pass lasts about BatchSize calls to the publisher, raise it
+	//only for publishers that are quick to answer.
+	BatchSize int
+	//DefaultInterval when zero
+	Interval time.Duration
+	//Delete published events instead of recording when they were published
+	Delete bool
+	//Delay before the next attempt to publish an event that failed attempt times, DefaultBackoff
+	//when nil
+	Backoff func(attempt int) time.Duration
+	//Invoked with errors that did not stop the relay i.e. failures to publish
+	OnError func(err error)
+}
+
+//Exponential backoff from a second doubling with every attempt up to an hour
+func DefaultBackoff(attempt int) time.Duration {
+	return backoff.Exponential(attempt)
+}
+
+//Publish events until ctx is done, pausing for Interval whenever there is nothing to publish
+func (r *Relay) Run(ctx context.Context) error {
+	for {
+		published, err := r.Deliver(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.report(err)
+		}
+		if published > 0 && err == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(r.interval()):
+		}
+	}
+}
+
+var columns = []string{"o.id", "o.aggregate", "o.topic", "o.payload::text AS payload", "o.attempts", "o.created_at",
+	"COALESCE(o.last_error, '') AS last_error"}
+
+//Publish the events that are due and first in line for their aggregate, at most BatchSize of them.
+//The events stay locked while they are published and are recorded as published or scheduled for a
+//retry in the same transaction, which stays open for all of the calls to the publisher. Reports how
+//many events were published.
+func (r *Relay) Deliver(ctx context.Context) (int, error) {
+	table := r.outbox.Table
+	published := 0
+	err := r.outbox.q.WithContext(ctx).TRANSACTION(func(tx *supersql.SqlQuery) error {
+		//an earlier event of the aggregate that is still pending keeps the later ones back even while
+		//another relay holds it locked
+		first := fmt.Sprintf("NOT EXISTS (SELECT 1 FROM %s e WHERE e.aggregate = o.aggregate AND e.published_at IS NULL AND e.id < o.id)", table)
+		rows, err := tx.SELECT(columns...).FROM(table + " o").
+			WHERE("o.published_at IS NULL AND o.retry_at <= now() AND " + first).
+			ORDER_BY("o.id").LIMIT(r.batchSize()).FOR_UPDATE().SKIP_LOCKED().GO()
+		if err != nil {
+			return err
+		}
+		events := []Event{}
+		for _, row := range rows.All() {
+			event, err := scan(row)
+			if err != nil {
+				return err
+			}
+			events = append(events, event)
+		}
+
+		for _, event := range events {
+			if err := r.publisher.Publish(ctx, event); err != nil {
+				r.report(fmt.Errorf("outbox: publishing %d %s of %s: %w", event.ID, event.Topic, event.Aggregate, err))
+				retry := fmt.Sprintf("UPDATE %s SET attempts = attempts + 1, retry_at = now() + ? * interval '1 second', last_error = ?", table)
+				if _, err := tx.RAW(retry+" WHERE id = ?", r.backoff(event.Attempts+1).Seconds(), err.Error(), event.ID).GO(); err != nil {
+					return err
+				}
+				continue
+			}
+			done := fmt.Sprintf("UPDATE %s SET attempts = attempts + 1, published_at = now()", table)
+			if r.Delete {
+				done = fmt.Sprintf("DELETE FROM %s", table)
+			}
+			if _, err := tx.RUN(done).WHERE("id = ?", event.ID).GO(); err != nil {
+				return err
+			}
+			published++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return published, nil
+}
+
+func (r *Relay) batchSize() int {
+	if r.BatchSize <= 0 {
+		return DefaultBatchSize
+	}
+	return r.BatchSize
+}
+
+func (r *Relay) interval() time.Duration {
+	if r.Interval <= 0 {
+		return DefaultInterval
+	}
+	return r.Interval
+}
+
+func (r *Relay) backoff(attempt int) time.Duration {
+	if r.Backoff == nil {
+		return DefaultBackoff(attempt)
+	}
+	return r.Backoff(attempt)
+}
+
+func (r *Relay) report(err error) {
+	if r.OnError != nil {
+		r.OnError(err)
+	}
+}
+
+func scan(row supersql.Row) (Event, error) {
+	id, err := row.Int64("id")
+	if err != nil {
+		return Event{}, err
+	}
+	attempts, err := row.Int64("attempts")
+	if err != nil {
+		return Event{}, err
+	}
+	event := Event{ID: id, Attempts: int(attempts)}
+	event.Aggregate, _ = row.String("aggregate")
+	event.Topic, _ = row.String("topic")
+	payload, _ := row.String("payload")
+	event.Payload = json.RawMessage(payload)
+	event.CreatedAt, _ = row.Column("created_at").(time.Time)
+	event.LastError, _ = row.String("last_error")
+	return event, nil
+}
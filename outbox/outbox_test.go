@@ -0,0 +1,166 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rayattack/supersql"
+	"github.com/rayattack/supersql/outbox"
+	"github.com/rayattack/supersql/supersqltest"
+)
+
+var (
+	ctx     = context.Background()
+	columns = []string{"id", "aggregate", "topic", "payload", "attempts", "created_at", "last_error"}
+	written = time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+)
+
+type orderPlaced struct {
+	ID int `json:"id"`
+}
+
+func event(id int64, aggregate string, attempts int32) []interface{} {
+	return []interface{}{id, aggregate, "order.placed", `{"id": 42}`, attempts, written, ""}
+}
+
+func sent(fake *supersqltest.Fake) []string {
+	ssql := []string{}
+	for _, call := range fake.Calls() {
+		ssql = append(ssql, call.SQL)
+	}
+	return ssql
+}
+
+func TestSetup(t *testing.T) {
+	fake := supersqltest.New()
+	box := outbox.New(fake.Root())
+	box.Table = `events."Outbox"`
+	if err := box.Setup(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if index := sent(fake)[1]; index != `CREATE INDEX IF NOT EXISTS "Outbox_pending" ON events."Outbox" (aggregate, id) WHERE published_at IS NULL` {
+		t.Fatal(index)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	fake := supersqltest.New()
+	fake.Expect("INSERT INTO supersql_outbox").Returns([]string{"id"}, []interface{}{int64(9)})
+	box := outbox.New(fake.Root())
+	var id int64
+	err := fake.Root().TRANSACTION(func(tx *supersql.SqlQuery) (err error) {
+		if _, err := tx.INSERT_INTO("orders", []string{"id"}).VALUES([]interface{}{42}).GO(); err != nil {
+			return err
+		}
+		id, err = box.Write(tx, "order:42", "order.placed", orderPlaced{42})
+		return err
+	})
+	if err != nil || id != 9 {
+		t.Fatal(id, err)
+	}
+	calls := fake.Calls()
+	if calls[0].SQL != "BEGIN" || calls[2].SQL != "INSERT INTO supersql_outbox (aggregate, topic, payload) VALUES ($1, $2, $3) RETURNING id" || calls[3].SQL != "COMMIT" {
+		t.Fatal(calls)
+	}
+	if calls[2].Args[0] != "order:42" || calls[2].Args[2] != `{"id":42}` {
+		t.Fatal(calls[2].Args)
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	fake := supersqltest.New()
+	fake.Expect("FOR UPDATE SKIP LOCKED").Returns(columns, event(1, "order:42", 0), event(3, "order:43", 2))
+	published := []outbox.Event{}
+	relay := outbox.New(fake.Root()).Relay(outbox.PublisherFunc(func(ctx context.Context, e outbox.Event) error {
+		published = append(published, e)
+		if e.Aggregate == "order:43" {
+			return errors.New("broker unavailable")
+		}
+		return nil
+	}))
+	relay.Backoff = func(attempt int) time.Duration { return time.Duration(attempt) * time.Second }
+	failures := []error{}
+	relay.OnError = func(err error) { failures = append(failures, err) }
+
+	n, err := relay.Deliver(ctx)
+	if err != nil || n != 1 || len(published) != 2 || len(failures) != 1 {
+		t.Fatal(n, err, published, failures)
+	}
+	var order orderPlaced
+	if err := published[0].Decode(&order); err != nil || order.ID != 42 || !published[0].CreatedAt.Equal(written) {
+		t.Fatal(order, err)
+	}
+
+	calls := fake.Calls()
+	ssql := sent(fake)
+	expected := "SELECT o.id, o.aggregate, o.topic, o.payload::text AS payload, o.attempts, o.created_at, COALESCE(o.last_error, '') AS last_error " +
+		"FROM supersql_outbox o WHERE o.published_at IS NULL AND o.retry_at <= now() AND " +
+		"NOT EXISTS (SELECT 1 FROM supersql_outbox e WHERE e.aggregate = o.aggregate AND e.published_at IS NULL AND e.id < o.id) " +
+		"ORDER BY o.id LIMIT 10 FOR UPDATE SKIP LOCKED"
+	if ssql[0] != "BEGIN" || ssql[1] != expected || ssql[len(ssql)-1] != "COMMIT" {
+		t.Fatal(strings.Join(ssql, "\n"))
+	}
+	if ssql[2] != "UPDATE supersql_outbox SET attempts = attempts + 1, published_at = now() WHERE id = $1" || calls[2].Args[0] != int64(1) {
+		t.Fatal(ssql[2], calls[2].Args)
+	}
+	if !strings.Contains(ssql[3], "retry_at = now() + $1 * interval '1 second', last_error = $2 WHERE id = $3") ||
+		calls[3].Args[0] != 3.0 || calls[3].Args[1] != "broker unavailable" || calls[3].Args[2] != int64(3) {
+		t.Fatal(ssql[3], calls[3].Args)
+	}
+}
+
+func TestDeliverDeletes(t *testing.T) {
+	fake := supersqltest.New()
+	fake.Expect("FOR UPDATE SKIP LOCKED").Returns(columns, event(1, "order:42", 0))
+	relay := outbox.New(fake.Root()).Relay(outbox.PublisherFunc(func(ctx context.Context, e outbox.Event) error { return nil }))
+	relay.Delete, relay.BatchSize = true, 50
+	if n, err := relay.Deliver(ctx); err != nil || n != 1 {
+		t.Fatal(n, err)
+	}
+	ssql := sent(fake)
+	if !strings.HasSuffix(ssql[1], "LIMIT 50 FOR UPDATE SKIP LOCKED") || ssql[2] != "DELETE FROM supersql_outbox WHERE id = $1" {
+		t.Fatal(strings.Join(ssql, "\n"))
+	}
+
+	fake = supersqltest.New()
+	fake.Expect("DELETE FROM").Fails(errors.New("conn closed"))
+	fake.Expect("FOR UPDATE SKIP LOCKED").Returns(columns, event(1, "order:42", 0))
+	relay = outbox.New(fake.Root()).Relay(outbox.PublisherFunc(func(ctx context.Context, e outbox.Event) error { return nil }))
+	relay.Delete = true
+	if n, err := relay.Deliver(ctx); err == nil || n != 0 {
+		t.Fatal("failing to record a publication rolls the pass back", n, err)
+	}
+	if ssql := sent(fake); ssql[len(ssql)-1] != "ROLLBACK" {
+		t.Fatal(ssql)
+	}
+}
+
+func TestRun(t *testing.T) {
+	fake := supersqltest.New()
+	fake.Expect("FOR UPDATE SKIP LOCKED").Returns(columns, event(1, "order:42", 0), event(2, "order:43", 0))
+	published := make(chan int64, 2)
+	relay := outbox.New(fake.Root()).Relay(outbox.PublisherFunc(func(ctx context.Context, e outbox.Event) error {
+		published <- e.ID
+		return nil
+	}))
+	relay.Interval = time.Millisecond
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan error)
+	go func() { done <- relay.Run(ctx) }()
+	if first, second := <-published, <-published; first != 1 || second != 2 {
+		t.Fatal(first, second)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDefaultBackoff(t *testing.T) {
+	if outbox.DefaultBackoff(1) != 2*time.Second || outbox.DefaultBackoff(50) != time.Hour {
+		t.Fatal(outbox.DefaultBackoff(1), outbox.DefaultBackoff(50))
+	}
+}
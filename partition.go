@@ -0,0 +1,144 @@
+package supersql
+
+import (
+	"fmt"
+	"strings"
+)
+
+//Bound a range partition can start or end at without naming a value
+type boundary string
+
+const (
+	MINVALUE boundary = "MINVALUE"
+	MAXVALUE boundary = "MAXVALUE"
+)
+
+//The values of the partition key a partition holds
+type PartitionBound struct {
+	spec string
+}
+
+//Records whose key is at least from and less than to. Multi column keys pass a []interface{} for both.
+//
+//	supersql.FROM_TO("2024-01-01", "2024-02-01")
+//	supersql.FROM_TO([]interface{}{2024, 1}, []interface{}{2024, supersql.MAXVALUE})
+func FROM_TO(from, to interface{}) PartitionBound {
+	return PartitionBound{spec: fmt.Sprintf("FOR VALUES FROM (%s) TO (%s)", bound(from), bound(to))}
+}
+
+//Records whose key is one of values
+func IN(values ...interface{}) PartitionBound {
+	return PartitionBound{spec: fmt.Sprintf("FOR VALUES IN (%s)", bound(values))}
+}
+
+//Records whose key hashes to remainder when divided by modulus
+func WITH_MODULUS(modulus, remainder int) PartitionBound {
+	return PartitionBound{spec: fmt.Sprintf("FOR VALUES WITH (MODULUS %d, REMAINDER %d)", modulus, remainder)}
+}
+
+//Records no other partition of the table holds
+var DEFAULT_PARTITION = PartitionBound{spec: "DEFAULT"}
+
+func (b PartitionBound) String() string {
+	return b.spec
+}
+
+func bound(value interface{}) string {
+	switch v := value.(type) {
+	case boundary:
+		return string(v)
+	case []interface{}:
+		values := []string{}
+		for _, element := range v {
+			values = append(values, bound(element))
+		}
+		return strings.Join(values, ", ")
+	}
+	return literal(value)
+}
+
+//A partition of a partitioned table
+//
+//	events := supersql.Table("events", fields...).PARTITION_BY_RANGE("created_at")
+//	events.PARTITION("events_2024_01", supersql.FROM_TO("2024-01-01", "2024-02-01")).CREATE()
+type SqlPartition struct {
+	name        string
+	parent      string
+	bound       PartitionBound
+	ifNotExists bool
+}
+
+//Declare the partition called name holding the records of t within bound
+func (t *SqlTable) PARTITION(name string, bound PartitionBound) SqlPartition {
+	return SqlPartition{name: name, parent: t.identifier(Postgres), bound: bound}
+}
+
+func (p SqlPartition) IF_NOT_EXISTS() SqlPartition {
+	p.ifNotExists = true
+	return p
+}
+
+func (p SqlPartition) Name() string {
+	return p.name
+}
+
+func (p SqlPartition) Parent() string {
+	return p.parent
+}
+
+func (p SqlPartition) Bound() PartitionBound {
+	return p.bound
+}
+
+//Generate the CREATE TABLE ... PARTITION OF statement
+func (p SqlPartition) CREATE() string {
+	parts := []string{"CREATE TABLE"}
+	if p.ifNotExists {
+		parts = append(parts, "IF NOT EXISTS")
+	}
+	parts = append(parts, p.name, "PARTITION OF", p.parent, p.bound.spec)
+	return strings.Join(parts, " ")
+}
+
+func (p SqlPartition) String() string {
+	return p.CREATE()
+}
+
+//Statement making an existing table with the columns of the parent a partition of it
+func (p SqlPartition) ATTACH() string {
+	return fmt.Sprintf("ALTER TABLE %s ATTACH PARTITION %s %s", p.parent, p.name, p.bound.spec)
+}
+
+//Statement turning the partition back into a standalone table, its records stay in it
+func (p SqlPartition) DETACH() SqlDetach {
+	return SqlDetach{parent: p.parent, name: p.name}
+}
+
+//Statement detaching the partition called name from t
+func (t *SqlTable) DETACH_PARTITION(name string) SqlDetach {
+	return SqlDetach{parent: t.identifier(Postgres), name: name}
+}
+
+func (p SqlPartition) DROP() SqlDrop {
+	return SqlDrop{kind: "TABLE", name: p.name}
+}
+
+type SqlDetach struct {
+	parent       string
+	name         string
+	concurrently bool
+}
+
+//Detach without blocking queries on the parent. Postgres 14 and above, not inside a transaction.
+func (d SqlDetach) CONCURRENTLY() SqlDetach {
+	d.concurrently = true
+	return d
+}
+
+func (d SqlDetach) String() string {
+	ssql := fmt.Sprintf("ALTER TABLE %s DETACH PARTITION %s", d.parent, d.name)
+	if d.concurrently {
+		ssql += " CONCURRENTLY"
+	}
+	return ssql
+}
@@ -0,0 +1,289 @@
+//Package partition keeps tables partitioned by ranges of time supplied with partitions. A manager
+//creates the partitions of the coming periods ahead of time and detaches and drops the ones that
+//fell out of the retention policy, run Maintain(...) from a scheduled job more often than the
+//interval of the partitions.
+//
+//	events := supersql.Table("events", fields...).PARTITION_BY_RANGE("created_at")
+//	m, _ := partition.New(q, events, partition.Monthly)
+//	m.Premake, m.Retention = 3, 12
+//	report, err := m.Maintain(ctx) // creates events_p2024_07 ... and drops events_p2023_06
+package partition
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/rayattack/supersql"
+	"github.com/rayattack/supersql/introspect"
+)
+
+//Length of the period of time a partition holds
+type Interval int
+
+const (
+	Daily Interval = iota + 1
+	//Weeks start on monday
+	Weekly
+	Monthly
+	Yearly
+)
+
+//Start of the period holding t
+func (i Interval) Start(t time.Time) time.Time {
+	switch i {
+	case Daily:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	case Weekly:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case Yearly:
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, t.Location())
+	}
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
+
+//Start of the period n periods after the one starting at start, before it when n is negative
+func (i Interval) Add(start time.Time, n int) time.Time {
+	switch i {
+	case Daily:
+		return start.AddDate(0, 0, n)
+	case Weekly:
+		return start.AddDate(0, 0, 7*n)
+	case Yearly:
+		return start.AddDate(n, 0, 0)
+	}
+	return start.AddDate(0, n, 0)
+}
+
+func (i Interval) layout() string {
+	switch i {
+	case Daily, Weekly:
+		return "2006_01_02"
+	case Yearly:
+		return "2006"
+	}
+	return "2006_01"
+}
+
+//Partitions created ahead of the current one when Manager.Premake is zero
+const DefaultPremake = 3
+
+type Manager struct {
+	q        *supersql.SqlQuery
+	table    *supersql.SqlTable
+	interval Interval
+
+	//Partitions created ahead of the one holding the current time, DefaultPremake when zero
+	Premake int
+	//Partitions kept before the one holding the current time, older ones are detached and dropped.
+	//Nothing expires when zero.
+	Retention int
+	//Detach expired partitions without dropping them i.e. to archive them first
+	KeepDetached bool
+	//Detach without blocking queries on the table, postgres 14 and above
+	Concurrently bool
+	//Time zone the periods are aligned to, UTC when nil
+	Location *time.Location
+	//Name of the partition starting at from given the name of the table without its schema, the
+	//table name followed by _p and the start of the period i.e. events_p2024_07 for monthly
+	//partitions when nil. Partitions are created in the schema of the table.
+	Naming func(table string, from time.Time) string
+	//When set the SQL of every step is written here instead of being executed
+	DryRun io.Writer
+	//Current time, time.Now when nil
+	Now func() time.Time
+}
+
+//Outcome of a maintenance run, partitions are listed by their schema qualified names
+type Report struct {
+	Created  []string
+	Detached []string
+	Dropped  []string
+	//Partitions that were detached but could not be dropped. They no longer belong to the table so
+	//later runs do not see them, drop them by hand.
+	Undropped []string
+}
+
+//Manage the partitions of table which must be declared with PARTITION_BY_RANGE over a single date
+//or timestamp column
+func New(q *supersql.SqlQuery, table *supersql.SqlTable, interval Interval) (*Manager, error) {
+	strategy, keys := table.Partitioning()
+	if strategy != "RANGE" || len(keys) != 1 {
+		return nil, fmt.Errorf("partition: %s must be partitioned by range of a single key", table.Name())
+	}
+	if interval < Daily || interval > Yearly {
+		return nil, fmt.Errorf("partition: unknown interval %d", interval)
+	}
+	return &Manager{q: q, table: table, interval: interval}, nil
+}
+
+//The existing partitions of the table as reported by the catalog
+func (m *Manager) Partitions(ctx context.Context) ([]introspect.Partition, error) {
+	schema, name := "", m.table.Name()
+	if qualifier, table, ok := strings.Cut(name, "."); ok {
+		schema, name = qualifier, table
+	}
+	return introspect.Partitions(ctx, m.q, schema, name)
+}
+
+//Create the missing partitions from the current period up to Premake periods ahead and expire
+//the partitions older than the retention policy. Periods already covered by an existing partition
+//are skipped whatever its name is.
+func (m *Manager) Maintain(ctx context.Context) (Report, error) {
+	report := Report{}
+	existing, err := m.Partitions(ctx)
+	if err != nil {
+		return report, err
+	}
+	spans := []span{}
+	for _, p := range existing {
+		if s, ok := m.span(p); ok {
+			spans = append(spans, s)
+		}
+	}
+
+	q := m.q.WithContext(ctx)
+	current := m.interval.Start(m.now())
+	premake := m.Premake
+	if premake == 0 {
+		premake = DefaultPremake
+	}
+	for n := 0; n <= premake; n++ {
+		from, to := m.interval.Add(current, n), m.interval.Add(current, n+1)
+		if overlaps(spans, from, to) {
+			continue
+		}
+		name := qualified(m.table.Schema(), m.name(from))
+		bound := supersql.FROM_TO(from.Format(timestamp), to.Format(timestamp))
+		if err := m.exec(q, m.table.PARTITION(name, bound).IF_NOT_EXISTS().CREATE()); err != nil {
+			return report, fmt.Errorf("partition: creating %s: %w", name, err)
+		}
+		report.Created = append(report.Created, name)
+	}
+
+	if m.Retention <= 0 {
+		return report, nil
+	}
+	cutoff := m.interval.Add(current, -m.Retention)
+	for _, s := range spans {
+		if s.to.After(cutoff) {
+			continue
+		}
+		name := qualified(s.schema, s.name)
+		detach := m.table.DETACH_PARTITION(name)
+		if m.Concurrently {
+			detach = detach.CONCURRENTLY()
+		}
+		if err := m.exec(q, detach.String()); err != nil {
+			return report, fmt.Errorf("partition: detaching %s: %w", name, err)
+		}
+		report.Detached = append(report.Detached, name)
+		if m.KeepDetached {
+			continue
+		}
+		if err := m.exec(q, "DROP TABLE "+name); err != nil {
+			report.Undropped = append(report.Undropped, name)
+			return report, fmt.Errorf("partition: %s was detached but dropping it failed, drop it by hand: %w", name, err)
+		}
+		report.Dropped = append(report.Dropped, name)
+	}
+	return report, nil
+}
+
+func (m *Manager) exec(q *supersql.SqlQuery, ssql string) error {
+	if m.DryRun != nil {
+		_, err := fmt.Fprintf(m.DryRun, "%s;\n", ssql)
+		return err
+	}
+	_, err := q.RUN(ssql).GO()
+	return err
+}
+
+func (m *Manager) now() time.Time {
+	now := time.Now
+	if m.Now != nil {
+		now = m.Now
+	}
+	location := m.Location
+	if location == nil {
+		location = time.UTC
+	}
+	return now().In(location)
+}
+
+func (m *Manager) name(from time.Time) string {
+	name := m.table.Name()
+	if _, table, ok := strings.Cut(name, "."); ok {
+		name = table
+	}
+	if m.Naming != nil {
+		return m.Naming(name, from)
+	}
+	return fmt.Sprintf("%s_p%s", name, from.Format(m.interval.layout()))
+}
+
+//Name of a partition in schema quoted where needed
+func qualified(schema, name string) string {
+	return supersql.Table(name).InSchema(schema).Identifier()
+}
+
+//Bounds are written with their offset so timestamptz keys do not depend on the session time zone
+const timestamp = "2006-01-02 15:04:05-07:00"
+
+//Layouts the catalog reports range bounds of date and timestamp keys in
+var layouts = []string{"2006-01-02 15:04:05.999999-07", "2006-01-02 15:04:05.999999-07:00", "2006-01-02 15:04:05.999999", "2006-01-02"}
+
+//Period of time held by a range partition
+type span struct {
+	schema   string
+	name     string
+	from, to time.Time
+}
+
+var (
+	earliest = time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)
+	latest   = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+)
+
+func (m *Manager) span(p introspect.Partition) (span, bool) {
+	if p.Default || len(p.From) != 1 || len(p.To) != 1 {
+		return span{}, false
+	}
+	from, ok := m.parse(p.From[0], earliest)
+	if !ok {
+		return span{}, false
+	}
+	to, ok := m.parse(p.To[0], latest)
+	if !ok {
+		return span{}, false
+	}
+	return span{schema: p.Schema, name: p.Name, from: from, to: to}, true
+}
+
+func (m *Manager) parse(value string, unbounded time.Time) (time.Time, bool) {
+	if value == "MINVALUE" || value == "MAXVALUE" {
+		return unbounded, true
+	}
+	location := m.Location
+	if location == nil {
+		location = time.UTC
+	}
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, value, location); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func overlaps(spans []span, from, to time.Time) bool {
+	for _, s := range spans {
+		if s.from.Before(to) && from.Before(s.to) {
+			return true
+		}
+	}
+	return false
+}
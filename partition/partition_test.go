@@ -0,0 +1,187 @@
+package partition_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rayattack/supersql"
+	"github.com/rayattack/supersql/partition"
+	"github.com/rayattack/supersql/supersqltest"
+)
+
+var events = supersql.Table("events", supersql.Timestamptz("created_at", supersql.NOT_NULL)).PARTITION_BY_RANGE("created_at")
+
+func existing(fake *supersqltest.Fake, rows ...[]interface{}) {
+	fake.Expect("FROM pg_inherits").Returns([]string{"schema", "name", "bound", "partitioned"}, rows...)
+}
+
+func month(name, from, to string) []interface{} {
+	return []interface{}{"public", name, "FOR VALUES FROM ('" + from + " 00:00:00+00') TO ('" + to + " 00:00:00+00')", false}
+}
+
+func statements(fake *supersqltest.Fake) []string {
+	ssql := []string{}
+	for _, call := range fake.Calls()[1:] {
+		ssql = append(ssql, call.SQL)
+	}
+	return ssql
+}
+
+func TestNew(t *testing.T) {
+	fake := supersqltest.New()
+	if _, err := partition.New(fake.Root(), supersql.Table("events"), partition.Monthly); err == nil {
+		t.Fatal("tables that are not partitioned can not be managed")
+	}
+	if _, err := partition.New(fake.Root(), supersql.Table("sales").PARTITION_BY_LIST("region"), partition.Monthly); err == nil {
+		t.Fatal("only range partitioned tables can be managed")
+	}
+	if _, err := partition.New(fake.Root(), events, partition.Interval(9)); err == nil {
+		t.Fatal("unknown intervals are rejected")
+	}
+}
+
+func TestIntervals(t *testing.T) {
+	now := time.Date(2024, 7, 18, 15, 30, 0, 0, time.UTC)
+	cases := map[partition.Interval]string{
+		partition.Daily:   "2024-07-18",
+		partition.Weekly:  "2024-07-15",
+		partition.Monthly: "2024-07-01",
+		partition.Yearly:  "2024-01-01",
+	}
+	for interval, want := range cases {
+		if start := interval.Start(now); start.Format("2006-01-02") != want {
+			t.Fatal(interval, start)
+		}
+	}
+	if next := partition.Monthly.Add(partition.Monthly.Start(now), -7); next.Format("2006-01-02") != "2023-12-01" {
+		t.Fatal(next)
+	}
+	if sunday := partition.Weekly.Start(time.Date(2024, 7, 21, 0, 0, 0, 0, time.UTC)); sunday.Day() != 15 {
+		t.Fatal(sunday)
+	}
+}
+
+func TestMaintain(t *testing.T) {
+	fake := supersqltest.New()
+	existing(fake,
+		[]interface{}{"public", "events_default", "DEFAULT", false},
+		month("events_p2023_12", "2023-12-01", "2024-01-01"),
+		month("events_p2024_01", "2024-01-01", "2024-02-01"),
+		month("events_p2024_06", "2024-06-01", "2024-07-01"),
+		month("events_july", "2024-07-01", "2024-08-01"),
+	)
+	m, err := partition.New(fake.Root(), events, partition.Monthly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Premake, m.Retention = 2, 6
+	m.Now = func() time.Time { return time.Date(2024, 7, 18, 12, 0, 0, 0, time.UTC) }
+
+	report, err := m.Maintain(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(report.Created, ",") != "events_p2024_08,events_p2024_09" {
+		t.Fatal(report.Created)
+	}
+	if strings.Join(report.Detached, ",") != "public.events_p2023_12" || strings.Join(report.Dropped, ",") != "public.events_p2023_12" {
+		t.Fatal(report)
+	}
+	want := []string{
+		"CREATE TABLE IF NOT EXISTS events_p2024_08 PARTITION OF events FOR VALUES FROM ('2024-08-01 00:00:00+00:00') TO ('2024-09-01 00:00:00+00:00')",
+		"CREATE TABLE IF NOT EXISTS events_p2024_09 PARTITION OF events FOR VALUES FROM ('2024-09-01 00:00:00+00:00') TO ('2024-10-01 00:00:00+00:00')",
+		"ALTER TABLE events DETACH PARTITION public.events_p2023_12",
+		"DROP TABLE public.events_p2023_12",
+	}
+	if got := statements(fake); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatal(strings.Join(got, "\n"))
+	}
+}
+
+func TestMaintainKeepsDetachedAndDryRun(t *testing.T) {
+	fake := supersqltest.New()
+	existing(fake,
+		month("events_p2024_05", "2024-05-01", "2024-06-01"),
+		month("events_p2024_06", "2024-06-01", "2024-07-01"),
+		[]interface{}{"public", "events_future", "FOR VALUES FROM ('2024-07-01 00:00:00+00') TO (MAXVALUE)", false},
+	)
+	m, _ := partition.New(fake.Root(), events, partition.Monthly)
+	m.Retention, m.KeepDetached, m.Concurrently = 1, true, true
+	m.Now = func() time.Time { return time.Date(2024, 7, 2, 0, 0, 0, 0, time.UTC) }
+	out := &bytes.Buffer{}
+	m.DryRun = out
+
+	report, err := m.Maintain(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Created) != 0 || strings.Join(report.Detached, ",") != "public.events_p2024_05" || len(report.Dropped) != 0 {
+		t.Fatal(report)
+	}
+	if out.String() != "ALTER TABLE events DETACH PARTITION public.events_p2024_05 CONCURRENTLY;\n" {
+		t.Fatal(out.String())
+	}
+	if len(fake.Calls()) != 1 {
+		t.Fatal("dry runs only read the catalog")
+	}
+}
+
+func TestMaintainNamingAndErrors(t *testing.T) {
+	fake := supersqltest.New()
+	existing(fake)
+	fake.Expect("CREATE TABLE").Fails(errors.New("permission denied"))
+	m, _ := partition.New(fake.Root(), supersql.Table("audit.log").PARTITION_BY_RANGE("at"), partition.Daily)
+	m.Now = func() time.Time { return time.Date(2024, 7, 18, 23, 0, 0, 0, time.UTC) }
+	m.Location = time.FixedZone("CEST", 2*60*60)
+
+	_, err := m.Maintain(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "creating audit.log_p2024_07_19") {
+		t.Fatal(err)
+	}
+	if call := fake.Calls()[0]; call.Args[0] != "audit" || call.Args[1] != "log" {
+		t.Fatal(call.Args)
+	}
+	if create := fake.Calls()[1].SQL; !strings.HasPrefix(create, "CREATE TABLE IF NOT EXISTS audit.log_p2024_07_19 PARTITION OF audit.log FOR VALUES FROM ('2024-07-19 00:00:00+02:00')") {
+		t.Fatal(create)
+	}
+
+	fake = supersqltest.New()
+	existing(fake)
+	m, _ = partition.New(fake.Root(), events, partition.Yearly)
+	m.Premake = 1
+	m.Naming = func(table string, from time.Time) string { return table + "_" + from.Format("06") }
+	m.Now = func() time.Time { return time.Date(2024, 7, 18, 0, 0, 0, 0, time.UTC) }
+	report, err := m.Maintain(context.Background())
+	if err != nil || strings.Join(report.Created, ",") != "events_24,events_25" {
+		t.Fatal(report, err)
+	}
+}
+
+func TestMaintainQualifiesNames(t *testing.T) {
+	fake := supersqltest.New()
+	existing(fake, []interface{}{"billing", "Events_p2024_01", "FOR VALUES FROM ('2024-01-01 00:00:00+00') TO ('2024-02-01 00:00:00+00')", false})
+	fake.Expect("DROP TABLE").Fails(errors.New("lock timeout"))
+	m, _ := partition.New(fake.Root(), supersql.Table(`billing."Events"`).PARTITION_BY_RANGE("created_at"), partition.Monthly)
+	m.Retention = 1
+	m.Now = func() time.Time { return time.Date(2024, 7, 18, 12, 0, 0, 0, time.UTC) }
+
+	report, err := m.Maintain(context.Background())
+	if err == nil || !strings.Contains(err.Error(), `billing."Events_p2024_01" was detached`) {
+		t.Fatal(err)
+	}
+	if len(report.Dropped) != 0 || strings.Join(report.Undropped, ",") != `billing."Events_p2024_01"` {
+		t.Fatal("partitions that were detached but not dropped are reported", report)
+	}
+	want := []string{
+		`CREATE TABLE IF NOT EXISTS billing."Events_p2024_07" PARTITION OF billing."Events" FOR VALUES FROM ('2024-07-01 00:00:00+00:00') TO ('2024-08-01 00:00:00+00:00')`,
+		`ALTER TABLE billing."Events" DETACH PARTITION billing."Events_p2024_01"`,
+		`DROP TABLE billing."Events_p2024_01"`,
+	}
+	if got := statements(fake); got[0] != want[0] || strings.Join(got[len(got)-2:], "\n") != strings.Join(want[1:], "\n") {
+		t.Fatal(strings.Join(got, "\n"))
+	}
+}
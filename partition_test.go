@@ -0,0 +1,71 @@
+package supersql_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rayattack/supersql"
+)
+
+func TestPartitionedTable(t *testing.T) {
+	events := supersql.Table("events",
+		supersql.BigInt("event_id", supersql.NOT_NULL),
+		supersql.Timestamptz("created_at", supersql.NOT_NULL),
+	).PARTITION_BY_RANGE("created_at")
+	if events.CREATE() != "CREATE TABLE events (event_id bigint NOT NULL, created_at timestamptz NOT NULL) PARTITION BY RANGE (created_at)" {
+		t.Fatal(events.CREATE())
+	}
+	if strategy, keys := events.Partitioning(); strategy != "RANGE" || len(keys) != 1 || keys[0] != "created_at" {
+		t.Fatal(strategy, keys)
+	}
+
+	january := events.PARTITION("events_2024_01", supersql.FROM_TO("2024-01-01", "2024-02-01"))
+	if january.CREATE() != "CREATE TABLE events_2024_01 PARTITION OF events FOR VALUES FROM ('2024-01-01') TO ('2024-02-01')" {
+		t.Fatal(january.CREATE())
+	}
+	if january.IF_NOT_EXISTS().String() != "CREATE TABLE IF NOT EXISTS events_2024_01 PARTITION OF events FOR VALUES FROM ('2024-01-01') TO ('2024-02-01')" {
+		t.Fatal(january.IF_NOT_EXISTS().String())
+	}
+	if january.ATTACH() != "ALTER TABLE events ATTACH PARTITION events_2024_01 FOR VALUES FROM ('2024-01-01') TO ('2024-02-01')" {
+		t.Fatal(january.ATTACH())
+	}
+	if detach := january.DETACH().CONCURRENTLY().String(); detach != "ALTER TABLE events DETACH PARTITION events_2024_01 CONCURRENTLY" {
+		t.Fatal(detach)
+	}
+	if drop := january.DROP().String(); drop != "DROP TABLE events_2024_01" {
+		t.Fatal(drop)
+	}
+
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	open := events.PARTITION("events_recent", supersql.FROM_TO(since, supersql.MAXVALUE))
+	if open.CREATE() != "CREATE TABLE events_recent PARTITION OF events FOR VALUES FROM ('2024-01-01T00:00:00Z') TO (MAXVALUE)" {
+		t.Fatal(open.CREATE())
+	}
+}
+
+func TestListAndHashPartitions(t *testing.T) {
+	stores := supersql.Table("sales", supersql.Text("region"), supersql.Integer("store_id")).PARTITION_BY_LIST("region")
+	if stores.CREATE() != "CREATE TABLE sales (region text, store_id integer) PARTITION BY LIST (region)" {
+		t.Fatal(stores.CREATE())
+	}
+	europe := stores.PARTITION("sales_europe", supersql.IN("de", "fr", "it"))
+	if europe.CREATE() != "CREATE TABLE sales_europe PARTITION OF sales FOR VALUES IN ('de', 'fr', 'it')" {
+		t.Fatal(europe.CREATE())
+	}
+	if other := stores.PARTITION("sales_other", supersql.DEFAULT_PARTITION); other.CREATE() != "CREATE TABLE sales_other PARTITION OF sales DEFAULT" {
+		t.Fatal(other.CREATE())
+	}
+
+	hashed := supersql.Table("sessions").PARTITION_BY_HASH("user_id")
+	if part := hashed.PARTITION("sessions_0", supersql.WITH_MODULUS(4, 0)); part.CREATE() != "CREATE TABLE sessions_0 PARTITION OF sessions FOR VALUES WITH (MODULUS 4, REMAINDER 0)" {
+		t.Fatal(part.CREATE())
+	}
+	if detach := hashed.DETACH_PARTITION("sessions_0").String(); detach != "ALTER TABLE sessions DETACH PARTITION sessions_0" {
+		t.Fatal(detach)
+	}
+
+	multi := supersql.FROM_TO([]interface{}{2024, 1}, []interface{}{2024, supersql.MAXVALUE})
+	if multi.String() != "FOR VALUES FROM (2024, 1) TO (2024, MAXVALUE)" {
+		t.Fatal(multi.String())
+	}
+}
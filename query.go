@@ -3,24 +3,33 @@ package supersql
 import (
 	"context"
 	"fmt"
-	"log"
 	"strings"
-
-	"github.com/jackc/pgx/v4"
-	"github.com/jackc/pgx/v4/pgxpool"
 )
 
 const POSTGRES_MAX_COLUMNS = 1600
 
 type SqlQuery struct {
-	conn *pgx.Conn
-	pool *pgxpool.Pool
+	exec Executor
 	ssql string
 	ctx  context.Context
 	args []interface{}
 	cols []string
 	vals [][]interface{}
 	void bool
+
+	dialect  Dialect
+	err      error
+	limited  bool
+	target   []string
+	table    string
+	upserted bool
+	windowed bool
+	locked   bool
+	hooks    []Hook
+
+	relations []*SqlTable
+	refs      []string
+	aliases   []string
 }
 
 //TODO: AS Documentation
@@ -32,24 +41,26 @@ func (q SqlQuery) AS(alias string) Command {
 //TODO: ASC Documentation
 func (q SqlQuery) ASC(ob ...string) Command {
 	if ob != nil {
+		q.reference(ob[0])
 		return q.ob(fmt.Sprintf("ORDER BY %s ASC", ob[0]))
 	}
 	q.ssql = fmt.Sprintf("%s ASC", q.ssql)
 	return q
 }
 
-//TODO: CLOSE Documentation
+//Release the connections owned by the query root. Roots created over database/sql handles with
+//QueryDB(...) leave the handle open as its lifecycle is managed by the caller.
 func (q *SqlQuery) CLOSE() error {
-	if q.pool != nil {
-		q.pool.Close()
-		return nil
+	if c, ok := q.exec.(closer); ok {
+		return c.Close()
 	}
-	return q.conn.Close(q.ctx)
+	return nil
 }
 
 //TODO: DESC Documentation
 func (q SqlQuery) DESC(ob ...string) Command {
 	if ob != nil {
+		q.reference(ob[0])
 		return q.ob(fmt.Sprintf("ORDER BY %s DESC", ob[0]))
 	}
 	q.ssql = fmt.Sprintf("%s DESC", q.ssql)
@@ -58,21 +69,81 @@ func (q SqlQuery) DESC(ob ...string) Command {
 
 //Helper function for DRY purposes to optimize inserting records by using pgx.CopyFrom as opposed
 //to a naive SQL INSERT command
-func (q SqlQuery) do(rows [][]interface{}) error {
-	affected, error := q.conn.CopyFrom(context.TODO(), pgx.Identifier{}, q.cols, pgx.CopyFromRows(rows))
-	if error != nil {
-		return error
+func (q SqlQuery) do(c copier, rows [][]interface{}) error {
+	ssql := fmt.Sprintf("COPY %s (%s) FROM STDIN", q.table, strings.Join(q.cols, ", "))
+	return q.observe(ssql, nil, func(ctx context.Context) (int64, error) {
+		return c.CopyFrom(ctx, q.table, q.cols, rows)
+	})
+}
+
+//Lock the selected records against concurrent UPDATE and DELETE until the transaction ends. Tables
+//given restrict the lock to records of those tables i.e. FOR UPDATE OF f when joining others.
+func (q SqlQuery) FOR_UPDATE(of ...interface{}) Command {
+	return q.lock("UPDATE", of)
+}
+
+//Weaker FOR_UPDATE(...) that still lets other transactions take FOR KEY SHARE locks i.e. for inserts
+//into tables referencing the locked records. Use it when the key columns are not going to change.
+func (q SqlQuery) FOR_NO_KEY_UPDATE(of ...interface{}) Command {
+	return q.lock("NO KEY UPDATE", of)
+}
+
+//Lock the selected records against changes while letting other transactions read and share lock them
+func (q SqlQuery) FOR_SHARE(of ...interface{}) Command {
+	return q.lock("SHARE", of)
+}
+
+//Weaker FOR_SHARE(...) that only blocks DELETE and changes to key columns
+func (q SqlQuery) FOR_KEY_SHARE(of ...interface{}) Command {
+	return q.lock("KEY SHARE", of)
+}
+
+func (q SqlQuery) lock(strength string, of []interface{}) Command {
+	tables := []string{}
+	for _, entity := range of {
+		if t, ok := entity.(*SqlTable); ok && t.alias != "" {
+			//aliased tables can only be locked by their alias
+			tables = append(tables, t.alias)
+			continue
+		}
+		tables = append(tables, q.relation(entity))
 	}
-	fmt.Print(affected)
-	return nil
+	clause, err := q.dialectOf().Lock(strength, tables)
+	if err != nil {
+		return q.fail(err)
+	}
+	q.locked = true
+	q.ssql = fmt.Sprintf("%s %s", q.ssql, clause)
+	return q
+}
+
+//Continuation expantiator for the FOR_... locks that fails at once instead of waiting for records
+//locked by other transactions
+func (q SqlQuery) NOWAIT() Command {
+	return q.wait("NOWAIT")
+}
+
+//Continuation expantiator for the FOR_... locks that leaves out records locked by other transactions
+//i.e. so workers sharing a queue table never pick the same record
+func (q SqlQuery) SKIP_LOCKED() Command {
+	return q.wait("SKIP LOCKED")
+}
+
+func (q SqlQuery) wait(policy string) Command {
+	if !q.locked {
+		return q.fail(fmt.Errorf("%s must follow a FOR_UPDATE, FOR_NO_KEY_UPDATE, FOR_SHARE or FOR_KEY_SHARE lock", policy))
+	}
+	q.locked = false
+	q.ssql = fmt.Sprintf("%s %s", q.ssql, policy)
+	return q
 }
 
 //TODO: FROM Documentation
 func (q SqlQuery) FROM(entities ...interface{}) Command {
 	e := []string{}
 	for _, entity := range entities {
-		t := coerceToString(entity)
-		e = append(e, t)
+		q.relate(entity)
+		e = append(e, q.relation(entity))
 	}
 	q.ssql = fmt.Sprintf("%s FROM %s", q.ssql, strings.Join(e, ","))
 	return q
@@ -83,48 +154,51 @@ func (q SqlQuery) FROM(entities ...interface{}) Command {
 //integer argument is less than zero i.e. -1, then all results will be loaded and if none is provided
 //then calling next() on the sqlresult and streaming back will be the default behaviour activated.
 func (q SqlQuery) GO(prefetch ...int) (Results, error) {
-	//i.e. if cols present we are in insert mode
-	if q.cols != nil {
-		return nil, q.do(q.vals)
+	if err := q.check(); err != nil {
+		return nil, err
 	}
-
-	q.ssql = countAndReplacePlaceholders(q.ssql)
-
-	if q.void {
-		_, error := q.pool.Exec(q.ctx, q.ssql, q.args...)
-		if error != nil {
-			return nil, error
+	//i.e. if cols present we are in insert mode
+	if c, ok := q.exec.(copier); ok && q.cols != nil && q.void && !q.upserted {
+		vals, err := q.bind()
+		if err != nil {
+			return nil, err
 		}
-		return nil, nil
+		return nil, q.do(c, vals)
 	}
-	ctrl, err := q.pool.Query(q.ctx, q.ssql, q.args...)
+	ssql, args, err := q.statement()
 	if err != nil {
 		return nil, err
 	}
+	q.ssql = ssql
 
-	var columns []string
-	for _, column := range ctrl.FieldDescriptions() {
-		columns = append(columns, string(column.Name))
+	if q.void {
+		err := q.observe(q.ssql, args, func(ctx context.Context) (int64, error) {
+			return q.exec.Exec(ctx, q.ssql, args...)
+		})
+		return nil, err
 	}
-
-	//Check for streaming flag here and stream (lazy read) as opposed to greedy read
-	rows := []Row{}
-	count := 0
-	for ctrl.Next() {
-		values, err := ctrl.Values()
+	var results SqlResult
+	err = q.observe(q.ssql, args, func(ctx context.Context) (int64, error) {
+		ctrl, err := q.exec.Query(ctx, q.ssql, args...)
 		if err != nil {
-			return nil, err
+			return 0, err
 		}
-		rows = append(rows, populateRow(columns, values))
-		count++
+		results, err = collect(ctrl)
+		return int64(results.count), err
+	})
+	if err != nil {
+		return nil, err
 	}
-	return SqlResult{columns, rows, count}, nil
+	return results, nil
 }
 
 //Only use this function if q.INTO(...) will be invoked immediately after this
 //is called i.e. this will register the value passed in for inversion
 //when q.INTO(...) is invoked
 func (q SqlQuery) INSERT(columns ...string) Command {
+	q.relations, q.refs, q.aliases = nil, nil, nil
+	q.windowed, q.locked = false, false
+	q.reference(columns...)
 	q.cols = columns
 	q.ssql = fmt.Sprintf("(%s)", strings.Join(columns, ", "))
 	return q
@@ -133,11 +207,16 @@ func (q SqlQuery) INSERT(columns ...string) Command {
 //Responsible for expantiation sql to write or create new records. This command is
 //exactly the same as invoking q.INSERT(...) followed immediately by q.INTO(...)
 func (q SqlQuery) INSERT_INTO(table interface{}, optionalColumns ...[]string) Command {
-	t := coerceToString(table)
+	q.relate(table)
+	t := q.written(table)
+	if fields := strings.Fields(t); len(fields) > 0 {
+		q.table = fields[0]
+	}
 
 	if len(optionalColumns) > 0 {
 		columns := optionalColumns[0]
 		if interpolative := len(columns); interpolative > 0 {
+			q.reference(columns...)
 			q.cols = columns
 			if placeholders := strings.Count(t, "?"); placeholders > 0 {
 				for _, col := range columns {
@@ -160,28 +239,116 @@ func (q SqlQuery) INSERT_INTO(table interface{}, optionalColumns ...[]string) Co
 //and so q.INTO(...) inverts the order autocorrecting q.ssql for further
 //expantiation
 func (q SqlQuery) INTO(table interface{}) Command {
-	t := coerceToString(table)
+	q.relate(table)
+	t := q.written(table)
 	//revisit and check correctness
 	return q.INSERT_INTO(fmt.Sprintf("%s %s", t, q.ssql))
 }
 
+//Run the values being inserted through the fields of the declared table they are written to
+func (q SqlQuery) bind() ([][]interface{}, error) {
+	var table *SqlTable
+	for _, relation := range q.relations {
+		if relation.identifier(q.dialectOf()) == q.table && len(relation.fields) > 0 {
+			table = relation
+		}
+	}
+	if table == nil {
+		return q.vals, nil
+	}
+
+	vals := make([][]interface{}, len(q.vals))
+	for i, val := range q.vals {
+		vals[i] = make([]interface{}, len(val))
+		for j, value := range val {
+			if j >= len(q.cols) {
+				return nil, fmt.Errorf("record %d has more values than the %d columns inserted", i+1, len(q.cols))
+			}
+			field, _ := table.Field(strings.TrimSpace(q.cols[j]))
+			bound, err := field.Bind(value)
+			if err != nil {
+				return nil, err
+			}
+			vals[i][j] = bound
+		}
+	}
+	return vals, nil
+}
+
+//The statement as sent to the server and its arguments. Values being inserted are bound to the
+//placeholders from VALUES(...) when they are not copied in bulk.
+func (q SqlQuery) statement() (string, []interface{}, error) {
+	args := append([]interface{}{}, q.args...)
+	if q.cols != nil {
+		vals, err := q.bind()
+		if err != nil {
+			return "", nil, err
+		}
+		for _, val := range vals {
+			args = append(args, val...)
+		}
+	}
+	if len(args) == 0 {
+		//nothing to bind, a ? left in the statement is part of it i.e. the jsonb ? operator
+		return q.ssql, args, nil
+	}
+	return replacePlaceholders(q.ssql, q.dialectOf()), args, nil
+}
+
 //Issue a join SQL command to tie entities/tables together. This should always
 //be followed by an invokation of q.ON(...)
 func (q SqlQuery) JOIN(entity interface{}) Command {
-	t := coerceToString(entity)
-	q.ssql = fmt.Sprintf("%s JOIN %s", q.ssql, t)
+	q.relate(entity)
+	q.ssql = fmt.Sprintf("%s JOIN %s", q.ssql, q.relation(entity))
 	return q
 }
 
-//TODO: LIMIT Documentation
+//Restrict the number of records returned. The syntax is rendered by the dialect of the query root.
 func (q SqlQuery) LIMIT(limit int) Command {
-	q.ssql = fmt.Sprintf("%s LIMIT %d", q.ssql, limit)
+	q.limited = true
+	q.ssql = fmt.Sprintf("%s %s", q.ssql, q.dialectOf().Limit(limit))
 	return &q
 }
 
-//TODO: OFFSET Documentation
+//Skip records before returning. Dialects that cannot express an OFFSET without a LIMIT i.e. SQLite
+//and MySQL get an unbounded LIMIT added for them when LIMIT(...) was not invoked beforehand.
 func (q SqlQuery) OFFSET(offset int) Command {
-	q.ssql = fmt.Sprintf("%s OFFSET %d", q.ssql, offset)
+	q.ssql = fmt.Sprintf("%s %s", q.ssql, q.dialectOf().Offset(offset, q.limited))
+	return q
+}
+
+//Start an upsert on the columns that identify a conflicting record. This should always be
+//followed by an invokation of q.DO_UPDATE(...) or q.DO_NOTHING()
+func (q SqlQuery) ON_CONFLICT(columns ...string) Command {
+	q.reference(columns...)
+	q.target = columns
+	return q
+}
+
+//Continuation expantiator for ON_CONFLICT(...) that overwrites the given columns of the
+//conflicting record with the values that were proposed for insertion.
+func (q SqlQuery) DO_UPDATE(columns ...string) Command {
+	if len(columns) == 0 {
+		return q.fail(fmt.Errorf("DO_UPDATE requires at least one column"))
+	}
+	q.reference(columns...)
+	return q.upsert(columns)
+}
+
+//Continuation expantiator for ON_CONFLICT(...) that silently skips conflicting records.
+func (q SqlQuery) DO_NOTHING() Command {
+	return q.upsert(nil)
+}
+
+func (q SqlQuery) upsert(update []string) Command {
+	clause, err := q.dialectOf().Upsert(q.target, update)
+	if err != nil {
+		return q.fail(err)
+	}
+	q.target = nil
+	//copying records in bulk cannot resolve conflicts so upserts always go out as INSERT commands
+	q.upserted = true
+	q.ssql = fmt.Sprintf("%s %s", q.ssql, clause)
 	return q
 }
 
@@ -189,6 +356,7 @@ func (q SqlQuery) OFFSET(offset int) Command {
 //a simple way to specify how the entities should be joined i.e. what columns across
 //the two entities intersect
 func (q SqlQuery) ON(statement string, conditions ...interface{}) Command {
+	q.reference(statement)
 	q.args = append(q.args, conditions...)
 	q.ssql = fmt.Sprintf("%s ON %s", q.ssql, statement)
 	return q
@@ -202,6 +370,7 @@ func (q SqlQuery) ob(ob string) Command {
 
 //TODO: ORDER_BY Documentation
 func (q SqlQuery) ORDER_BY(ob string) Command {
+	q.reference(ob)
 	q.ssql = fmt.Sprintf("%s ORDER BY %s", q.ssql, ob)
 	return q
 }
@@ -216,15 +385,44 @@ func (q SqlQuery) PP() string {
 	return csql
 }
 
+//Ask for the given columns of written records to be sent back. An empty column list returns
+//every column i.e. RETURNING *
+func (q SqlQuery) RETURNING(columns ...string) Command {
+	clause, err := q.dialectOf().Returning(columns)
+	if err != nil {
+		return q.fail(err)
+	}
+	q.reference(columns...)
+	q.void = false
+	q.ssql = fmt.Sprintf("%s %s", q.ssql, clause)
+	return q
+}
+
 func (q SqlQuery) RUN(ddl string) Command {
+	q.relations, q.refs, q.aliases = nil, nil, nil
+	q.windowed, q.locked = false, false
 	q.ssql = ddl
 	q.void = true
 	return q
 }
 
+//Expantiate a complete statement written by hand i.e. one loaded from an .sql file. The arguments
+//are bound to its ? placeholders and GO() loads the records it returns if there are any.
+func (q SqlQuery) RAW(ssql string, args ...interface{}) Command {
+	q.relations, q.refs, q.aliases = nil, nil, nil
+	q.windowed, q.locked = false, false
+	q.ssql = ssql
+	q.args = args
+	q.void = false
+	return q
+}
+
 //TODO: SELECT Documentation
 func (q SqlQuery) SELECT(fields ...string) Command {
 	q.void = false
+	q.relations, q.refs, q.aliases = nil, nil, nil
+	q.windowed, q.locked = false, false
+	q.reference(fields...)
 	if len(fields) == 0 {
 		fields = []string{"*"}
 	}
@@ -252,37 +450,104 @@ func (q SqlQuery) VALUES(vals ...[]interface{}) Command {
 	return q
 }
 
+//Returns the statement exactly as it will be sent to the server i.e. with the placeholders of the
+//dialect in use, together with the arguments that will be bound to it. Any error recorded while
+//the command was being expantiated is returned as well.
+func (q SqlQuery) SQL() (string, []interface{}, error) {
+	ssql, args, err := q.statement()
+	if err != nil {
+		return ssql, args, err
+	}
+	return ssql, args, q.check()
+}
+
 //TODO: WHERE Documentation
 func (q SqlQuery) WHERE(statement string, conditions ...interface{}) Command {
+	q.reference(statement)
 	q.args = append(q.args, conditions...)
 	q.ssql = fmt.Sprintf("%s WHERE %s", q.ssql, statement)
 	return q
 }
 
-//TODO: Query Documentation
-func Query(ctx context.Context, dsn string) (*SqlQuery, error) {
-	var pool *pgxpool.Pool
-	var conn *pgx.Conn
-	var ssql string
-	var args []interface{}
-	var cols []string
-	var vals [][]interface{}
-
-	pool, err := pgxpool.Connect(ctx, dsn)
-	if err != nil {
-		log.Fatalf("Could not initialize connection to database due to: %s", err)
-		return nil, err
+//Returns a copy of the query root that renders every command for the given dialect. Roots
+//created with Query(...) use the Postgres dialect.
+func (q *SqlQuery) WithDialect(dialect Dialect) *SqlQuery {
+	c := *q
+	c.dialect = dialect
+	return &c
+}
+
+func (q SqlQuery) dialectOf() Dialect {
+	if q.dialect == nil {
+		return Postgres
+	}
+	return q.dialect
+}
+
+//Remember tables declared with fields that the command reads from or writes to
+func (q *SqlQuery) relate(entity interface{}) {
+	if t, ok := entity.(*SqlTable); ok {
+		//force a copy so commands branching off the same base never share what they append
+		q.relations = append(q.relations[:len(q.relations):len(q.relations)], t)
+	}
+}
+
+//Remember the columns referenced by expressions so they can be checked against declared tables
+func (q *SqlQuery) reference(exprs ...string) {
+	for _, expr := range exprs {
+		refs, aliases, ok := identifiers(expr)
+		if !ok {
+			continue
+		}
+		q.refs = append(q.refs[:len(q.refs):len(q.refs)], refs...)
+		q.aliases = append(q.aliases[:len(q.aliases):len(q.aliases)], aliases...)
+	}
+}
+
+//Report expantiation errors and references to columns the declared tables do not have
+func (q SqlQuery) check() error {
+	if q.err != nil {
+		return q.err
+	}
+	aliases := map[string]bool{}
+	for _, alias := range q.aliases {
+		aliases[alias] = true
 	}
+	return checkColumns(q.relations, q.refs, aliases)
+}
+
+//Record the first error found while expantiating so it can be reported by GO() instead
+//of breaking the fluent chain of commands
+func (q SqlQuery) fail(err error) Command {
+	if q.err == nil {
+		q.err = err
+	}
+	return q
+}
+
+//Connect to the postgres server at dsn through a pgx connection pool and return the query root
+//every command is expantiated from. Use Open(...) to configure the pool.
+func Query(ctx context.Context, dsn string) (*SqlQuery, error) {
+	return Open(ctx, Config{DSN: dsn})
+}
+
+//Return a query root over an existing *sql.DB, *sql.Tx or *sql.Conn. Commands are rendered for the
+//given dialect and the handle is never closed by supersql.
+func QueryDB(ctx context.Context, db DBTX, dialect Dialect) *SqlQuery {
+	return root(ctx, sqlExecutor{db}, dialect)
+}
+
+//Return a query root that sends every command through exec. This is how custom backends and the
+//fake in the supersqltest package are plugged in.
+func QueryWith(ctx context.Context, exec Executor, dialect Dialect) *SqlQuery {
+	return root(ctx, exec, dialect)
+}
 
-	query := &SqlQuery{
-		conn: conn,
-		pool: pool,
-		ssql: ssql,
-		ctx:  ctx,
-		args: args,
-		cols: cols,
-		vals: vals,
-		void: true,
+func root(ctx context.Context, exec Executor, dialect Dialect) *SqlQuery {
+	return &SqlQuery{
+		exec:    exec,
+		ctx:     ctx,
+		void:    true,
+		dialect: dialect,
 	}
-	return query, nil
 }
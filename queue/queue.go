@@ -0,0 +1,307 @@
+//Package queue runs background jobs off a postgres table instead of a separate broker. Workers lease
+//jobs with SELECT ... FOR UPDATE SKIP LOCKED so concurrent workers never pick the same job, failed
+//jobs are retried with backoff and jobs that keep failing are dead-lettered for inspection.
+//
+//	emails := queue.New(q, "emails")
+//	emails.Setup(ctx)
+//	emails.Enqueue(ctx, Welcome{UserID: 42})
+//	processed, err := emails.Process(ctx, func(ctx context.Context, job queue.Job) error {
+//		var w Welcome
+//		if err := job.Decode(&w); err != nil {
+//			return err
+//		}
+//		return send(ctx, w)
+//	})
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rayattack/supersql"
+	"github.com/rayattack/supersql/internal/backoff"
+)
+
+const (
+	DefaultTable = "supersql_jobs"
+	//Time a worker has to finish a job before other workers may take it over
+	DefaultLease = 5 * time.Minute
+	//Attempts before a failing job is dead-lettered
+	DefaultMaxAttempts = 5
+)
+
+//The job was taken over by another worker after its lease expired or was settled already
+var ErrLeaseLost = errors.New("queue: lease on the job was lost")
+
+//A job as leased by Dequeue(...)
+type Job struct {
+	ID    int64
+	Queue string
+	//JSON encoding of the payload given to Enqueue(...)
+	Payload     json.RawMessage
+	Attempts    int
+	MaxAttempts int
+	RunAt       time.Time
+	LeasedUntil time.Time
+	//Error of the previous attempt if there was one
+	LastError string
+	Dead      bool
+}
+
+//Unmarshal the payload of the job into v
+func (j Job) Decode(v interface{}) error {
+	return json.Unmarshal(j.Payload, v)
+}
+
+type Queue struct {
+	q    *supersql.SqlQuery
+	name string
+
+	//Table shared by every queue, DefaultTable unless changed before Setup(...)
+	Table string
+	//DefaultLease when zero
+	Lease time.Duration
+	//Attempts given to jobs enqueued from now on, DefaultMaxAttempts when zero
+	MaxAttempts int
+	//Delay before the next attempt of a job that failed attempt times, DefaultBackoff when nil
+	Backoff func(attempt int) time.Duration
+}
+
+//The jobs named name in the table shared by every queue
+func New(q *supersql.SqlQuery, name string) *Queue {
+	return &Queue{q: q, name: name, Table: DefaultTable}
+}
+
+func (qu *Queue) Name() string {
+	return qu.name
+}
+
+//Exponential backoff from a second doubling with every attempt up to an hour
+func DefaultBackoff(attempt int) time.Duration {
+	return backoff.Exponential(attempt)
+}
+
+//Create the job table and its index if they do not exist yet
+func (qu *Queue) Setup(ctx context.Context) error {
+	q := qu.q.WithContext(ctx)
+	table := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id bigserial PRIMARY KEY, queue text NOT NULL, "+
+		"payload jsonb NOT NULL, attempts integer NOT NULL DEFAULT 0, max_attempts integer NOT NULL, "+
+		"run_at timestamptz NOT NULL DEFAULT now(), leased_until timestamptz, last_error text, "+
+		"dead boolean NOT NULL DEFAULT false, created_at timestamptz NOT NULL DEFAULT now())", qu.Table)
+	if _, err := q.RUN(table).GO(); err != nil {
+		return err
+	}
+	//the index is named after the table without its schema, it is created in the schema of the table
+	t := supersql.Table(qu.Table)
+	name := strings.TrimPrefix(t.Name(), t.Schema()+".")
+	index := supersql.Index(name+"_pending", t, "queue", "run_at").WHERE("NOT dead").IF_NOT_EXISTS()
+	_, err := q.RUN(index.CREATE()).GO()
+	return err
+}
+
+//Add a job that is due at once and return its id. The payload is stored as JSON.
+func (qu *Queue) Enqueue(ctx context.Context, payload interface{}) (int64, error) {
+	return qu.EnqueueAt(ctx, payload, time.Time{})
+}
+
+//Add a job that is not due before at
+func (qu *Queue) EnqueueAt(ctx context.Context, payload interface{}, at time.Time) (int64, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return 0, fmt.Errorf("queue: encoding payload: %w", err)
+	}
+	attempts := qu.MaxAttempts
+	if attempts <= 0 {
+		attempts = DefaultMaxAttempts
+	}
+	columns := []string{"queue", "payload", "max_attempts"}
+	values := []interface{}{qu.name, string(data), attempts}
+	if !at.IsZero() {
+		columns = append(columns, "run_at")
+		values = append(values, at)
+	}
+	r, err := qu.q.WithContext(ctx).INSERT_INTO(qu.Table, columns).VALUES(values).RETURNING("id").GO()
+	if err != nil {
+		return 0, err
+	}
+	if r.Count() == 0 {
+		return 0, fmt.Errorf("queue: no id returned for the new job")
+	}
+	return r.Rows(1).Int64("id")
+}
+
+var columns = []string{"id", "queue", "payload::text AS payload", "attempts", "max_attempts", "run_at",
+	"COALESCE(last_error, '') AS last_error", "dead"}
+
+//Lease the next due job. Jobs whose lease expired are due again, nil is returned when there is no
+//job to run. The job must be settled with Ack(...), Retry(...) or DeadLetter(...) before the lease
+//runs out or it is handed to another worker.
+func (qu *Queue) Dequeue(ctx context.Context) (*Job, error) {
+	var job *Job
+	err := qu.q.WithContext(ctx).TRANSACTION(func(tx *supersql.SqlQuery) error {
+		for {
+			r, err := tx.SELECT(columns...).FROM(qu.Table).
+				WHERE("queue = ? AND NOT dead AND run_at <= now() AND (leased_until IS NULL OR leased_until <= now())", qu.name).
+				ORDER_BY("run_at, id").LIMIT(1).FOR_UPDATE().SKIP_LOCKED().GO()
+			if err != nil {
+				return err
+			}
+			if r.Count() == 0 {
+				return nil
+			}
+			candidate, err := scan(r.Rows(1))
+			if err != nil {
+				return err
+			}
+			if candidate.Attempts >= candidate.MaxAttempts {
+				//the worker holding the last attempt went away without settling the job
+				ssql := fmt.Sprintf("UPDATE %s SET dead = true, leased_until = NULL, last_error = COALESCE(last_error, 'lease expired')", qu.Table)
+				if _, err := tx.RUN(ssql).WHERE("id = ?", candidate.ID).GO(); err != nil {
+					return err
+				}
+				continue
+			}
+
+			ssql := fmt.Sprintf("UPDATE %s SET attempts = attempts + 1, leased_until = now() + ? * interval '1 second' "+
+				"WHERE id = ? RETURNING leased_until", qu.Table)
+			leased, err := tx.RAW(ssql, qu.lease().Seconds(), candidate.ID).GO()
+			if err != nil {
+				return err
+			}
+			candidate.Attempts++
+			if leased.Count() > 0 {
+				candidate.LeasedUntil, _ = leased.Rows(1).Column("leased_until").(time.Time)
+			}
+			job = &candidate
+			return nil
+		}
+	})
+	return job, err
+}
+
+//Remove a job that ran successfully
+func (qu *Queue) Ack(ctx context.Context, job *Job) error {
+	ssql := fmt.Sprintf("DELETE FROM %s WHERE id = ? AND attempts = ? AND NOT dead RETURNING id", qu.Table)
+	return qu.settle(ctx, ssql, job.ID, job.Attempts)
+}
+
+//Schedule the next attempt of a failed job after the backoff delay, jobs out of attempts are
+//dead-lettered instead
+func (qu *Queue) Retry(ctx context.Context, job *Job, cause error) error {
+	if job.Attempts >= job.MaxAttempts {
+		return qu.DeadLetter(ctx, job, cause)
+	}
+	backoff := qu.Backoff
+	if backoff == nil {
+		backoff = DefaultBackoff
+	}
+	ssql := fmt.Sprintf("UPDATE %s SET run_at = now() + ? * interval '1 second', leased_until = NULL, last_error = ? "+
+		"WHERE id = ? AND attempts = ? AND NOT dead RETURNING id", qu.Table)
+	return qu.settle(ctx, ssql, backoff(job.Attempts).Seconds(), message(cause), job.ID, job.Attempts)
+}
+
+//Stop attempting a job and keep it with its error for inspection, see Dead(...) and Requeue(...)
+func (qu *Queue) DeadLetter(ctx context.Context, job *Job, cause error) error {
+	ssql := fmt.Sprintf("UPDATE %s SET dead = true, leased_until = NULL, last_error = ? "+
+		"WHERE id = ? AND attempts = ? AND NOT dead RETURNING id", qu.Table)
+	return qu.settle(ctx, ssql, message(cause), job.ID, job.Attempts)
+}
+
+//The dead-lettered jobs of the queue, most recently failed first
+func (qu *Queue) Dead(ctx context.Context, limit int) ([]Job, error) {
+	r, err := qu.q.WithContext(ctx).SELECT(columns...).FROM(qu.Table).WHERE("queue = ? AND dead", qu.name).
+		ORDER_BY("run_at DESC, id DESC").LIMIT(limit).GO()
+	if err != nil {
+		return nil, err
+	}
+	jobs := []Job{}
+	for _, row := range r.All() {
+		job, err := scan(row)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+//Give a dead-lettered job a fresh set of attempts starting now
+func (qu *Queue) Requeue(ctx context.Context, id int64) error {
+	ssql := fmt.Sprintf("UPDATE %s SET dead = false, attempts = 0, run_at = now(), leased_until = NULL "+
+		"WHERE id = ? AND queue = ? AND dead RETURNING id", qu.Table)
+	r, err := qu.q.WithContext(ctx).RAW(ssql, id, qu.name).GO()
+	if err != nil {
+		return err
+	}
+	if r.Count() == 0 {
+		return fmt.Errorf("queue: there is no dead job %d in %s", id, qu.name)
+	}
+	return nil
+}
+
+//Dequeue a job and run fn with it, acknowledging the job when fn succeeds and retrying it when fn
+//fails. Reports false when there was no job to run.
+func (qu *Queue) Process(ctx context.Context, fn func(ctx context.Context, job Job) error) (bool, error) {
+	job, err := qu.Dequeue(ctx)
+	if err != nil || job == nil {
+		return false, err
+	}
+	if err := fn(ctx, *job); err != nil {
+		return true, qu.Retry(ctx, job, err)
+	}
+	return true, qu.Ack(ctx, job)
+}
+
+//Run a statement that settles a leased job. The attempt count identifies the lease so a worker whose
+//lease expired can not settle the attempt of another worker.
+func (qu *Queue) settle(ctx context.Context, ssql string, args ...interface{}) error {
+	r, err := qu.q.WithContext(ctx).RAW(ssql, args...).GO()
+	if err != nil {
+		return err
+	}
+	if r.Count() == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+func (qu *Queue) lease() time.Duration {
+	if qu.Lease <= 0 {
+		return DefaultLease
+	}
+	return qu.Lease
+}
+
+func scan(row supersql.Row) (Job, error) {
+	id, err := row.Int64("id")
+	if err != nil {
+		return Job{}, err
+	}
+	attempts, err := row.Int64("attempts")
+	if err != nil {
+		return Job{}, err
+	}
+	max, err := row.Int64("max_attempts")
+	if err != nil {
+		return Job{}, err
+	}
+	job := Job{ID: id, Attempts: int(attempts), MaxAttempts: int(max)}
+	job.Queue, _ = row.String("queue")
+	payload, _ := row.String("payload")
+	job.Payload = json.RawMessage(payload)
+	job.RunAt, _ = row.Column("run_at").(time.Time)
+	job.LastError, _ = row.String("last_error")
+	job.Dead, _ = row.Column("dead").(bool)
+	return job, nil
+}
+
+func message(cause error) string {
+	if cause == nil {
+		return ""
+	}
+	return cause.Error()
+}
@@ -0,0 +1,189 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rayattack/supersql/queue"
+	"github.com/rayattack/supersql/supersqltest"
+)
+
+var (
+	ctx     = context.Background()
+	columns = []string{"id", "queue", "payload", "attempts", "max_attempts", "run_at", "last_error", "dead"}
+	due     = time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+)
+
+type welcome struct {
+	UserID int `json:"user_id"`
+}
+
+func job(id int64, attempts int32) []interface{} {
+	return []interface{}{id, "emails", `{"user_id": 42}`, attempts, int32(3), due, "", false}
+}
+
+func TestSetup(t *testing.T) {
+	fake := supersqltest.New()
+	jobs := queue.New(fake.Root(), "emails")
+	jobs.Table = "jobs.queue"
+	if err := jobs.Setup(ctx); err != nil {
+		t.Fatal(err)
+	}
+	calls := fake.Calls()
+	if !strings.HasPrefix(calls[0].SQL, "CREATE TABLE IF NOT EXISTS jobs.queue (") {
+		t.Fatal(calls[0].SQL)
+	}
+	if calls[1].SQL != "CREATE INDEX IF NOT EXISTS queue_pending ON jobs.queue (queue, run_at) WHERE NOT dead" {
+		t.Fatal(calls[1].SQL)
+	}
+}
+
+func TestEnqueue(t *testing.T) {
+	fake := supersqltest.New()
+	fake.Expect("INSERT INTO supersql_jobs").Returns([]string{"id"}, []interface{}{int64(7)}).Always()
+	emails := queue.New(fake.Root(), "emails")
+	emails.MaxAttempts = 3
+	id, err := emails.Enqueue(ctx, welcome{42})
+	if err != nil || id != 7 {
+		t.Fatal(id, err)
+	}
+	call := fake.Calls()[0]
+	if call.SQL != "INSERT INTO supersql_jobs (queue, payload, max_attempts) VALUES ($1, $2, $3) RETURNING id" {
+		t.Fatal(call.SQL)
+	}
+	if call.Args[0] != "emails" || call.Args[1] != `{"user_id":42}` || call.Args[2] != 3 {
+		t.Fatal(call.Args)
+	}
+
+	if _, err := emails.EnqueueAt(ctx, welcome{43}, due); err != nil {
+		t.Fatal(err)
+	}
+	if call := fake.Calls()[1]; !strings.Contains(call.SQL, "run_at) VALUES ($1, $2, $3, $4)") || call.Args[3] != due {
+		t.Fatal(call.SQL, call.Args)
+	}
+}
+
+func TestDequeue(t *testing.T) {
+	fake := supersqltest.New()
+	lease := due.Add(time.Minute)
+	fake.Expect("FOR UPDATE SKIP LOCKED").Returns(columns, job(7, 0))
+	fake.Expect("attempts = attempts + 1").Returns([]string{"leased_until"}, []interface{}{lease})
+	emails := queue.New(fake.Root(), "emails")
+	emails.Lease = time.Minute
+
+	j, err := emails.Dequeue(ctx)
+	if err != nil || j == nil {
+		t.Fatal(j, err)
+	}
+	var w welcome
+	if err := j.Decode(&w); err != nil || w.UserID != 42 || j.ID != 7 || j.Attempts != 1 || j.MaxAttempts != 3 || !j.LeasedUntil.Equal(lease) {
+		t.Fatal(w, j, err)
+	}
+
+	calls := fake.Calls()
+	if len(calls) != 4 || calls[0].SQL != "BEGIN" || calls[3].SQL != "COMMIT" {
+		t.Fatal(calls)
+	}
+	expected := "SELECT id, queue, payload::text AS payload, attempts, max_attempts, run_at, COALESCE(last_error, '') AS last_error, dead " +
+		"FROM supersql_jobs WHERE queue = $1 AND NOT dead AND run_at <= now() AND (leased_until IS NULL OR leased_until <= now()) " +
+		"ORDER BY run_at, id LIMIT 1 FOR UPDATE SKIP LOCKED"
+	if calls[1].SQL != expected {
+		t.Fatal(calls[1].SQL)
+	}
+	if calls[2].Args[0] != 60.0 || calls[2].Args[1] != int64(7) {
+		t.Fatal(calls[2].Args)
+	}
+
+	fake.Reset()
+	if j, err := emails.Dequeue(ctx); err != nil || j != nil {
+		t.Fatal("an empty queue has no job", j, err)
+	}
+}
+
+func TestDequeueBuriesExpiredLastAttempt(t *testing.T) {
+	fake := supersqltest.New()
+	fake.Expect("FOR UPDATE SKIP LOCKED").Returns(columns, job(7, 3))
+	j, err := queue.New(fake.Root(), "emails").Dequeue(ctx)
+	if err != nil || j != nil {
+		t.Fatal(j, err)
+	}
+	calls := fake.Calls()
+	if len(calls) != 5 || !strings.Contains(calls[2].SQL, "SET dead = true") || !strings.Contains(calls[3].SQL, "FOR UPDATE SKIP LOCKED") {
+		t.Fatal(calls)
+	}
+}
+
+func TestProcess(t *testing.T) {
+	fake := supersqltest.New()
+	fake.Expect("FOR UPDATE SKIP LOCKED").Returns(columns, job(7, 0))
+	fake.Expect("DELETE FROM supersql_jobs").Returns([]string{"id"}, []interface{}{int64(7)})
+	emails := queue.New(fake.Root(), "emails")
+	processed, err := emails.Process(ctx, func(ctx context.Context, j queue.Job) error { return nil })
+	if err != nil || !processed {
+		t.Fatal(processed, err)
+	}
+	calls := fake.Calls()
+	if ack := calls[len(calls)-1]; ack.SQL != "DELETE FROM supersql_jobs WHERE id = $1 AND attempts = $2 AND NOT dead RETURNING id" || ack.Args[1] != 1 {
+		t.Fatal(ack)
+	}
+
+	fake.Reset()
+	fake.Expect("FOR UPDATE SKIP LOCKED").Returns(columns, job(7, 1))
+	fake.Expect("SET run_at = now()").Returns([]string{"id"}, []interface{}{int64(7)})
+	emails.Backoff = func(attempt int) time.Duration { return time.Duration(attempt) * time.Minute }
+	processed, err = emails.Process(ctx, func(ctx context.Context, j queue.Job) error { return errors.New("smtp down") })
+	if err != nil || !processed {
+		t.Fatal(processed, err)
+	}
+	calls = fake.Calls()
+	if retry := calls[len(calls)-1]; retry.Args[0] != 120.0 || retry.Args[1] != "smtp down" {
+		t.Fatal(retry)
+	}
+
+	fake.Reset()
+	fake.Expect("FOR UPDATE SKIP LOCKED").Returns(columns, job(7, 2))
+	fake.Expect("SET dead = true").Returns([]string{"id"}, []interface{}{int64(7)})
+	if _, err := emails.Process(ctx, func(ctx context.Context, j queue.Job) error { return errors.New("smtp down") }); err != nil {
+		t.Fatal(err)
+	}
+	calls = fake.Calls()
+	if dead := calls[len(calls)-1]; !strings.Contains(dead.SQL, "SET dead = true") || dead.Args[0] != "smtp down" {
+		t.Fatal(dead)
+	}
+
+	fake.Reset()
+	if processed, err := emails.Process(ctx, nil); err != nil || processed {
+		t.Fatal(processed, err)
+	}
+}
+
+func TestLeaseLost(t *testing.T) {
+	fake := supersqltest.New()
+	emails := queue.New(fake.Root(), "emails")
+	if err := emails.Ack(ctx, &queue.Job{ID: 7, Attempts: 1}); !errors.Is(err, queue.ErrLeaseLost) {
+		t.Fatal(err)
+	}
+	if err := emails.Requeue(ctx, 7); err == nil {
+		t.Fatal("only dead jobs can be requeued")
+	}
+}
+
+func TestDead(t *testing.T) {
+	fake := supersqltest.New()
+	dead := job(7, 3)
+	dead[6], dead[7] = "smtp down", true
+	fake.Expect("AND dead ORDER BY").Returns(columns, dead)
+	jobs, err := queue.New(fake.Root(), "emails").Dead(ctx, 10)
+	if err != nil || len(jobs) != 1 || !jobs[0].Dead || jobs[0].LastError != "smtp down" || jobs[0].Attempts != 3 {
+		t.Fatal(jobs, err)
+	}
+}
+
+func TestDefaultBackoff(t *testing.T) {
+	if queue.DefaultBackoff(1) != 2*time.Second || queue.DefaultBackoff(3) != 8*time.Second || queue.DefaultBackoff(40) != time.Hour {
+		t.Fatal(queue.DefaultBackoff(1), queue.DefaultBackoff(40))
+	}
+}
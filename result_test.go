@@ -8,6 +8,7 @@ import (
 )
 
 func TestSingleSelect(t *testing.T) {
+	requireDB(t)
 	r, _ := Xql.SELECT("title").FROM("film").WHERE("film_id = ?", 007).GO()
 	if r.Count() != 1 {
 		t.Fail()
@@ -0,0 +1,290 @@
+package supersql
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+//Declaration of a role. Roles with LOGIN are what other databases call users.
+//
+//	supersql.Role("app").LOGIN().PASSWORD(secret).CONNECTION_LIMIT(20).CREATE()
+type SqlRole struct {
+	name     string
+	options  []string
+	password *string
+	until    *time.Time
+	inRoles  []string
+}
+
+func Role(name string) SqlRole {
+	return SqlRole{name: name}
+}
+
+func (r SqlRole) option(option string) SqlRole {
+	r.options = append(r.options[:len(r.options):len(r.options)], option)
+	return r
+}
+
+func (r SqlRole) LOGIN() SqlRole {
+	return r.option("LOGIN")
+}
+
+func (r SqlRole) NOLOGIN() SqlRole {
+	return r.option("NOLOGIN")
+}
+
+func (r SqlRole) SUPERUSER() SqlRole {
+	return r.option("SUPERUSER")
+}
+
+func (r SqlRole) CREATEDB() SqlRole {
+	return r.option("CREATEDB")
+}
+
+func (r SqlRole) CREATEROLE() SqlRole {
+	return r.option("CREATEROLE")
+}
+
+//Do not pick up the privileges of the roles this role is a member of without SET ROLE
+func (r SqlRole) NOINHERIT() SqlRole {
+	return r.option("NOINHERIT")
+}
+
+func (r SqlRole) CONNECTION_LIMIT(limit int) SqlRole {
+	return r.option(fmt.Sprintf("CONNECTION LIMIT %d", limit))
+}
+
+//Password of the role, written into the statement as a literal so keep the statement out of logs
+func (r SqlRole) PASSWORD(password string) SqlRole {
+	r.password = &password
+	return r
+}
+
+//Time after which the password of the role stops working
+func (r SqlRole) VALID_UNTIL(until time.Time) SqlRole {
+	r.until = &until
+	return r
+}
+
+//Make the new role a member of roles
+func (r SqlRole) IN_ROLE(roles ...string) SqlRole {
+	r.inRoles = append(r.inRoles[:len(r.inRoles):len(r.inRoles)], roles...)
+	return r
+}
+
+func (r SqlRole) Name() string {
+	return r.name
+}
+
+//Quote a role name like any other identifier except for the role specifications postgres gives a
+//meaning of their own i.e. PUBLIC
+func quoteRole(role string) string {
+	switch strings.ToUpper(role) {
+	case "PUBLIC", "CURRENT_ROLE", "CURRENT_USER", "SESSION_USER":
+		return strings.ToUpper(role)
+	}
+	return quoteIdentifier(Postgres, role)
+}
+
+func quoteRoles(roles []string) string {
+	quoted := []string{}
+	for _, role := range roles {
+		quoted = append(quoted, quoteRole(role))
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func (r SqlRole) statement(verb string, membership bool) string {
+	options := append([]string{}, r.options...)
+	if r.password != nil {
+		options = append(options, "PASSWORD "+literal(*r.password))
+	}
+	if r.until != nil {
+		options = append(options, "VALID UNTIL "+literal(*r.until))
+	}
+	if membership && len(r.inRoles) > 0 {
+		options = append(options, "IN ROLE "+quoteRoles(r.inRoles))
+	}
+	ssql := fmt.Sprintf("%s ROLE %s", verb, quoteIdentifier(Postgres, r.name))
+	if len(options) > 0 {
+		ssql += " WITH " + strings.Join(options, " ")
+	}
+	return ssql
+}
+
+//Generate the CREATE ROLE statement
+func (r SqlRole) CREATE() string {
+	return r.statement("CREATE", true)
+}
+
+//Generate the ALTER ROLE statement changing the options of an existing role i.e. to rotate its
+//password. Membership is changed with GRANT and REVOKE instead.
+func (r SqlRole) ALTER() string {
+	return r.statement("ALTER", false)
+}
+
+func (r SqlRole) DROP() SqlDrop {
+	return SqlDrop{kind: "ROLE", name: quoteIdentifier(Postgres, r.name)}
+}
+
+//Privilege that can be granted on a database object
+type Privilege string
+
+const (
+	ALL_PRIVILEGES Privilege = "ALL PRIVILEGES"
+	SELECT         Privilege = "SELECT"
+	INSERT         Privilege = "INSERT"
+	UPDATE         Privilege = "UPDATE"
+	DELETE         Privilege = "DELETE"
+	TRUNCATE       Privilege = "TRUNCATE"
+	TRIGGER        Privilege = "TRIGGER"
+	USAGE          Privilege = "USAGE"
+	CONNECT        Privilege = "CONNECT"
+	CREATE         Privilege = "CREATE"
+	TEMPORARY      Privilege = "TEMPORARY"
+	EXECUTE        Privilege = "EXECUTE"
+)
+
+//GRANT or REVOKE statement. Without an ON clause the privileges are taken to be roles and
+//membership in them is granted or revoked.
+//
+//	supersql.GRANT(supersql.SELECT, supersql.INSERT).ON_TABLE(film, "actor").TO("app")
+//	supersql.REVOKE(supersql.ALL_PRIVILEGES).ON_SCHEMA("public").FROM("PUBLIC")
+//	supersql.GRANT("readonly").TO("alice") // GRANT readonly TO alice
+type SqlGrant struct {
+	revoke      bool
+	privileges  []string
+	target      string
+	roles       []string
+	grantOption bool
+	adminOption bool
+	cascade     bool
+}
+
+func GRANT(privileges ...Privilege) SqlGrant {
+	return SqlGrant{privileges: privilegeList(privileges)}
+}
+
+func REVOKE(privileges ...Privilege) SqlGrant {
+	return SqlGrant{revoke: true, privileges: privilegeList(privileges)}
+}
+
+func privilegeList(privileges []Privilege) []string {
+	list := []string{}
+	for _, p := range privileges {
+		list = append(list, string(p))
+	}
+	return list
+}
+
+func (g SqlGrant) on(kind string, objects []string) SqlGrant {
+	g.target = fmt.Sprintf("%s %s", kind, strings.Join(objects, ", "))
+	return g
+}
+
+//Schemas and databases are single names quoted where needed, tables and sequences given as strings
+//are SQL written by hand like everywhere else
+func quoteNames(names []string) []string {
+	quoted := []string{}
+	for _, name := range names {
+		quoted = append(quoted, quoteIdentifier(Postgres, name))
+	}
+	return quoted
+}
+
+//Tables given by name or as *SqlTable
+func (g SqlGrant) ON_TABLE(tables ...interface{}) SqlGrant {
+	names := []string{}
+	for _, table := range tables {
+		names = append(names, coerceToString(table))
+	}
+	return g.on("TABLE", names)
+}
+
+//Every table that exists in schemas at the time of the statement
+func (g SqlGrant) ON_ALL_TABLES_IN_SCHEMA(schemas ...string) SqlGrant {
+	return g.on("ALL TABLES IN SCHEMA", quoteNames(schemas))
+}
+
+func (g SqlGrant) ON_SEQUENCE(sequences ...string) SqlGrant {
+	return g.on("SEQUENCE", sequences)
+}
+
+func (g SqlGrant) ON_ALL_SEQUENCES_IN_SCHEMA(schemas ...string) SqlGrant {
+	return g.on("ALL SEQUENCES IN SCHEMA", quoteNames(schemas))
+}
+
+func (g SqlGrant) ON_SCHEMA(schemas ...string) SqlGrant {
+	return g.on("SCHEMA", quoteNames(schemas))
+}
+
+func (g SqlGrant) ON_DATABASE(databases ...string) SqlGrant {
+	return g.on("DATABASE", quoteNames(databases))
+}
+
+//Roles receiving the privileges, PUBLIC for every role
+func (g SqlGrant) TO(roles ...string) SqlGrant {
+	g.roles = append(g.roles[:len(g.roles):len(g.roles)], roles...)
+	return g
+}
+
+//Roles losing the privileges, the same as TO
+func (g SqlGrant) FROM(roles ...string) SqlGrant {
+	return g.TO(roles...)
+}
+
+//Allow the receiving roles to grant the privileges to others. On a REVOKE only that permission is
+//revoked.
+func (g SqlGrant) WITH_GRANT_OPTION() SqlGrant {
+	g.grantOption = true
+	return g
+}
+
+//Allow the receiving roles to grant membership in the granted roles to others. On a REVOKE only
+//that permission is revoked.
+func (g SqlGrant) WITH_ADMIN_OPTION() SqlGrant {
+	g.adminOption = true
+	return g
+}
+
+//Also revoke the privileges the roles granted to others
+func (g SqlGrant) CASCADE() SqlGrant {
+	g.cascade = true
+	return g
+}
+
+func (g SqlGrant) String() string {
+	parts := []string{"GRANT"}
+	if g.revoke {
+		parts = []string{"REVOKE"}
+		if g.grantOption {
+			parts = append(parts, "GRANT OPTION FOR")
+		}
+		if g.adminOption {
+			parts = append(parts, "ADMIN OPTION FOR")
+		}
+	}
+	if g.target != "" {
+		parts = append(parts, strings.Join(g.privileges, ", "), "ON", g.target)
+	} else {
+		//without a target the privileges are roles
+		parts = append(parts, quoteRoles(g.privileges))
+	}
+	if g.revoke {
+		parts = append(parts, "FROM")
+	} else {
+		parts = append(parts, "TO")
+	}
+	parts = append(parts, quoteRoles(g.roles))
+	if g.grantOption && !g.revoke {
+		parts = append(parts, "WITH GRANT OPTION")
+	}
+	if g.adminOption && !g.revoke {
+		parts = append(parts, "WITH ADMIN OPTION")
+	}
+	if g.cascade && g.revoke {
+		parts = append(parts, "CASCADE")
+	}
+	return strings.Join(parts, " ")
+}
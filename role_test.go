@@ -0,0 +1,76 @@
+package supersql_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rayattack/supersql"
+	"github.com/rayattack/supersql/supersqltest"
+)
+
+func TestRole(t *testing.T) {
+	if readonly := supersql.Role("readonly").NOLOGIN(); readonly.CREATE() != "CREATE ROLE readonly WITH NOLOGIN" {
+		t.Fatal(readonly.CREATE())
+	}
+	if bare := supersql.Role("bare"); bare.CREATE() != "CREATE ROLE bare" {
+		t.Fatal(bare.CREATE())
+	}
+
+	until := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	app := supersql.Role("app").LOGIN().CREATEDB().CONNECTION_LIMIT(20).PASSWORD("it's secret").VALID_UNTIL(until).IN_ROLE("readonly", "writers")
+	if app.CREATE() != "CREATE ROLE app WITH LOGIN CREATEDB CONNECTION LIMIT 20 PASSWORD 'it''s secret' VALID UNTIL '2025-01-01T00:00:00Z' IN ROLE readonly, writers" {
+		t.Fatal(app.CREATE())
+	}
+	if rotate := supersql.Role("app").PASSWORD("new").IN_ROLE("ignored"); rotate.ALTER() != "ALTER ROLE app WITH PASSWORD 'new'" {
+		t.Fatal(rotate.ALTER())
+	}
+	if drop := app.DROP().IF_EXISTS().String(); drop != "DROP ROLE IF EXISTS app" {
+		t.Fatal(drop)
+	}
+	if mixed := supersql.Role("Reporting").IN_ROLE("Analysts"); mixed.CREATE() != `CREATE ROLE "Reporting" WITH IN ROLE "Analysts"` {
+		t.Fatal(mixed.CREATE())
+	}
+}
+
+func TestGrantAndRevoke(t *testing.T) {
+	film := supersql.Table("film")
+	cases := map[string]supersql.SqlGrant{
+		"GRANT SELECT, INSERT ON TABLE film, actor TO app, reporting":             supersql.GRANT(supersql.SELECT, supersql.INSERT).ON_TABLE(film, "actor").TO("app", "reporting"),
+		"GRANT SELECT ON ALL TABLES IN SCHEMA public, billing TO readonly":        supersql.GRANT(supersql.SELECT).ON_ALL_TABLES_IN_SCHEMA("public", "billing").TO("readonly"),
+		"GRANT USAGE ON ALL SEQUENCES IN SCHEMA public TO app WITH GRANT OPTION":  supersql.GRANT(supersql.USAGE).ON_ALL_SEQUENCES_IN_SCHEMA("public").TO("app").WITH_GRANT_OPTION(),
+		"GRANT USAGE, CREATE ON SCHEMA billing TO app":                            supersql.GRANT(supersql.USAGE, supersql.CREATE).ON_SCHEMA("billing").TO("app"),
+		"GRANT CONNECT, TEMPORARY ON DATABASE pagila TO app":                      supersql.GRANT(supersql.CONNECT, supersql.TEMPORARY).ON_DATABASE("pagila").TO("app"),
+		"GRANT UPDATE ON SEQUENCE film_film_id_seq TO app":                        supersql.GRANT(supersql.UPDATE).ON_SEQUENCE("film_film_id_seq").TO("app"),
+		"REVOKE ALL PRIVILEGES ON SCHEMA public FROM PUBLIC":                      supersql.REVOKE(supersql.ALL_PRIVILEGES).ON_SCHEMA("public").FROM("PUBLIC"),
+		"REVOKE GRANT OPTION FOR DELETE, TRUNCATE ON TABLE film FROM app CASCADE": supersql.REVOKE(supersql.DELETE, supersql.TRUNCATE).ON_TABLE(film).FROM("app").WITH_GRANT_OPTION().CASCADE(),
+		"GRANT readonly, writers TO alice WITH ADMIN OPTION":                      supersql.GRANT("readonly", "writers").TO("alice").WITH_ADMIN_OPTION(),
+		"REVOKE ADMIN OPTION FOR readonly FROM alice":                             supersql.REVOKE("readonly").FROM("alice").WITH_ADMIN_OPTION(),
+		`GRANT USAGE ON SCHEMA "Audit" TO "Analysts", PUBLIC`:                     supersql.GRANT(supersql.USAGE).ON_SCHEMA("Audit").TO("Analysts", "public"),
+		`GRANT "Analysts" TO "user"`:                                              supersql.GRANT("Analysts").TO("user"),
+	}
+	for want, grant := range cases {
+		if grant.String() != want {
+			t.Errorf("%s != %s", grant.String(), want)
+		}
+	}
+}
+
+func TestRunKeepsQuestionMarksInLiterals(t *testing.T) {
+	fake := supersqltest.New()
+	q := fake.Root()
+	if _, err := q.RUN(supersql.Role("app").PASSWORD("s3cr?t").CREATE()).GO(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.RAW(`SELECT '?' AS "why?", doc FROM film WHERE film_id = ? /* or ? */ -- and ?
+AND title = ?`, 1, "Chamber Italian").GO(); err != nil {
+		t.Fatal(err)
+	}
+	calls := fake.Calls()
+	if calls[0].SQL != "CREATE ROLE app WITH PASSWORD 's3cr?t'" {
+		t.Fatal(calls[0].SQL)
+	}
+	if calls[1].SQL != `SELECT '?' AS "why?", doc FROM film WHERE film_id = $1 /* or ? */ -- and ?
+AND title = $2` {
+		t.Fatal(calls[1].SQL)
+	}
+}
@@ -3,6 +3,9 @@ package supersql
 import (
 	"encoding/json"
 	"fmt"
+	"net"
+	"reflect"
+	"time"
 )
 
 const TIP = "could not coerece data type to"
@@ -35,6 +38,23 @@ func (s SqlRow) Integer(col string) (int, error) {
 	return val, fmt.Errorf("%s %T", TIP, val)
 }
 
+//Integer of any width i.e. smallint, integer and bigint columns alike
+func (s SqlRow) Int64(col string) (int64, error) {
+	switch val := s.datum[col].(type) {
+	case int64:
+		return val, nil
+	case int32:
+		return int64(val), nil
+	case int16:
+		return int64(val), nil
+	case int:
+		return int64(val), nil
+	case float64:
+		return int64(val), nil
+	}
+	return 0, fmt.Errorf("%s int64 from %T", TIP, s.datum[col])
+}
+
 func (s SqlRow) Boolean(col string) (bool, error) {
 	val, ok := s.datum[col].(bool)
 	if ok {
@@ -72,6 +92,114 @@ func (s SqlRow) Transfer(v interface{}) error {
 	return json.Unmarshal(datum, v)
 }
 
+//Store a column value as handed over by the driver in dest, a pointer to the Go type the column is
+//read into. Values of postgres types without a plain Go equivalent are converted i.e. uuid and inet
+//to string, numeric to float64 and interval to time.Duration. NULL leaves dest at its zero value.
+//
+//	var id string
+//	err := supersql.Assign(&id, row.Column("customer_id"))
+func Assign(dest interface{}, value interface{}) error {
+	target := reflect.ValueOf(dest)
+	if target.Kind() != reflect.Ptr || target.IsNil() {
+		return fmt.Errorf("supersql: can not assign to %T", dest)
+	}
+	target = target.Elem()
+	if getter, ok := value.(interface{ Get() interface{} }); ok && getter.Get() == nil {
+		//NULL in a value of the pgtype package
+		value = nil
+	}
+	if value == nil {
+		target.Set(reflect.Zero(target.Type()))
+		return nil
+	}
+	if target.Kind() == reflect.Ptr && target.Type() != reflect.TypeOf(value) {
+		//nullable columns are read into pointers
+		inner := reflect.New(target.Type().Elem())
+		if err := Assign(inner.Interface(), value); err != nil {
+			return err
+		}
+		target.Set(inner)
+		return nil
+	}
+
+	switch d := dest.(type) {
+	case *string:
+		switch v := value.(type) {
+		case []byte:
+			*d = string(v)
+			return nil
+		case [16]byte:
+			*d = fmt.Sprintf("%x-%x-%x-%x-%x", v[0:4], v[4:6], v[6:8], v[8:10], v[10:16])
+			return nil
+		case *net.IPNet:
+			if ones, bits := v.Mask.Size(); ones == bits {
+				*d = v.IP.String()
+			} else {
+				*d = v.String()
+			}
+			return nil
+		case net.IP:
+			*d = v.String()
+			return nil
+		}
+	case *json.RawMessage:
+		switch v := value.(type) {
+		case string:
+			*d = json.RawMessage(v)
+		case []byte:
+			*d = append(json.RawMessage{}, v...)
+		default:
+			data, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			*d = data
+		}
+		return nil
+	case *time.Duration:
+		if v, ok := value.(time.Duration); ok {
+			*d = v
+			return nil
+		}
+	}
+
+	source := reflect.ValueOf(value)
+	if source.Type().AssignableTo(target.Type()) {
+		target.Set(source)
+		return nil
+	}
+	//values of the pgtype package i.e. numeric and interval know how to assign themselves
+	addressable := reflect.New(source.Type())
+	addressable.Elem().Set(source)
+	if a, ok := addressable.Interface().(interface{ AssignTo(dst interface{}) error }); ok {
+		return a.AssignTo(dest)
+	}
+	if numeric(source.Kind()) && numeric(target.Kind()) {
+		target.Set(source.Convert(target.Type()))
+		return nil
+	}
+	if source.Kind() == reflect.Slice && target.Kind() == reflect.Slice {
+		elements := reflect.MakeSlice(target.Type(), source.Len(), source.Len())
+		for i := 0; i < source.Len(); i++ {
+			if err := Assign(elements.Index(i).Addr().Interface(), source.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		target.Set(elements)
+		return nil
+	}
+	return fmt.Errorf("%s %s from %T", TIP, target.Type(), value)
+}
+
+func numeric(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8,
+		reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
 func populateRow(cols []string, values []interface{}) SqlRow {
 	datum := make(map[string]interface{})
 	for i, column := range cols {
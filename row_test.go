@@ -1,8 +1,17 @@
 package supersql_test
 
-import "testing"
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/rayattack/supersql"
+	"github.com/rayattack/supersql/supersqltest"
+)
 
 func TestRowColumn(t *testing.T) {
+	requireDB(t)
 	res, _ := Xql.SELECT("title").FROM("film").WHERE("film_id = ?", 133).GO()
 	row := res.Rows(1)
 
@@ -15,3 +24,72 @@ func TestRowColumn(t *testing.T) {
 		}
 	}
 }
+
+func TestRowInt64(t *testing.T) {
+	fake := supersqltest.New()
+	fake.Expect("FROM film").Returns([]string{"film_id", "length", "rental_duration", "title"}, []interface{}{int64(133), int32(88), int16(7), "Chamber Italian"})
+	r, err := fake.Root().SELECT("film_id", "length", "rental_duration", "title").FROM("film").GO()
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := r.Rows(1)
+	id, _ := row.Int64("film_id")
+	length, _ := row.Int64("length")
+	duration, _ := row.Int64("rental_duration")
+	if id != 133 || length != 88 || duration != 7 {
+		t.Fatal(id, length, duration)
+	}
+	if _, err := row.Int64("title"); err == nil {
+		t.Fatal("text is not an integer")
+	}
+}
+
+func TestAssign(t *testing.T) {
+	var (
+		id       string
+		network  string
+		address  string
+		nickname *string
+		length   *int16
+		rate     float64
+		period   time.Duration
+		features []string
+		document json.RawMessage
+	)
+	_, block, _ := net.ParseCIDR("10.0.0.0/8")
+	assignments := []struct {
+		dest  interface{}
+		value interface{}
+	}{
+		{&id, [16]byte{0x8f, 0x0c, 0x1a, 0x2b, 0x3c, 0x4d, 0x4e, 0x5f, 0x80, 0x91, 0xa2, 0xb3, 0xc4, 0xd5, 0xe6, 0xf7}},
+		{&network, block},
+		{&address, &net.IPNet{IP: net.IPv4(10, 1, 2, 3), Mask: net.CIDRMask(32, 32)}},
+		{&nickname, nil},
+		{&length, int32(88)},
+		{&rate, int64(4)},
+		{&period, 90 * time.Minute},
+		{&features, []interface{}{"Trailers", "Deleted Scenes"}},
+		{&document, map[string]interface{}{"a": 1}},
+	}
+	for _, a := range assignments {
+		if err := supersql.Assign(a.dest, a.value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if id != "8f0c1a2b-3c4d-4e5f-8091-a2b3c4d5e6f7" || network != "10.0.0.0/8" || address != "10.1.2.3" {
+		t.Fatal(id, network, address)
+	}
+	if nickname != nil || length == nil || *length != 88 || rate != 4 || period != 90*time.Minute {
+		t.Fatal(nickname, length, rate, period)
+	}
+	if len(features) != 2 || features[1] != "Deleted Scenes" || string(document) != `{"a":1}` {
+		t.Fatal(features, string(document))
+	}
+
+	if err := supersql.Assign(&rate, "many"); err == nil {
+		t.Fatal("text was assigned to a float64")
+	}
+	if err := supersql.Assign(rate, 1.5); err == nil {
+		t.Fatal("assigned to a value instead of a pointer")
+	}
+}
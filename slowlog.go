@@ -0,0 +1,122 @@
+package supersql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/rayattack/supersql/explain"
+)
+
+//A statement that took longer than the configured threshold together with its query plan. When the
+//plan could not be captured PlanErr says why and the rest of the event is still delivered.
+type SlowQueryEvent struct {
+	SQL      string
+	Args     []interface{}
+	Duration time.Duration
+	Err      error
+	Plan     *explain.Plan
+	PlanErr  error
+	SeqScans []*explain.Node
+}
+
+//Destination of slow query events i.e. a logger, an alerting system or a test
+type SlowQuerySink interface {
+	SlowQuery(ctx context.Context, event SlowQueryEvent)
+}
+
+type SlowQueryFunc func(ctx context.Context, event SlowQueryEvent)
+
+func (f SlowQueryFunc) SlowQuery(ctx context.Context, event SlowQueryEvent) {
+	f(ctx, event)
+}
+
+//Options for SlowQueries(...). Analyze runs EXPLAIN ANALYZE which executes the statement a second
+//time so it is only ever used for SELECT commands and should only be enabled against replicas.
+//Sequential scans reading at least SeqScanRows rows are flagged on the event. Events are logged with
+//the standard logger when Sink is nil.
+type SlowQueryConfig struct {
+	Threshold   time.Duration
+	Analyze     bool
+	SeqScanRows float64
+	Sink        SlowQuerySink
+}
+
+//Sink used when none is configured
+var logSlowQueries = SlowQueryFunc(func(ctx context.Context, event SlowQueryEvent) {
+	log.Printf("supersql: slow query took %s: %s", event.Duration, event.SQL)
+})
+
+//Returns a copy of the query root that re-runs statements slower than the threshold through EXPLAIN
+//and delivers their plans to the sink. The plan is captured before GO() returns so slow statements
+//pay for one more round trip, on the connection or transaction that ran them.
+func (q *SqlQuery) SlowQueries(config SlowQueryConfig) *SqlQuery {
+	if config.Sink == nil {
+		config.Sink = logSlowQueries
+	}
+	return q.Use(slowlog{config: config})
+}
+
+type slowlog struct {
+	config SlowQueryConfig
+}
+
+func (s slowlog) Before(ctx context.Context, event *QueryEvent) context.Context {
+	return ctx
+}
+
+func (s slowlog) After(ctx context.Context, event *QueryEvent) {
+	if event.Duration < s.config.Threshold {
+		return
+	}
+	slow := SlowQueryEvent{SQL: event.SQL, Args: event.Args, Duration: event.Duration, Err: event.Err}
+	slow.Plan, slow.PlanErr = s.explain(ctx, event)
+	if slow.Plan != nil {
+		slow.SeqScans = slow.Plan.SeqScans(s.config.SeqScanRows)
+	}
+	s.config.Sink.SlowQuery(ctx, slow)
+}
+
+func (s slowlog) explain(ctx context.Context, event *QueryEvent) (*explain.Plan, error) {
+	keyword := strings.ToUpper(strings.SplitN(strings.TrimSpace(event.SQL), " ", 2)[0])
+	switch keyword {
+	case "SELECT", "INSERT", "UPDATE", "DELETE", "WITH":
+	default:
+		return nil, fmt.Errorf("%s statements can not be explained", keyword)
+	}
+
+	options := "FORMAT JSON"
+	if s.config.Analyze && keyword == "SELECT" {
+		options = "ANALYZE, BUFFERS, FORMAT JSON"
+	}
+	if event.exec == nil {
+		return nil, fmt.Errorf("no executor to explain the statement with")
+	}
+	ctrl, err := event.exec.Query(ctx, fmt.Sprintf("EXPLAIN (%s) %s", options, event.SQL), event.Args...)
+	if err != nil {
+		return nil, err
+	}
+	results, err := collect(ctrl)
+	if err != nil {
+		return nil, err
+	}
+	if results.count == 0 {
+		return nil, fmt.Errorf("EXPLAIN returned no plan")
+	}
+	return explain.Parse(document(results.rows[0].Column("QUERY PLAN")))
+}
+
+//The plan column is json and depending on the driver arrives as text, bytes or already decoded
+func document(value interface{}) []byte {
+	switch v := value.(type) {
+	case []byte:
+		return v
+	case string:
+		return []byte(v)
+	}
+	data, _ := json.Marshal(value)
+	return data
+}
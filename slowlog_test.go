@@ -0,0 +1,99 @@
+package supersql_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rayattack/supersql"
+	"github.com/rayattack/supersql/supersqltest"
+)
+
+const plan = `[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "rental", "Plan Rows": 16044}}]`
+
+func TestSlowQueriesCapturePlans(t *testing.T) {
+	fake := supersqltest.New()
+	fake.Expect("EXPLAIN (FORMAT JSON)").Returns([]string{"QUERY PLAN"}, []interface{}{plan})
+
+	events := []supersql.SlowQueryEvent{}
+	q := fake.Root().SlowQueries(supersql.SlowQueryConfig{
+		SeqScanRows: 1000,
+		Sink: supersql.SlowQueryFunc(func(ctx context.Context, event supersql.SlowQueryEvent) {
+			events = append(events, event)
+		}),
+	})
+	_, err := q.SELECT().FROM("rental").WHERE("customer_id = ?", 4).GO()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 1 {
+		t.Fatal(events)
+	}
+	event := events[0]
+	if event.PlanErr != nil || event.Plan == nil || len(event.SeqScans) != 1 {
+		t.Log(event)
+		t.Fail()
+	}
+	calls := fake.Calls()
+	if len(calls) != 2 || calls[1].SQL != "EXPLAIN (FORMAT JSON) SELECT * FROM rental WHERE customer_id = $1" || len(calls[1].Args) != 1 {
+		t.Log(calls)
+		t.Fail()
+	}
+}
+
+func TestSlowQueriesAnalyzeOnlySelects(t *testing.T) {
+	fake := supersqltest.New()
+	fake.Expect("EXPLAIN").Returns([]string{"QUERY PLAN"}, []interface{}{plan}).Always()
+
+	events := 0
+	q := fake.Root().SlowQueries(supersql.SlowQueryConfig{
+		Analyze: true,
+		Sink: supersql.SlowQueryFunc(func(ctx context.Context, event supersql.SlowQueryEvent) {
+			events++
+		}),
+	})
+	q.SELECT().FROM("rental").GO()
+	q.RUN("DELETE FROM rental").GO()
+	q.RUN("VACUUM rental").GO()
+
+	calls := fake.Calls()
+	if events != 3 || len(calls) != 5 {
+		t.Fatal(events, calls)
+	}
+	if calls[1].SQL != "EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) SELECT * FROM rental" {
+		t.Fail()
+	}
+	//writes must never be executed twice
+	if calls[3].SQL != "EXPLAIN (FORMAT JSON) DELETE FROM rental" {
+		t.Fail()
+	}
+}
+
+//Executor counting the statements that are not run by one of its transactions
+type pooled struct {
+	*supersqltest.Fake
+	queries *int
+}
+
+func (p pooled) Query(ctx context.Context, ssql string, args ...interface{}) (supersql.Cursor, error) {
+	*p.queries++
+	return p.Fake.Query(ctx, ssql, args...)
+}
+
+func TestSlowQueriesExplainInTransaction(t *testing.T) {
+	fake := supersqltest.New()
+	fake.Expect("EXPLAIN").Returns([]string{"QUERY PLAN"}, []interface{}{plan})
+	queries := 0
+	q := supersql.QueryWith(context.Background(), pooled{fake, &queries}, supersql.Postgres).SlowQueries(supersql.SlowQueryConfig{})
+	err := q.TRANSACTION(func(tx *supersql.SqlQuery) error {
+		_, err := tx.SELECT().FROM("rental").GO()
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := fake.Calls()
+	if queries != 0 || len(calls) != 4 || calls[2].SQL != "EXPLAIN (FORMAT JSON) SELECT * FROM rental" || calls[3].SQL != "COMMIT" {
+		t.Fatal("statements are explained by the transaction that ran them", queries, calls)
+	}
+}
@@ -0,0 +1,237 @@
+//Package sqlfile loads named queries from annotated .sql files, typically embedded with embed.FS.
+//Every query starts with a header comment naming it and saying what it returns:
+//
+//	-- name: GetFilm :one
+//	SELECT film_id, title FROM film WHERE film_id = :film_id;
+//
+//	-- name: ListFilms :many
+//	SELECT film_id, title FROM film WHERE rating = ? ORDER BY title;
+//
+//	-- name: DeleteFilm :exec
+//	DELETE FROM film WHERE film_id = ?;
+//
+//Queries use either ? placeholders or :named placeholders, never both.
+package sqlfile
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/rayattack/supersql"
+)
+
+//What a query returns
+type Kind string
+
+const (
+	One  Kind = ":one"
+	Many Kind = ":many"
+	Exec Kind = ":exec"
+)
+
+type Query struct {
+	Name string
+	Kind Kind
+	//The statement with every placeholder written as ?
+	SQL string
+	//Names of the :named placeholders in order of appearance, nil for queries with ? placeholders.
+	//A name used more than once appears once for every use.
+	Params []string
+	//Number of arguments the query takes
+	Arity int
+	File  string
+	Line  int
+}
+
+//Queries in the order they were loaded
+type Queries []Query
+
+func (qs Queries) Get(name string) (Query, bool) {
+	for _, q := range qs {
+		if q.Name == name {
+			return q, true
+		}
+	}
+	return Query{}, false
+}
+
+var header = regexp.MustCompile(`^--\s*name:\s*(\S+)\s*(\S*)\s*$`)
+
+//Load the queries of every file in fsys matching one of the glob patterns i.e. "queries/*.sql".
+//Files are read in lexical order and query names must be unique across all of them.
+func Load(fsys fs.FS, patterns ...string) (Queries, error) {
+	files := []string{}
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+
+	queries := Queries{}
+	for i, file := range files {
+		if i > 0 && files[i-1] == file {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := Parse(file, data)
+		if err != nil {
+			return nil, err
+		}
+		for _, q := range parsed {
+			if existing, ok := queries.Get(q.Name); ok {
+				return nil, fmt.Errorf("sqlfile: %s:%d: %s is already defined at %s:%d", q.File, q.Line, q.Name, existing.File, existing.Line)
+			}
+			queries = append(queries, q)
+		}
+	}
+	return queries, nil
+}
+
+//Parse the queries of a single file, file is only used in errors and Query.File
+func Parse(file string, data []byte) (Queries, error) {
+	queries := Queries{}
+	var current *Query
+	body := []string{}
+
+	finish := func() error {
+		if current == nil {
+			return nil
+		}
+		ssql := strings.TrimSpace(strings.Join(body, "\n"))
+		ssql = strings.TrimSpace(strings.TrimSuffix(ssql, ";"))
+		if ssql == "" {
+			return fmt.Errorf("sqlfile: %s:%d: %s has no statement", file, current.Line, current.Name)
+		}
+		rewritten, params, arity, err := placeholders(ssql)
+		if err != nil {
+			return fmt.Errorf("sqlfile: %s:%d: %s: %w", file, current.Line, current.Name, err)
+		}
+		current.SQL, current.Params, current.Arity = rewritten, params, arity
+		queries = append(queries, *current)
+		return nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if match := header.FindStringSubmatch(strings.TrimSpace(text)); match != nil {
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			kind := Kind(match[2])
+			if kind != One && kind != Many && kind != Exec {
+				return nil, fmt.Errorf("sqlfile: %s:%d: %s must be annotated with :one, :many or :exec", file, line, match[1])
+			}
+			current, body = &Query{Name: match[1], Kind: kind, File: file, Line: line}, nil
+			continue
+		}
+		if current == nil {
+			if trimmed := strings.TrimSpace(text); trimmed != "" && !strings.HasPrefix(trimmed, "--") {
+				return nil, fmt.Errorf("sqlfile: %s:%d: statement without a -- name: header", file, line)
+			}
+			continue
+		}
+		body = append(body, text)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+	return queries, nil
+}
+
+//Rewrite :named placeholders to ? and collect their names. Text inside literals, quoted identifiers
+//and comments is left alone and :: casts are not placeholders.
+func placeholders(ssql string) (string, []string, int, error) {
+	out := strings.Builder{}
+	params := []string{}
+	positional := 0
+	runes := []rune(ssql)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\'' || r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				end++
+			}
+			if end == len(runes) {
+				return "", nil, 0, fmt.Errorf("unterminated %c", r)
+			}
+			out.WriteString(string(runes[i : end+1]))
+			i = end
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			end := i
+			for end < len(runes) && runes[end] != '\n' {
+				end++
+			}
+			out.WriteString(string(runes[i:end]))
+			i = end - 1
+		case r == '?':
+			positional++
+			out.WriteRune(r)
+		case r == ':' && i+1 < len(runes) && runes[i+1] == ':':
+			out.WriteString("::")
+			i++
+		case r == ':' && i+1 < len(runes) && word(runes[i+1]) && !digit(runes[i+1]):
+			end := i + 1
+			for end < len(runes) && word(runes[end]) {
+				end++
+			}
+			params = append(params, string(runes[i+1:end]))
+			out.WriteRune('?')
+			i = end - 1
+		default:
+			out.WriteRune(r)
+		}
+	}
+	if positional > 0 && len(params) > 0 {
+		return "", nil, 0, fmt.Errorf("? and :named placeholders can not be mixed")
+	}
+	if len(params) == 0 {
+		return out.String(), nil, positional, nil
+	}
+	return out.String(), params, len(params), nil
+}
+
+func word(r rune) bool {
+	return r == '_' || digit(r) || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+func digit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+//The query as a command of root with args bound to its ? placeholders in order
+func (q Query) Bind(root *supersql.SqlQuery, args ...interface{}) (supersql.Command, error) {
+	if len(args) != q.Arity {
+		return nil, fmt.Errorf("sqlfile: %s takes %d arguments, got %d", q.Name, q.Arity, len(args))
+	}
+	return root.RAW(q.SQL, args...), nil
+}
+
+//The query as a command of root with the values of its :named placeholders taken from args
+func (q Query) BindNamed(root *supersql.SqlQuery, args map[string]interface{}) (supersql.Command, error) {
+	values := []interface{}{}
+	for _, name := range q.Params {
+		value, ok := args[name]
+		if !ok {
+			return nil, fmt.Errorf("sqlfile: %s has no value for :%s", q.Name, name)
+		}
+		values = append(values, value)
+	}
+	return q.Bind(root, values...)
+}
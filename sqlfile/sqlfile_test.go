@@ -0,0 +1,116 @@
+package sqlfile_test
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/rayattack/supersql/sqlfile"
+	"github.com/rayattack/supersql/supersqltest"
+)
+
+var files = fstest.MapFS{
+	"queries/film.sql": {Data: []byte(`-- Film queries
+
+-- name: GetFilm :one
+SELECT film_id, title, rental_rate::text
+FROM film
+WHERE film_id = :film_id AND title <> ':not_a_param' -- :nor_this
+  AND rating = :rating OR :film_id = 0;
+
+-- name: ListFilms :many
+SELECT title FROM film WHERE rating = ? ORDER BY title LIMIT ?;
+`)},
+	"queries/actor.sql": {Data: []byte(`-- name: DeleteActor :exec
+DELETE FROM actor WHERE actor_id = ?
+`)},
+	"queries/README.md": {Data: []byte("ignored")},
+}
+
+func TestLoad(t *testing.T) {
+	queries, err := sqlfile.Load(files, "queries/*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queries) != 3 || queries[0].Name != "DeleteActor" || queries[0].Kind != sqlfile.Exec || queries[0].File != "queries/actor.sql" {
+		t.Fatal(queries)
+	}
+
+	film, ok := queries.Get("GetFilm")
+	if !ok || film.Kind != sqlfile.One || film.Line != 3 {
+		t.Fatal(film)
+	}
+	expected := "SELECT film_id, title, rental_rate::text\nFROM film\nWHERE film_id = ? AND title <> ':not_a_param' -- :nor_this\n  AND rating = ? OR ? = 0"
+	if film.SQL != expected {
+		t.Fatal(film.SQL)
+	}
+	if strings.Join(film.Params, ",") != "film_id,rating,film_id" || film.Arity != 3 {
+		t.Fatal(film.Params)
+	}
+
+	list, _ := queries.Get("ListFilms")
+	if list.Params != nil || list.Arity != 2 || strings.HasSuffix(list.SQL, ";") {
+		t.Fatal(list)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	broken := map[string]string{
+		"missing kind": "-- name: GetFilm\nSELECT 1",
+		"unknown kind": "-- name: GetFilm :first\nSELECT 1",
+		"no header":    "SELECT 1",
+		"empty":        "-- name: GetFilm :one\n\n-- name: ListFilms :many\nSELECT 1",
+		"mixed":        "-- name: GetFilm :one\nSELECT 1 WHERE a = ? AND b = :b",
+		"unterminated": "-- name: GetFilm :one\nSELECT 'oops",
+	}
+	for reason, source := range broken {
+		if _, err := sqlfile.Parse("film.sql", []byte(source)); err == nil {
+			t.Errorf("%s was accepted", reason)
+		}
+	}
+
+	duplicated := fstest.MapFS{
+		"a.sql": {Data: []byte("-- name: GetFilm :one\nSELECT 1")},
+		"b.sql": {Data: []byte("-- name: GetFilm :one\nSELECT 2")},
+	}
+	if _, err := sqlfile.Load(duplicated, "*.sql"); err == nil || !strings.Contains(err.Error(), "a.sql:1") {
+		t.Fatal(err)
+	}
+}
+
+func TestBind(t *testing.T) {
+	queries, _ := sqlfile.Load(files, "queries/*.sql")
+	fake := supersqltest.New()
+	fake.Expect("FROM film").Returns([]string{"film_id", "title"}, []interface{}{int32(133), "Chamber Italian"})
+
+	film, _ := queries.Get("GetFilm")
+	command, err := film.BindNamed(fake.Root(), map[string]interface{}{"film_id": 133, "rating": "PG"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := command.GO()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title, _ := r.Rows(1).String("title"); title != "Chamber Italian" {
+		t.Fatal(title)
+	}
+	call := fake.Calls()[0]
+	if !strings.Contains(call.SQL, "film_id = $1 AND") || !strings.Contains(call.SQL, "rating = $2 OR $3 = 0") {
+		t.Fatal(call.SQL)
+	}
+	if len(call.Args) != 3 || call.Args[0] != 133 || call.Args[2] != 133 {
+		t.Fatal(call.Args)
+	}
+
+	if _, err := film.BindNamed(fake.Root(), map[string]interface{}{"film_id": 133}); err == nil {
+		t.Fail()
+	}
+	list, _ := queries.Get("ListFilms")
+	if _, err := list.Bind(fake.Root(), "PG"); err == nil {
+		t.Fail()
+	}
+	if _, err := list.Bind(fake.Root(), "PG", 10); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,286 @@
+//Package supersqltest provides a scriptable in-memory supersql.Executor so code built on supersql
+//can be unit tested without a database server.
+//
+//	fake := supersqltest.New()
+//	fake.Expect("FROM film").Returns([]string{"title"}, []interface{}{"Chamber Italian"})
+//	results, err := fake.Root().SELECT("title").FROM("film").WHERE("film_id = ?", 133).GO()
+//	fake.Calls()[0].SQL // SELECT title FROM film WHERE film_id = $1
+package supersqltest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/rayattack/supersql"
+)
+
+//A statement that reached the fake, rendered exactly as a real server would have received it
+type Call struct {
+	SQL  string
+	Args []interface{}
+	Exec bool
+}
+
+//Scripted answer for statements containing a fragment of SQL. An expectation is used up by the first
+//statement it answers unless Always() is invoked.
+type Expectation struct {
+	fragment string
+	columns  []string
+	rows     [][]interface{}
+	affected int64
+	err      error
+	always   bool
+	used     int
+}
+
+//Answer with the given records. Every record must have a value for each of the columns.
+func (e *Expectation) Returns(columns []string, rows ...[]interface{}) *Expectation {
+	e.columns = columns
+	e.rows = rows
+	return e
+}
+
+//Answer commands that do not return records with the number of affected records
+func (e *Expectation) Affects(count int64) *Expectation {
+	e.affected = count
+	return e
+}
+
+//Fail matching statements with err
+func (e *Expectation) Fails(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+//Keep answering matching statements instead of being used up by the first one
+func (e *Expectation) Always() *Expectation {
+	e.always = true
+	return e
+}
+
+type Fake struct {
+	mu     sync.Mutex
+	calls  []Call
+	script []*Expectation
+	strict bool
+	events chan event
+}
+
+//Something that happens to the connection of a listener
+type event struct {
+	notification supersql.Notification
+	err          error
+}
+
+func New() *Fake {
+	return &Fake{events: make(chan event, 64)}
+}
+
+//Return a Postgres query root backed by the fake. Use WithDialect(...) on it for other dialects.
+func (f *Fake) Root() *supersql.SqlQuery {
+	return supersql.QueryWith(context.Background(), f, supersql.Postgres)
+}
+
+//Script the answer for the next statement whose SQL contains fragment. Expectations are matched in
+//the order they were registered.
+func (f *Fake) Expect(fragment string) *Expectation {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	e := &Expectation{fragment: fragment}
+	f.script = append(f.script, e)
+	return e
+}
+
+//Fail statements that were not scripted instead of answering them with an empty result
+func (f *Fake) Strict() *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.strict = true
+	return f
+}
+
+//Every statement received so far in the order they were received
+func (f *Fake) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+//Forget the statements received so far. Scripted expectations are kept.
+func (f *Fake) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = nil
+}
+
+//Report scripted expectations that were never used
+func (f *Fake) Verify() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	unmet := []string{}
+	for _, e := range f.script {
+		if e.used == 0 {
+			unmet = append(unmet, fmt.Sprintf("%q", e.fragment))
+		}
+	}
+	if len(unmet) > 0 {
+		return fmt.Errorf("supersqltest: expected statements were never sent: %s", strings.Join(unmet, ", "))
+	}
+	return nil
+}
+
+func (f *Fake) Exec(ctx context.Context, ssql string, args ...interface{}) (int64, error) {
+	e, err := f.answer(ssql, args, true)
+	if err != nil {
+		return 0, err
+	}
+	return e.affected, nil
+}
+
+func (f *Fake) Query(ctx context.Context, ssql string, args ...interface{}) (supersql.Cursor, error) {
+	e, err := f.answer(ssql, args, false)
+	if err != nil {
+		return nil, err
+	}
+	return &cursor{columns: e.columns, rows: e.rows, position: -1}, nil
+}
+
+//Start a transaction. BEGIN, COMMIT and ROLLBACK are recorded as calls so tests can assert on them
+//and can be scripted to fail like any other statement.
+func (f *Fake) Begin(ctx context.Context) (supersql.Transaction, error) {
+	if _, err := f.answer("BEGIN", nil, true); err != nil {
+		return nil, err
+	}
+	return transaction{f}, nil
+}
+
+//The fake has no pool so sessions share it
+func (f *Fake) Session(ctx context.Context) (supersql.Executor, func(), error) {
+	return f, func() {}, nil
+}
+
+//Answer the statements of a batch in order like a server running them in an implicit transaction,
+//the statements after a failing one fail as well
+func (f *Fake) Batch(ctx context.Context, statements []supersql.BatchStatement, each func(i int, cursor supersql.Cursor, err error)) error {
+	var failure error
+	for i, statement := range statements {
+		if failure != nil {
+			f.mu.Lock()
+			f.calls = append(f.calls, Call{SQL: statement.SQL, Args: statement.Args, Exec: statement.Exec})
+			f.mu.Unlock()
+			each(i, nil, fmt.Errorf("supersqltest: batch aborted: %w", failure))
+			continue
+		}
+		e, err := f.answer(statement.SQL, statement.Args, statement.Exec)
+		switch {
+		case err != nil:
+			failure = err
+			each(i, nil, err)
+		case statement.Exec:
+			each(i, nil, nil)
+		default:
+			each(i, &cursor{columns: e.columns, rows: e.rows, position: -1}, nil)
+		}
+	}
+	return failure
+}
+
+//Deliver a notification to the listener of the fake as if another session sent it. Notifications
+//sent through the fake with pg_notify(...) are delivered too.
+func (f *Fake) Notify(channel string, payload string) {
+	f.events <- event{notification: supersql.Notification{Channel: channel, Payload: payload}}
+}
+
+//Drop the connection of the listener of the fake with err so it has to reconnect
+func (f *Fake) Disconnect(err error) {
+	f.events <- event{err: err}
+}
+
+func (f *Fake) WaitForNotification(ctx context.Context) (supersql.Notification, error) {
+	select {
+	case e := <-f.events:
+		return e.notification, e.err
+	case <-ctx.Done():
+		return supersql.Notification{}, ctx.Err()
+	}
+}
+
+func (f *Fake) answer(ssql string, args []interface{}, exec bool) (*Expectation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, Call{SQL: ssql, Args: args, Exec: exec})
+
+	for _, e := range f.script {
+		if (e.always || e.used == 0) && strings.Contains(ssql, e.fragment) {
+			e.used++
+			if e.err == nil {
+				f.notify(ssql, args)
+			}
+			return e, e.err
+		}
+	}
+	if f.strict {
+		return nil, fmt.Errorf("supersqltest: unexpected statement: %s", ssql)
+	}
+	f.notify(ssql, args)
+	return &Expectation{}, nil
+}
+
+//Deliver what pg_notify(channel, payload) statements send to the listener of the fake
+func (f *Fake) notify(ssql string, args []interface{}) {
+	if !strings.Contains(ssql, "pg_notify(") || len(args) != 2 {
+		return
+	}
+	select {
+	case f.events <- event{notification: supersql.Notification{Channel: fmt.Sprint(args[0]), Payload: fmt.Sprint(args[1])}}:
+	default:
+		//nobody is listening
+	}
+}
+
+type transaction struct {
+	*Fake
+}
+
+func (t transaction) Commit(ctx context.Context) error {
+	_, err := t.answer("COMMIT", nil, true)
+	return err
+}
+
+func (t transaction) Rollback(ctx context.Context) error {
+	_, err := t.answer("ROLLBACK", nil, true)
+	return err
+}
+
+type cursor struct {
+	columns  []string
+	rows     [][]interface{}
+	position int
+}
+
+func (c *cursor) Columns() ([]string, error) {
+	return c.columns, nil
+}
+
+func (c *cursor) Next() bool {
+	c.position++
+	return c.position < len(c.rows)
+}
+
+func (c *cursor) Values() ([]interface{}, error) {
+	row := c.rows[c.position]
+	if len(row) != len(c.columns) {
+		return nil, fmt.Errorf("supersqltest: record %d has %d values for %d columns", c.position+1, len(row), len(c.columns))
+	}
+	return row, nil
+}
+
+func (c *cursor) Err() error {
+	return nil
+}
+
+func (c *cursor) Close() error {
+	return nil
+}
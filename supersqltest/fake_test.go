@@ -0,0 +1,90 @@
+package supersqltest_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rayattack/supersql"
+	"github.com/rayattack/supersql/supersqltest"
+)
+
+func TestFakeRecordsRenderedSQL(t *testing.T) {
+	fake := supersqltest.New()
+	_, err := fake.Root().SELECT("title").FROM("film").WHERE("film_id = ?", 133).GO()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := fake.Calls()
+	if len(calls) != 1 || calls[0].SQL != "SELECT title FROM film WHERE film_id = $1" || calls[0].Exec {
+		t.Log(calls)
+		t.Fail()
+	}
+	if len(calls[0].Args) != 1 || calls[0].Args[0] != 133 {
+		t.Fail()
+	}
+}
+
+func TestFakeReturnsCannedRows(t *testing.T) {
+	fake := supersqltest.New()
+	fake.Expect("FROM film").Returns([]string{"title"}, []interface{}{"Chamber Italian"}, []interface{}{"Academy Dinosaur"})
+
+	r, err := fake.Root().SELECT("title").FROM("film").GO()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Count() != 2 {
+		t.Fail()
+	}
+	if title, _ := r.Rows(2).String("title"); title != "Academy Dinosaur" {
+		t.Fail()
+	}
+	if fake.Verify() != nil {
+		t.Fail()
+	}
+
+	//expectations are used up unless Always() was invoked
+	r, _ = fake.Root().SELECT("title").FROM("film").GO()
+	if r.Count() != 0 {
+		t.Fail()
+	}
+}
+
+func TestFakeInjectsErrors(t *testing.T) {
+	broken := errors.New("connection reset")
+	fake := supersqltest.New()
+	fake.Expect("DELETE").Fails(broken).Always()
+
+	q := fake.Root().RUN("DELETE FROM film")
+	for i := 0; i < 2; i++ {
+		if _, err := q.GO(); err != broken {
+			t.Fail()
+		}
+	}
+	if !fake.Calls()[0].Exec {
+		t.Fail()
+	}
+}
+
+func TestFakeStrictAndVerify(t *testing.T) {
+	fake := supersqltest.New().Strict()
+	fake.Expect("UPDATE film")
+
+	if _, err := fake.Root().SELECT().FROM("actor").GO(); err == nil {
+		t.Fail()
+	}
+	if fake.Verify() == nil {
+		t.Fail()
+	}
+}
+
+func TestFakeDialect(t *testing.T) {
+	fake := supersqltest.New()
+	fake.Root().WithDialect(supersql.MySQL).INSERT_INTO("actor", []string{"name"}).VALUES([]interface{}{"ab"}).GO()
+
+	calls := fake.Calls()
+	if len(calls) != 1 || calls[0].SQL != "INSERT INTO actor (name) VALUES (?)" || calls[0].Args[0] != "ab" {
+		t.Log(calls)
+		t.Fail()
+	}
+}
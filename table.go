@@ -1,44 +1,220 @@
 package supersql
 
-import "fmt"
-
-func getnom(t *SqlTable) string {
-	if len(t.alias) > 0 {
-		return fmt.Sprintf("%s.%s", t.alias, t.name)
-	} else {
-		return t.name
-	}
-}
+import (
+	"fmt"
+	"strings"
+)
 
 type SqlTable struct {
-	alias string
-	name  string
-	ddl   string
+	alias     string
+	schema    string
+	name      string
+	ddl       string
+	fields    []Field
+	strategy  string
+	partition []string
+	dialect   Dialect
 }
 
-func Table(name string) (table *SqlTable) {
+//Declare a table. When fields are provided the table knows how to create itself and commands
+//expantiated against it reject columns it does not have. Names can be schema qualified i.e.
+//billing.invoice and are quoted where they are rendered when they need to be i.e. "user" or "Invoice".
+func Table(name string, fields ...Field) (table *SqlTable) {
 	table = new(SqlTable)
-	table.name = name
+	parts := splitIdentifier(name)
+	if len(parts) > 1 {
+		table.schema = strings.Join(parts[:len(parts)-1], ".")
+	}
+	table.name = parts[len(parts)-1]
+	table.fields = fields
 	return
 }
 
-func (t *SqlTable) AS(alias ...string) string {
-	if alias != nil {
-		t.alias = alias[0]
+//Place the table in schema instead of the schemas on the search path
+func (t *SqlTable) InSchema(schema string) *SqlTable {
+	t.schema = schema
+	return t
+}
+
+//Render the table for dialect where it is written outside a command i.e. in Col(...), REFERENCES(...),
+//Index(...) and GRANT ... ON_TABLE(...). Tables render for postgres unless told otherwise, commands
+//always render their tables for the dialect of the query.
+func (t *SqlTable) InDialect(dialect Dialect) *SqlTable {
+	t.dialect = dialect
+	return t
+}
+
+func (t *SqlTable) dialectOf() Dialect {
+	if t.dialect == nil {
+		return Postgres
 	}
+	return t.dialect
+}
+
+//A copy of the table known by alias within commands. The table itself is left untouched so the
+//same declaration can be aliased differently i.e. twice in a self join.
+//
+//	a, b := film.AS("a"), film.AS("b")
+//	q.SELECT(a.Col("title"), b.Col("title")).FROM(a).JOIN(b).ON(a.Col("language_id") + " = " + b.Col("language_id"))
+func (t *SqlTable) AS(alias string) *SqlTable {
+	aliased := *t
+	aliased.alias = alias
+	return &aliased
+}
+
+//Alias of the table, empty when it was not aliased
+func (t *SqlTable) Alias() string {
+	return t.alias
+}
+
+//Reference to column of the table qualified with its alias or else its name i.e. r.rental_date
+func (t *SqlTable) Col(column string) string {
 	if t.alias != "" {
-		return fmt.Sprintf("%s %s", t.name, t.alias)
-	} else {
-		return t.name
+		return fmt.Sprintf("%s.%s", t.alias, column)
 	}
+	return fmt.Sprintf("%s.%s", t.identifier(t.dialectOf()), column)
 }
 
 func (t *SqlTable) DDL(ddl string) error {
-	//todo implement sql syntax check logic and return early malformed warning here
+	if err := ValidateDDL(ddl); err != nil {
+		return err
+	}
 	t.ddl = ddl
 	return nil
 }
 
+//Returns the DDL registered with t.DDL(...) or the CREATE TABLE statement generated from the fields
+//the table was declared with.
 func (t *SqlTable) GO() string {
+	if t.ddl == "" && len(t.fields) > 0 {
+		return t.CREATE()
+	}
 	return t.ddl
 }
+
+//Generate the CREATE TABLE statement for the fields the table was declared with
+func (t *SqlTable) CREATE() string {
+	columns := []string{}
+	for _, field := range t.fields {
+		columns = append(columns, field.definition(t.dialectOf()))
+	}
+	ddl := fmt.Sprintf("CREATE TABLE %s (%s)", t.identifier(t.dialectOf()), strings.Join(columns, ", "))
+	if t.strategy != "" {
+		ddl += fmt.Sprintf(" PARTITION BY %s (%s)", t.strategy, strings.Join(t.partition, ", "))
+	}
+	return ddl
+}
+
+//Declare the table as partitioned by ranges of keys i.e. PARTITION_BY_RANGE("created_at"). Keys are
+//column names or expressions, postgres requires primary keys and unique constraints to include them.
+func (t *SqlTable) PARTITION_BY_RANGE(keys ...string) *SqlTable {
+	t.strategy, t.partition = "RANGE", keys
+	return t
+}
+
+//Declare the table as partitioned by lists of values of a single key
+func (t *SqlTable) PARTITION_BY_LIST(key string) *SqlTable {
+	t.strategy, t.partition = "LIST", []string{key}
+	return t
+}
+
+//Declare the table as partitioned by the hash of keys
+func (t *SqlTable) PARTITION_BY_HASH(keys ...string) *SqlTable {
+	t.strategy, t.partition = "HASH", keys
+	return t
+}
+
+//Returns RANGE, LIST or HASH and the partition keys of a partitioned table, an empty strategy otherwise
+func (t *SqlTable) Partitioning() (strategy string, keys []string) {
+	return t.strategy, t.partition
+}
+
+func (t *SqlTable) DROP() SqlDrop {
+	return SqlDrop{kind: "TABLE", name: t.identifier(t.dialectOf())}
+}
+
+//Name of the table qualified with its schema when it has one, unquoted
+func (t *SqlTable) Name() string {
+	if t.schema != "" {
+		return t.schema + "." + t.name
+	}
+	return t.name
+}
+
+//Schema the table was declared in, empty for tables found through the search path
+func (t *SqlTable) Schema() string {
+	return t.schema
+}
+
+//First error in the declaration of the fields of the table
+func (t *SqlTable) Err() error {
+	for _, field := range t.fields {
+		if field.err != nil {
+			return field.err
+		}
+	}
+	return nil
+}
+
+func (t *SqlTable) Fields() []Field {
+	return t.fields
+}
+
+//Returns the field called name and whether the table was declared with it
+func (t *SqlTable) Field(name string) (Field, bool) {
+	for _, field := range t.fields {
+		if field.name == name {
+			return field, true
+		}
+	}
+	return Field{}, false
+}
+
+//Reject column references that none of the relations of a command declare. Qualified references
+//i.e. r.rental_date are checked against the relation with that alias or name, unqualified ones only
+//when every relation in the command was declared with fields as they could belong to any of them.
+func checkColumns(relations []*SqlTable, refs []string, aliases map[string]bool) error {
+	for _, relation := range relations {
+		if err := relation.Err(); err != nil {
+			return err
+		}
+	}
+	declared := true
+	for _, relation := range relations {
+		if len(relation.fields) == 0 {
+			declared = false
+		}
+	}
+
+	for _, ref := range refs {
+		if dot := strings.LastIndex(ref, "."); dot > 0 {
+			qualifier, column := strings.Join(splitIdentifier(ref[:dot]), "."), ref[dot+1:]
+			for _, relation := range relations {
+				if len(relation.fields) == 0 || (qualifier != relation.alias && qualifier != relation.name && qualifier != relation.Name()) {
+					continue
+				}
+				if _, ok := relation.Field(column); !ok && column != "*" {
+					return fmt.Errorf("column %s does not exist on table %s", column, relation.name)
+				}
+			}
+			continue
+		}
+		if !declared || len(relations) == 0 || aliases[ref] {
+			continue
+		}
+		found := false
+		for _, relation := range relations {
+			if _, ok := relation.Field(ref); ok {
+				found = true
+			}
+		}
+		if !found {
+			names := []string{}
+			for _, relation := range relations {
+				names = append(names, relation.name)
+			}
+			return fmt.Errorf("column %s does not exist on %s", ref, strings.Join(names, ", "))
+		}
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/rayattack/supersql"
+	"github.com/rayattack/supersql/supersqltest"
 )
 
 var (
@@ -32,14 +33,43 @@ func TestTableHelper(t *testing.T) {
 		t.Fail()
 	}
 
-	rental.AS("r")
-	query = Xql.SELECT().FROM(rental.AS())
-	if query.PP() != "SELECT * FROM rental r" {
+	r := rental.AS("r")
+	query = Xql.SELECT(r.Col("rental_date")).FROM(r)
+	if query.PP() != "SELECT r.rental_date FROM rental r" {
 		t.Fail()
 	}
+	//aliasing leaves the table itself alone
+	if query = Xql.SELECT(rental.Col("rental_date")).FROM(rental); query.PP() != "SELECT rental.rental_date FROM rental" {
+		t.Log(query.PP())
+		t.Fail()
+	}
+}
+
+func TestSelfJoinWithAliases(t *testing.T) {
+	q := supersqltest.New().Root()
+	a, b := film.AS("a"), film.AS("b")
+	query := q.SELECT(a.Col("title"), b.Col("title")+" AS sequel").FROM(a).JOIN(b).ON(a.Col("language_id") + " = " + b.Col("language_id"))
+	if query.PP() != "SELECT a.title, b.title AS sequel FROM film a JOIN film b ON a.language_id = b.language_id" {
+		t.Fatal(query.PP())
+	}
+	if _, _, err := query.SQL(); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := q.SELECT(b.Col("rating")).FROM(a).JOIN(b).ON("a.film_id = b.film_id").SQL(); err == nil {
+		t.Fatal("columns of aliased tables should be checked")
+	}
+	if a.Alias() != "a" || film.Alias() != "" || a.Name() != "film" {
+		t.Fatal(a.Alias(), film.Alias())
+	}
+
+	insert := q.INSERT_INTO(film.AS("f"), []string{"film_id", "title"}).VALUES([]interface{}{1, "Chamber Italian"}).ON_CONFLICT("film_id").DO_UPDATE("title")
+	if sql, _, err := insert.SQL(); err != nil || sql != "INSERT INTO film AS f (film_id, title) VALUES ($1, $2) ON CONFLICT (film_id) DO UPDATE SET title = EXCLUDED.title" {
+		t.Fatal(sql, err)
+	}
 }
 
 func TestCreateTable(t *testing.T) {
+	requireDB(t)
 	select_by_id := ReadRepository(actor)
 	results, err := select_by_id("45")
 	if err != nil {
@@ -48,3 +78,70 @@ func TestCreateTable(t *testing.T) {
 	}
 	t.Log(results.Rows(1))
 }
+
+var (
+	language = supersql.Table("language",
+		supersql.Integer("language_id", supersql.PRIMARY_KEY),
+		supersql.Varchar("name", supersql.NOT_NULL),
+	)
+	film = supersql.Table("film",
+		supersql.Integer("film_id", supersql.PRIMARY_KEY),
+		supersql.Varchar("title", supersql.NOT_NULL, supersql.UNIQUE),
+		supersql.Integer("rental_duration", supersql.NOT_NULL, supersql.DEFAULT(3), supersql.CHECK("rental_duration > 0")),
+		supersql.Integer("language_id", supersql.REFERENCES(language, "language_id").ON_DELETE(supersql.CASCADE)),
+	)
+)
+
+func TestDeclarativeTable(t *testing.T) {
+	expected := "CREATE TABLE film (film_id integer PRIMARY KEY, title varchar NOT NULL UNIQUE, " +
+		"rental_duration integer NOT NULL DEFAULT 3 CHECK (rental_duration > 0), " +
+		"language_id integer REFERENCES language (language_id) ON DELETE CASCADE)"
+	if film.CREATE() != expected {
+		t.Log(film.CREATE())
+		t.Fail()
+	}
+	if film.GO() != expected {
+		t.Fail()
+	}
+	if _, ok := film.Field("title"); !ok {
+		t.Fail()
+	}
+}
+
+func TestDeclaredTableRejectsUnknownColumns(t *testing.T) {
+	q := supersqltest.New().Root()
+
+	valid := []supersql.Command{
+		q.SELECT("title", "rental_duration * 2 AS weeks").FROM(film).WHERE("film_id = ? AND lower(title) LIKE ?", 1, "a%").ORDER_BY("weeks"),
+		q.SELECT("film.title", "name").FROM(film).JOIN(language).ON("language.language_id = film.language_id"),
+		q.INSERT_INTO(film, []string{"film_id", "title"}).VALUES([]interface{}{1, "Chamber Italian"}).RETURNING("film_id"),
+		q.INSERT("title").INTO(film),
+		q.SELECT("title t", "count(*) OVER () total").FROM(film).ORDER_BY("t, total DESC"),
+		q.SELECT().FROM(film).WHERE("film_id > ? AND now() AT TIME ZONE 'UTC' > DATE '2020-01-01'", 1),
+		q.SELECT("film_id::text id").FROM(film).WHERE("title COLLATE \"C\" > 'A'").ORDER_BY("id"),
+	}
+	for _, cmd := range valid {
+		if _, _, err := cmd.SQL(); err != nil {
+			t.Log(err)
+			t.Fail()
+		}
+	}
+
+	invalid := []supersql.Command{
+		q.SELECT("titel").FROM(film),
+		q.SELECT().FROM(film).WHERE("rating = 'PG'"),
+		q.SELECT().FROM(film).ORDER_BY("release_year"),
+		q.INSERT_INTO(film, []string{"film_id", "rating"}),
+		q.INSERT("rating").INTO(film),
+		q.SELECT("film.rating").FROM(film).JOIN("inventory").ON("inventory.film_id = film.film_id"),
+		q.SELECT("titel t").FROM(film),
+		q.SELECT().FROM(film).WHERE("release_date > DATE '2020-01-01'"),
+		q.SELECT().FROM(film).WHERE("last_update AT TIME ZONE 'UTC' > now()"),
+	}
+	for _, cmd := range invalid {
+		if _, err := cmd.GO(); err == nil {
+			t.Log(cmd.PP())
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package supersql
+
+import (
+	"context"
+	"fmt"
+)
+
+//Returns a copy of the query root that executes commands with ctx
+func (q *SqlQuery) WithContext(ctx context.Context) *SqlQuery {
+	c := *q
+	c.ctx = ctx
+	return &c
+}
+
+//Run fn with a query root bound to a new transaction. The transaction is committed when fn returns
+//nil and rolled back when it returns an error or panics. Invoking TRANSACTION on the root handed to
+//fn starts a nested transaction i.e. a savepoint where the backend supports it.
+func (q *SqlQuery) TRANSACTION(fn func(tx *SqlQuery) error) (err error) {
+	b, ok := q.exec.(beginner)
+	if !ok {
+		return fmt.Errorf("%T does not support transactions", q.exec)
+	}
+	ctx := q.context()
+	tx, err := b.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback(ctx)
+			panic(p)
+		}
+	}()
+
+	c := *q
+	c.exec = tx
+	if err := fn(&c); err != nil {
+		if rerr := tx.Rollback(ctx); rerr != nil {
+			return fmt.Errorf("%w (rollback failed: %s)", err, rerr)
+		}
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+//Run fn with a query root pinned to a single connection of the pool for session level state such as
+//advisory locks, temporary tables or SET commands. Roots that are already bound to a single
+//connection or transaction hand themselves to fn.
+func (q *SqlQuery) SESSION(fn func(s *SqlQuery) error) error {
+	s, release, err := q.pinned()
+	if err != nil {
+		return err
+	}
+	defer release()
+	return fn(s)
+}
+
+//Copy of the query root bound to a connection taken out of the pool and the function that gives it
+//back. Roots that are already bound to a single connection or transaction return themselves.
+func (q *SqlQuery) pinned() (*SqlQuery, func(), error) {
+	p, ok := q.exec.(sessioner)
+	if !ok {
+		return q, func() {}, nil
+	}
+	exec, release, err := p.Session(q.context())
+	if err != nil {
+		return nil, nil, err
+	}
+	c := *q
+	c.exec = exec
+	return &c, release, nil
+}
+
+func (q SqlQuery) context() context.Context {
+	if q.ctx == nil {
+		return context.Background()
+	}
+	return q.ctx
+}
@@ -0,0 +1,63 @@
+package supersql_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rayattack/supersql"
+	"github.com/rayattack/supersql/supersqltest"
+)
+
+func statements(fake *supersqltest.Fake) []string {
+	sqls := []string{}
+	for _, call := range fake.Calls() {
+		sqls = append(sqls, call.SQL)
+	}
+	return sqls
+}
+
+func TestTransactionCommits(t *testing.T) {
+	fake := supersqltest.New()
+	err := fake.Root().TRANSACTION(func(tx *supersql.SqlQuery) error {
+		_, err := tx.RUN("UPDATE film SET rental_rate = 0.99").GO()
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqls := statements(fake)
+	if len(sqls) != 3 || sqls[0] != "BEGIN" || sqls[2] != "COMMIT" {
+		t.Log(sqls)
+		t.Fail()
+	}
+}
+
+func TestTransactionRollsBack(t *testing.T) {
+	broken := errors.New("serialization failure")
+	fake := supersqltest.New()
+	fake.Expect("UPDATE").Fails(broken)
+
+	err := fake.Root().TRANSACTION(func(tx *supersql.SqlQuery) error {
+		_, err := tx.RUN("UPDATE film SET rental_rate = 0.99").GO()
+		return err
+	})
+	if err != broken {
+		t.Fail()
+	}
+	sqls := statements(fake)
+	if len(sqls) != 3 || sqls[2] != "ROLLBACK" {
+		t.Log(sqls)
+		t.Fail()
+	}
+}
+
+func TestSession(t *testing.T) {
+	fake := supersqltest.New()
+	err := fake.Root().SESSION(func(s *supersql.SqlQuery) error {
+		_, err := s.RUN("SET search_path TO billing").GO()
+		return err
+	})
+	if err != nil || len(fake.Calls()) != 1 {
+		t.Fail()
+	}
+}
@@ -0,0 +1,464 @@
+package supersql
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+//Converts a Go value into what is sent to the server for a column, rejecting values the column
+//can not hold. nil always binds as NULL.
+type binder func(value interface{}) (interface{}, error)
+
+//Bind a Go value for this field i.e. check an integer fits a smallint, a string is short enough for
+//a varchar(n) or marshal a struct for a jsonb column. Pointers i.e. the fields generated for nullable
+//columns bind what they point to or NULL and a driver.Valuer binds its value. Fields without a known
+//type pass values as is.
+func (f Field) Bind(value interface{}) (interface{}, error) {
+	if value == nil || f.bind == nil {
+		return value, nil
+	}
+	value, err := underlying(value)
+	if err != nil {
+		return nil, fmt.Errorf("column %s: %w", f.name, err)
+	}
+	if value == nil {
+		return nil, nil
+	}
+	bound, err := f.bind(value)
+	if err != nil {
+		return nil, fmt.Errorf("column %s: %w", f.name, err)
+	}
+	return bound, nil
+}
+
+//The value behind pointers and driver.Valuer implementations, nil for a nil pointer
+func underlying(value interface{}) (interface{}, error) {
+	for value != nil {
+		v := reflect.ValueOf(value)
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return nil, nil
+		}
+		if valuer, ok := value.(driver.Valuer); ok {
+			return valuer.Value()
+		}
+		if v.Kind() != reflect.Ptr {
+			return value, nil
+		}
+		value = v.Elem().Interface()
+	}
+	return nil, nil
+}
+
+func SmallInt(name string, options ...interface{}) Field {
+	return typed(colmaker(name, "smallint", false, options...), integers(math.MinInt16, math.MaxInt16))
+}
+
+func Integer(name string, options ...interface{}) Field {
+	return typed(colmaker(name, "integer", false, options...), integers(math.MinInt32, math.MaxInt32))
+}
+
+func BigInt(name string, options ...interface{}) Field {
+	return typed(colmaker(name, "bigint", false, options...), integers(math.MinInt64, math.MaxInt64))
+}
+
+//Auto incrementing integer backed by a sequence. Prefer identity columns on Postgres 10 and above
+//i.e. Integer("id", GENERATED_ALWAYS_AS_IDENTITY).
+func Serial(name string, options ...interface{}) Field {
+	return typed(colmaker(name, "serial", false, options...), integers(math.MinInt32, math.MaxInt32))
+}
+
+func BigSerial(name string, options ...interface{}) Field {
+	return typed(colmaker(name, "bigserial", false, options...), integers(math.MinInt64, math.MaxInt64))
+}
+
+func Boolean(name string, options ...interface{}) Field {
+	return typed(colmaker(name, "boolean", false, options...), func(value interface{}) (interface{}, error) {
+		if _, ok := value.(bool); !ok {
+			return nil, fmt.Errorf("%s %T", TIP, value)
+		}
+		return value, nil
+	})
+}
+
+func Text(name string, options ...interface{}) Field {
+	return typed(colmaker(name, "text", true, options...), characters(0))
+}
+
+//Variable length text, an int option sets the maximum length i.e. Varchar("title", 255, NOT_NULL)
+func Varchar(name string, options ...interface{}) Field {
+	length, options := sized(options)
+	sqltype := "varchar"
+	if length > 0 {
+		sqltype = fmt.Sprintf("varchar(%d)", length)
+	}
+	return typed(colmaker(name, sqltype, true, options...), characters(length))
+}
+
+//Fixed length text padded with spaces by the server
+func Char(name string, length int, options ...interface{}) Field {
+	return typed(colmaker(name, fmt.Sprintf("char(%d)", length), true, options...), characters(length))
+}
+
+//Exact number with precision digits in total and scale digits after the decimal point. Bind strings
+//i.e. "19.99" to avoid the rounding of float64.
+func Numeric(name string, precision int, scale int, options ...interface{}) Field {
+	sqltype := fmt.Sprintf("numeric(%d, %d)", precision, scale)
+	if precision <= 0 {
+		sqltype = "numeric"
+	}
+	return typed(colmaker(name, sqltype, false, options...), numbers(true))
+}
+
+func Real(name string, options ...interface{}) Field {
+	return typed(colmaker(name, "real", false, options...), numbers(false))
+}
+
+func Double(name string, options ...interface{}) Field {
+	return typed(colmaker(name, "double precision", false, options...), numbers(false))
+}
+
+func Date(name string, options ...interface{}) Field {
+	return typed(colmaker(name, "date", true, options...), times)
+}
+
+func Time(name string, options ...interface{}) Field {
+	return typed(colmaker(name, "time", true, options...), times)
+}
+
+func Timestamp(name string, options ...interface{}) Field {
+	return typed(colmaker(name, "timestamp", true, options...), times)
+}
+
+func Timestamptz(name string, options ...interface{}) Field {
+	return typed(colmaker(name, "timestamptz", true, options...), times)
+}
+
+//Bind time.Duration values or postgres interval strings i.e. "1 day 02:00:00"
+func Interval(name string, options ...interface{}) Field {
+	return typed(colmaker(name, "interval", true, options...), func(value interface{}) (interface{}, error) {
+		switch value.(type) {
+		case time.Duration, string:
+			return value, nil
+		}
+		return nil, fmt.Errorf("%s interval %T", TIP, value)
+	})
+}
+
+var uuidPattern = regexp.MustCompile(`^(?i)\{?[0-9a-f]{8}-?[0-9a-f]{4}-?[0-9a-f]{4}-?[0-9a-f]{4}-?[0-9a-f]{12}\}?$`)
+
+//Bind strings in any of the formats postgres accepts or [16]byte values
+func UUID(name string, options ...interface{}) Field {
+	return typed(colmaker(name, "uuid", true, options...), func(value interface{}) (interface{}, error) {
+		switch v := value.(type) {
+		case [16]byte:
+			return fmt.Sprintf("%x-%x-%x-%x-%x", v[0:4], v[4:6], v[6:8], v[8:10], v[10:16]), nil
+		case string:
+			if uuidPattern.MatchString(v) {
+				return v, nil
+			}
+			return nil, fmt.Errorf("%q is not a uuid", v)
+		case fmt.Stringer:
+			return v.String(), nil
+		}
+		return nil, fmt.Errorf("%s uuid %T", TIP, value)
+	})
+}
+
+//json column. []byte and json.RawMessage values are sent as already encoded documents, everything
+//else including strings is marshaled.
+func JSON(name string, options ...interface{}) Field {
+	return typed(colmaker(name, "json", true, options...), documents)
+}
+
+func JSONB(name string, options ...interface{}) Field {
+	return typed(colmaker(name, "jsonb", true, options...), documents)
+}
+
+func Bytea(name string, options ...interface{}) Field {
+	return typed(colmaker(name, "bytea", false, options...), func(value interface{}) (interface{}, error) {
+		switch v := value.(type) {
+		case []byte:
+			return v, nil
+		case string:
+			return []byte(v), nil
+		}
+		return nil, fmt.Errorf("%s bytea %T", TIP, value)
+	})
+}
+
+//IPv4 or IPv6 host address optionally with its subnet i.e. 192.168.0.1/24
+func Inet(name string, options ...interface{}) Field {
+	return typed(colmaker(name, "inet", true, options...), addresses(false))
+}
+
+//IPv4 or IPv6 network i.e. 10.0.0.0/8
+func Cidr(name string, options ...interface{}) Field {
+	return typed(colmaker(name, "cidr", true, options...), addresses(true))
+}
+
+//Array column of the element's type named after the element i.e. Array(Integer("scores")) is
+//scores integer[]. Constraints passed as options apply to the array column.
+func Array(element Field, options ...interface{}) Field {
+	field := colmaker(element.name, element.ddl+"[]", false, options...)
+	return typed(field, func(value interface{}) (interface{}, error) {
+		v := reflect.ValueOf(value)
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return nil, fmt.Errorf("%s array %T", TIP, value)
+		}
+		for i := 0; i < v.Len(); i++ {
+			if _, err := element.Bind(v.Index(i).Interface()); err != nil {
+				return nil, fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+		return value, nil
+	})
+}
+
+//User defined enumerated type. The type must exist before tables using it are created.
+type SqlEnum struct {
+	name   string
+	values []string
+}
+
+func EnumType(name string, values ...string) *SqlEnum {
+	return &SqlEnum{name, values}
+}
+
+func (e *SqlEnum) Name() string {
+	return e.name
+}
+
+func (e *SqlEnum) Values() []string {
+	return e.values
+}
+
+func (e *SqlEnum) CREATE() string {
+	values := []string{}
+	for _, value := range e.values {
+		values = append(values, fmt.Sprintf("'%s'", strings.ReplaceAll(value, "'", "''")))
+	}
+	return fmt.Sprintf("CREATE TYPE %s AS ENUM (%s)", e.name, strings.Join(values, ", "))
+}
+
+//Column of a user defined enumerated type, only the values of the type can be bound
+func Enum(name string, enum *SqlEnum, options ...interface{}) Field {
+	return typed(colmaker(name, enum.name, true, options...), func(value interface{}) (interface{}, error) {
+		s := fmt.Sprint(value)
+		for _, allowed := range enum.values {
+			if s == allowed {
+				return s, nil
+			}
+		}
+		return nil, fmt.Errorf("%q is not one of %s", s, strings.Join(enum.values, ", "))
+	})
+}
+
+func typed(f Field, bind binder) Field {
+	f.bind = bind
+	return f
+}
+
+//Pull the length out of the options of a Field constructor
+func sized(options []interface{}) (int, []interface{}) {
+	length := 0
+	rest := []interface{}{}
+	for _, option := range options {
+		if n, ok := option.(int); ok {
+			length = n
+			continue
+		}
+		rest = append(rest, option)
+	}
+	return length, rest
+}
+
+func integers(min int64, max int64) binder {
+	return func(value interface{}) (interface{}, error) {
+		v := reflect.ValueOf(value)
+		var n int64
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n = v.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if v.Uint() > math.MaxInt64 {
+				return nil, fmt.Errorf("%d is out of range", v.Uint())
+			}
+			n = int64(v.Uint())
+		default:
+			return nil, fmt.Errorf("%s integer %T", TIP, value)
+		}
+		if n < min || n > max {
+			return nil, fmt.Errorf("%d is out of range", n)
+		}
+		return n, nil
+	}
+}
+
+func numbers(decimals bool) binder {
+	return func(value interface{}) (interface{}, error) {
+		v := reflect.ValueOf(value)
+		switch v.Kind() {
+		case reflect.Float32, reflect.Float64:
+			return v.Float(), nil
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return value, nil
+		case reflect.String:
+			if decimals {
+				return value, nil
+			}
+		}
+		return nil, fmt.Errorf("%s number %T", TIP, value)
+	}
+}
+
+func characters(length int) binder {
+	return func(value interface{}) (interface{}, error) {
+		var s string
+		switch v := value.(type) {
+		case string:
+			s = v
+		case fmt.Stringer:
+			s = v.String()
+		default:
+			return nil, fmt.Errorf("%s string %T", TIP, value)
+		}
+		if length > 0 && utf8.RuneCountInString(s) > length {
+			return nil, fmt.Errorf("value is longer than %d characters", length)
+		}
+		return s, nil
+	}
+}
+
+func times(value interface{}) (interface{}, error) {
+	switch value.(type) {
+	case time.Time, string:
+		return value, nil
+	}
+	return nil, fmt.Errorf("%s time %T", TIP, value)
+}
+
+func documents(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case json.RawMessage:
+		return string(v), nil
+	case []byte:
+		return string(v), nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func addresses(network bool) binder {
+	return func(value interface{}) (interface{}, error) {
+		switch v := value.(type) {
+		case net.IP:
+			return v.String(), nil
+		case *net.IPNet:
+			return v.String(), nil
+		case net.IPNet:
+			return v.String(), nil
+		case string:
+			if network || strings.Contains(v, "/") {
+				if _, _, err := net.ParseCIDR(v); err != nil {
+					return nil, err
+				}
+				return v, nil
+			}
+			if net.ParseIP(v) == nil {
+				return nil, fmt.Errorf("%q is not an ip address", v)
+			}
+			return v, nil
+		}
+		return nil, fmt.Errorf("%s address %T", TIP, value)
+	}
+}
+
+var sizedType = regexp.MustCompile(`^([a-z ]+)\((\d+)(?:,\s*(\d+))?\)$`)
+
+//Build a field from the name of a postgres type as written in DDL or reported by format_type(...)
+//i.e. "character varying(45)", "timestamp with time zone" or "integer[]". Types outside the catalog
+//such as enums and domains get a field that renders the type as is and binds values unchanged.
+func Typed(name string, sqltype string, options ...interface{}) Field {
+	sqltype = strings.TrimSpace(sqltype)
+	if !strings.Contains(sqltype, `"`) {
+		//quoted names of user defined types keep their case
+		sqltype = strings.ToLower(sqltype)
+	}
+	if element := strings.TrimSuffix(sqltype, "[]"); element != sqltype {
+		return Array(Typed(name, element), options...)
+	}
+
+	if match := sizedType.FindStringSubmatch(sqltype); match != nil {
+		n, _ := strconv.Atoi(match[2])
+		switch match[1] {
+		case "varchar", "character varying":
+			return Varchar(name, append([]interface{}{n}, options...)...)
+		case "char", "character", "bpchar":
+			return Char(name, n, options...)
+		case "numeric", "decimal":
+			scale, _ := strconv.Atoi(match[3])
+			return Numeric(name, n, scale, options...)
+		}
+	}
+
+	switch sqltype {
+	case "smallint", "int2":
+		return SmallInt(name, options...)
+	case "integer", "int", "int4":
+		return Integer(name, options...)
+	case "bigint", "int8":
+		return BigInt(name, options...)
+	case "serial", "serial4":
+		return Serial(name, options...)
+	case "bigserial", "serial8":
+		return BigSerial(name, options...)
+	case "boolean", "bool":
+		return Boolean(name, options...)
+	case "text":
+		return Text(name, options...)
+	case "varchar", "character varying":
+		return Varchar(name, options...)
+	case "numeric", "decimal":
+		return Numeric(name, 0, 0, options...)
+	case "real", "float4":
+		return Real(name, options...)
+	case "double precision", "float8":
+		return Double(name, options...)
+	case "date":
+		return Date(name, options...)
+	case "time", "time without time zone":
+		return Time(name, options...)
+	case "timestamp", "timestamp without time zone":
+		return Timestamp(name, options...)
+	case "timestamptz", "timestamp with time zone":
+		return Timestamptz(name, options...)
+	case "interval":
+		return Interval(name, options...)
+	case "uuid":
+		return UUID(name, options...)
+	case "json":
+		return JSON(name, options...)
+	case "jsonb":
+		return JSONB(name, options...)
+	case "bytea":
+		return Bytea(name, options...)
+	case "inet":
+		return Inet(name, options...)
+	case "cidr":
+		return Cidr(name, options...)
+	}
+	return colmaker(name, sqltype, true, options...)
+}
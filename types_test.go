@@ -0,0 +1,204 @@
+package supersql_test
+
+import (
+	"database/sql"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/rayattack/supersql"
+	"github.com/rayattack/supersql/supersqltest"
+)
+
+var mpaa = supersql.EnumType("mpaa_rating", "G", "PG", "PG-13", "R", "NC-17")
+
+func TestColumnTypeDDL(t *testing.T) {
+	expectations := map[string]supersql.Field{
+		"a smallint":                             supersql.SmallInt("a"),
+		"a bigint GENERATED ALWAYS AS IDENTITY":  supersql.BigInt("a", supersql.GENERATED_ALWAYS_AS_IDENTITY),
+		"a serial PRIMARY KEY":                   supersql.Serial("a", supersql.PRIMARY_KEY),
+		"a bigserial":                            supersql.BigSerial("a"),
+		"a text":                                 supersql.Text("a"),
+		"a varchar(45) NOT NULL":                 supersql.Varchar("a", 45, supersql.NOT_NULL),
+		"a char(2)":                              supersql.Char("a", 2),
+		"a boolean DEFAULT TRUE":                 supersql.Boolean("a", supersql.DEFAULT(true)),
+		"a numeric(4, 2)":                        supersql.Numeric("a", 4, 2),
+		"a real":                                 supersql.Real("a"),
+		"a double precision":                     supersql.Double("a"),
+		"a date":                                 supersql.Date("a"),
+		"a time":                                 supersql.Time("a"),
+		"a timestamp":                            supersql.Timestamp("a"),
+		"a timestamptz NOT NULL DEFAULT now()":   supersql.Timestamptz("a", supersql.NOT_NULL, supersql.DEFAULT("now()")),
+		"a interval":                             supersql.Interval("a"),
+		"a uuid DEFAULT gen_random_uuid()":       supersql.UUID("a", supersql.DEFAULT("gen_random_uuid()")),
+		"a json":                                 supersql.JSON("a"),
+		"a jsonb":                                supersql.JSONB("a"),
+		"a bytea":                                supersql.Bytea("a"),
+		"a inet":                                 supersql.Inet("a"),
+		"a cidr":                                 supersql.Cidr("a"),
+		"a text[] NOT NULL":                      supersql.Array(supersql.Text("a"), supersql.NOT_NULL),
+		"a mpaa_rating DEFAULT 'G'::mpaa_rating": supersql.Enum("a", mpaa, supersql.DEFAULT("'G'::mpaa_rating")),
+		"a integer[] CHECK (cardinality(a) < 10)": supersql.Array(supersql.Integer("a"), supersql.CHECK("cardinality(a) < 10")),
+		`"Order" integer`:                         supersql.Integer("Order"),
+		`"user" text NOT NULL`:                    supersql.Text("user", supersql.NOT_NULL),
+	}
+	for expected, field := range expectations {
+		if field.DDL() != expected {
+			t.Logf("%s != %s", field.DDL(), expected)
+			t.Fail()
+		}
+	}
+	if mpaa.CREATE() != "CREATE TYPE mpaa_rating AS ENUM ('G', 'PG', 'PG-13', 'R', 'NC-17')" {
+		t.Log(mpaa.CREATE())
+		t.Fail()
+	}
+}
+
+func TestInvalidColumnOptions(t *testing.T) {
+	title := supersql.Text("title", supersql.NOT_NULL, "UNIQUE")
+	if title.Err() == nil || title.DDL() != "title text NOT NULL" {
+		t.Fatal(title.Err(), title.DDL())
+	}
+	film := supersql.Table("film", supersql.Integer("film_id"), title)
+	if film.Err() == nil {
+		t.Fatal("tables report the errors of their fields")
+	}
+	if _, err := supersqltest.New().Root().SELECT("film_id").FROM(film).GO(); err == nil {
+		t.Fatal("commands against tables with invalid fields fail")
+	}
+}
+
+type binding struct {
+	field supersql.Field
+	value interface{}
+}
+
+func TestColumnTypeBinding(t *testing.T) {
+	valid := []binding{
+		{supersql.SmallInt("a"), int16(4)},
+		{supersql.BigInt("a"), uint32(4)},
+		{supersql.Varchar("a", 4), "four"},
+		{supersql.Numeric("a", 4, 2), "19.99"},
+		{supersql.Timestamptz("a"), time.Now()},
+		{supersql.Interval("a"), time.Hour},
+		{supersql.UUID("a"), "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"},
+		{supersql.Inet("a"), net.ParseIP("10.0.0.1")},
+		{supersql.Cidr("a"), "10.0.0.0/8"},
+		{supersql.Array(supersql.Integer("a")), []int{1, 2, 3}},
+		{supersql.Enum("a", mpaa), "PG-13"},
+	}
+	for _, b := range valid {
+		if _, err := b.field.Bind(b.value); err != nil {
+			t.Logf("%s: %s", b.field.Type(), err)
+			t.Fail()
+		}
+	}
+
+	invalid := []binding{
+		{supersql.SmallInt("a"), 40000},
+		{supersql.Integer("a"), "4"},
+		{supersql.Varchar("a", 4), "fourty"},
+		{supersql.Real("a"), "1.5"},
+		{supersql.Boolean("a"), 1},
+		{supersql.UUID("a"), "not-a-uuid"},
+		{supersql.Inet("a"), "10.0.0.256"},
+		{supersql.Cidr("a"), "10.0.0.1"},
+		{supersql.Array(supersql.Integer("a")), []string{"1"}},
+		{supersql.Enum("a", mpaa), "X"},
+	}
+	for _, b := range invalid {
+		if _, err := b.field.Bind(b.value); err == nil {
+			t.Logf("%s accepted %v", b.field.Type(), b.value)
+			t.Fail()
+		}
+	}
+
+	doc, _ := supersql.JSONB("a").Bind(map[string]int{"stars": 5})
+	if doc != `{"stars":5}` {
+		t.Fail()
+	}
+}
+
+func TestInsertBindsDeclaredColumns(t *testing.T) {
+	fake := supersqltest.New()
+	customer := supersql.Table("customer",
+		supersql.Serial("customer_id", supersql.PRIMARY_KEY),
+		supersql.Varchar("email", 50),
+		supersql.JSONB("preferences"),
+	)
+
+	q := fake.Root().INSERT_INTO(customer, []string{"email", "preferences"})
+	if _, err := q.VALUES([]interface{}{"mary@example.com", map[string]bool{"newsletter": true}}).GO(); err != nil {
+		t.Fatal(err)
+	}
+	if args := fake.Calls()[0].Args; args[1] != `{"newsletter":true}` {
+		t.Log(args)
+		t.Fail()
+	}
+
+	long := "an.address.that.is.far.too.long.for.the.column@example.com"
+	if _, err := q.VALUES([]interface{}{long, nil}).GO(); err == nil {
+		t.Fail()
+	}
+}
+
+func TestInsertBindsPointers(t *testing.T) {
+	fake := supersqltest.New()
+	customer := supersql.Table("customer",
+		supersql.Integer("customer_id", supersql.PRIMARY_KEY),
+		supersql.Varchar("nickname", 20),
+		supersql.SmallInt("store_id"),
+		supersql.Varchar("email", 50),
+	)
+
+	var nickname *string
+	store := int16(2)
+	email := sql.NullString{String: "mary@example.com", Valid: true}
+	q := fake.Root().INSERT_INTO(customer, []string{"customer_id", "nickname", "store_id", "email"})
+	if _, err := q.VALUES([]interface{}{1, nickname, &store, email}).GO(); err != nil {
+		t.Fatal(err)
+	}
+	if args := fake.Calls()[0].Args; args[1] != nil || args[2] != int64(2) || args[3] != "mary@example.com" {
+		t.Fatal(args)
+	}
+
+	long := "far too long for the column"
+	if _, err := q.VALUES([]interface{}{2, &long, nil, nil}).GO(); err == nil {
+		t.Fatal("the value behind a pointer was not checked")
+	}
+}
+
+func TestTyped(t *testing.T) {
+	types := map[string]string{
+		"character varying(45)":       "varchar(45)",
+		"character varying":           "varchar",
+		"character(2)":                "char(2)",
+		"numeric(4,2)":                "numeric(4, 2)",
+		"numeric":                     "numeric",
+		"int4":                        "integer",
+		"timestamp with time zone":    "timestamptz",
+		"timestamp without time zone": "timestamp",
+		"double precision":            "double precision",
+		"integer[]":                   "integer[]",
+		"character varying(10)[]":     "varchar(10)[]",
+		"mpaa_rating":                 "mpaa_rating",
+		`"Mood"`:                      `"Mood"`,
+		`billing."Currency"[]`:        `billing."Currency"[]`,
+	}
+	for sqltype, expected := range types {
+		if field := supersql.Typed("a", sqltype); field.Type() != expected {
+			t.Logf("%s is %s", sqltype, field.Type())
+			t.Fail()
+		}
+	}
+
+	if _, err := supersql.Typed("a", "smallint").Bind(70000); err == nil {
+		t.Fail()
+	}
+	if ddl := supersql.Typed("a", "text", supersql.NOT_NULL).DDL(); ddl != "a text NOT NULL" {
+		t.Fatal(ddl)
+	}
+	if ddl := supersql.Numeric("total", 10, 2, supersql.GENERATED_ALWAYS_AS("price * quantity")).DDL(); ddl != "total numeric(10, 2) GENERATED ALWAYS AS (price * quantity) STORED" {
+		t.Fatal(ddl)
+	}
+}
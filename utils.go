@@ -3,24 +3,205 @@ package supersql
 import (
 	"fmt"
 	"strings"
+	"time"
 )
 
 func coerceToString(input interface{}) (t string) {
 	switch val := input.(type) {
 	case *SqlTable:
-		t = val.name
+		t = val.identifier(val.dialectOf())
 	case string:
 		t = val
 	}
 	return
 }
 
-func countAndReplacePlaceholders(ssql string) string {
-	if placeholders := strings.Count(ssql, "?"); placeholders > 0 {
-		for i := 0; i < placeholders; i++ {
-			//remember $params start at $1 not $0 so offset index here
-			ssql = strings.Replace(ssql, "?", fmt.Sprintf("$%d", i+1), 1)
+func replacePlaceholders(ssql string, dialect Dialect) string {
+	return placeholders(ssql, func(i int) string {
+		//remember $params start at $1 not $0 so offset index here
+		return dialect.Placeholder(i + 1)
+	})
+}
+
+//SQL literal of a value for statements that can not take parameters i.e. the query of a view
+func literal(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		return strings.ToUpper(fmt.Sprint(v))
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v)
+	case []byte:
+		return fmt.Sprintf("'\\x%x'", v)
+	case time.Time:
+		return fmt.Sprintf("'%s'", v.Format(time.RFC3339Nano))
+	}
+	return fmt.Sprintf("'%s'", strings.ReplaceAll(fmt.Sprint(value), "'", "''"))
+}
+
+//Replace the ? placeholders of ssql with the literals of args in order. Quoted text is skipped so
+//neither a ? inside the statement's own strings nor one inside an inlined literal is replaced.
+func inline(ssql string, args []interface{}) string {
+	return placeholders(ssql, func(i int) string {
+		if i < len(args) {
+			return literal(args[i])
+		}
+		return "?"
+	})
+}
+
+//Replace every ? placeholder of ssql with what replace returns for its index. Quoted literals,
+//quoted identifiers and comments are copied untouched so a ? inside them i.e. a password or a
+//commented out condition stays as written.
+func placeholders(ssql string, replace func(i int) string) string {
+	var b strings.Builder
+	n := 0
+	for i := 0; i < len(ssql); i++ {
+		c := ssql[i]
+		end := ""
+		switch {
+		case c == '\'' || c == '"':
+			end = string(c)
+		case strings.HasPrefix(ssql[i:], "--"):
+			end = "\n"
+		case strings.HasPrefix(ssql[i:], "/*"):
+			end = "*/"
+		case c == '?':
+			b.WriteString(replace(n))
+			n++
+			continue
+		default:
+			b.WriteByte(c)
+			continue
+		}
+		//copy the quoted text or comment up to and including its end
+		skip := strings.Index(ssql[i+1:], end)
+		if skip < 0 {
+			b.WriteString(ssql[i:])
+			break
+		}
+		next := i + 1 + skip + len(end)
+		b.WriteString(ssql[i:next])
+		i = next - 1
+	}
+	return b.String()
+}
+
+//Words that can appear in expressions without being column references
+var reserved = map[string]bool{
+	"ALL": true, "AND": true, "ANY": true, "ARRAY": true, "AS": true, "ASC": true, "BETWEEN": true,
+	"BY": true, "CASE": true, "CAST": true, "COLLATE": true, "CURRENT_DATE": true, "CURRENT_TIME": true,
+	"CURRENT_TIMESTAMP": true, "CURRENT_USER": true, "DAY": true, "DEFAULT": true, "DESC": true,
+	"DISTINCT": true, "DOW": true, "DOY": true, "ELSE": true, "END": true, "EPOCH": true, "ESCAPE": true,
+	"EXISTS": true, "FALSE": true, "FILTER": true, "FIRST": true, "FROM": true, "HOUR": true, "ILIKE": true,
+	"IN": true, "INTERVAL": true, "IS": true, "ISNULL": true, "LAST": true, "LIKE": true, "LOCALTIME": true,
+	"LOCALTIMESTAMP": true, "MINUTE": true, "MONTH": true, "NOT": true, "NOTNULL": true, "NULL": true,
+	"NULLS": true, "OR": true, "ORDER": true, "OVER": true, "PARTITION": true, "QUARTER": true,
+	"SECOND": true, "SESSION_USER": true, "SIMILAR": true, "SOME": true, "THEN": true, "TO": true,
+	"TRUE": true, "UNKNOWN": true, "WEEK": true, "WHEN": true, "YEAR": true,
+	//window frames
+	"CURRENT": true, "EXCLUDE": true, "FOLLOWING": true, "GROUPS": true, "NO": true, "OTHERS": true,
+	"PRECEDING": true, "RANGE": true, "ROW": true, "ROWS": true, "TIES": true, "UNBOUNDED": true,
+	"WHERE": true,
+}
+
+//Words that end an operand like a column reference does
+var operands = map[string]bool{
+	"CURRENT_DATE": true, "CURRENT_TIME": true, "CURRENT_TIMESTAMP": true, "CURRENT_USER": true,
+	"END": true, "FALSE": true, "LOCALTIME": true, "LOCALTIMESTAMP": true, "NULL": true,
+	"SESSION_USER": true, "TRUE": true, "UNKNOWN": true,
+}
+
+//Pull the column references out of an SQL expression for validation against declared tables.
+//String literals, typed literals i.e. DATE '2020-01-01', numbers, placeholders, keywords, function
+//names and casts are skipped and the names given to expressions with or without AS are returned
+//separately. Expressions holding a subquery or a word that can not be told apart from a column
+//reference report ok as false.
+func identifiers(expr string) (refs []string, aliases []string, ok bool) {
+	isStart := func(c byte) bool {
+		return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+	}
+	isPart := func(c byte) bool {
+		return isStart(c) || c == '$' || c == '.' || c == '*' || (c >= '0' && c <= '9')
+	}
+
+	previous := ""
+	//whether the previous token ended an operand so a word after it can only be an alias
+	operand := false
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == '\'':
+			//string literal with '' as the escaped quote
+			for i++; i < len(expr); i++ {
+				if expr[i] == '\'' {
+					if i+1 < len(expr) && expr[i+1] == '\'' {
+						i++
+						continue
+					}
+					break
+				}
+			}
+			i++
+			previous, operand = "'", true
+		case c == ':' && i+1 < len(expr) && expr[i+1] == ':':
+			i += 2
+			previous, operand = "::", false
+		case c == '$' || (c >= '0' && c <= '9'):
+			for i++; i < len(expr) && (isPart(expr[i]) || expr[i] == '.'); i++ {
+			}
+			previous, operand = "0", true
+		case isStart(c) || c == '"':
+			start := i
+			for i++; i < len(expr) && (isPart(expr[i]) || expr[i] == '"'); i++ {
+			}
+			word := strings.ReplaceAll(expr[start:i], `"`, "")
+			upper := strings.ToUpper(word)
+			next := strings.TrimLeft(expr[i:], " \t\n")
+			kind := strings.ToUpper(strings.Join(strings.Fields(next), " "))
+
+			switch {
+			case upper == "SELECT":
+				return nil, nil, false
+			case upper == "AT" && operand && strings.HasPrefix(kind, "TIME ZONE"):
+				//the time zone conversion operator, the zone follows as an operand
+				i = len(expr) - len(next)
+				i += strings.Index(strings.ToUpper(next), "ZONE") + len("ZONE")
+				previous, operand = "ZONE", false
+				continue
+			case previous == "AS":
+				aliases = append(aliases, word)
+				operand = true
+			case previous == "OVER" || previous == "COLLATE":
+				//name of a window from the WINDOW clause or of a collation
+				operand = true
+			case previous == "::":
+				operand = true
+			case strings.HasPrefix(next, "("):
+				operand = false
+			case strings.HasPrefix(next, "'") && !operand:
+				//type of a typed literal
+				operand = false
+			case reserved[upper]:
+				operand = operands[upper]
+			case operand && next == "":
+				//alias given without AS
+				aliases = append(aliases, word)
+			case operand:
+				return nil, nil, false
+			default:
+				refs = append(refs, word)
+				operand = true
+			}
+			previous = upper
+		default:
+			i++
+			if c != ' ' && c != '\t' && c != '\n' {
+				previous = string(c)
+				operand = c == ')' || c == ']' || c == '?'
+			}
 		}
 	}
-	return ssql
+	return refs, aliases, true
 }
@@ -0,0 +1,178 @@
+package supersql
+
+import (
+	"fmt"
+	"strings"
+)
+
+//Declaration of a view or materialized view over an expantiated command. Arguments bound to the
+//command are written into the view as literals because views can not take parameters.
+//
+//	recent := Xql.SELECT("title").FROM("film").WHERE("release_year > ?", 2005)
+//	supersql.View("recent_films", recent).CREATE() // CREATE VIEW recent_films AS SELECT ...
+type SqlView struct {
+	name         string
+	query        Command
+	columns      []string
+	materialized bool
+	orReplace    bool
+	noData       bool
+}
+
+func View(name string, query Command) SqlView {
+	return SqlView{name: name, query: query}
+}
+
+//Materialized views store the records of their query until they are refreshed
+func MaterializedView(name string, query Command) SqlView {
+	return SqlView{name: name, query: query, materialized: true}
+}
+
+//Name the columns of the view instead of using the names of the query's columns
+func (v SqlView) COLUMNS(columns ...string) SqlView {
+	v.columns = columns
+	return v
+}
+
+//Replace an existing view of the same name, only plain views can be replaced
+func (v SqlView) OR_REPLACE() SqlView {
+	v.orReplace = true
+	return v
+}
+
+//Create a materialized view without running its query, it can not be read until refreshed
+func (v SqlView) WITH_NO_DATA() SqlView {
+	v.noData = true
+	return v
+}
+
+func (v SqlView) Name() string {
+	return v.name
+}
+
+//Generate the CREATE VIEW or CREATE MATERIALIZED VIEW statement. Fails when the query of the view
+//failed to expantiate or the combination of modifiers is not valid.
+func (v SqlView) CREATE() (string, error) {
+	if v.query == nil {
+		return "", fmt.Errorf("supersql: view %s has no query", v.name)
+	}
+	if v.orReplace && v.materialized {
+		return "", fmt.Errorf("supersql: materialized view %s can not be replaced, drop it first", v.name)
+	}
+	if v.noData && !v.materialized {
+		return "", fmt.Errorf("supersql: only materialized views can be created WITH NO DATA")
+	}
+	var query SqlQuery
+	switch c := v.query.(type) {
+	case SqlQuery:
+		query = c
+	case *SqlQuery:
+		query = *c
+	default:
+		return "", fmt.Errorf("supersql: view %s can not be made from %T", v.name, v.query)
+	}
+	if err := query.check(); err != nil {
+		return "", err
+	}
+
+	parts := []string{"CREATE"}
+	if v.orReplace {
+		parts = append(parts, "OR REPLACE")
+	}
+	if v.materialized {
+		parts = append(parts, "MATERIALIZED")
+	}
+	parts = append(parts, "VIEW", v.identifier())
+	if len(v.columns) > 0 {
+		parts = append(parts, fmt.Sprintf("(%s)", strings.Join(v.columns, ", ")))
+	}
+	parts = append(parts, "AS", strings.TrimSpace(inline(query.ssql, query.args)))
+	if v.noData {
+		parts = append(parts, "WITH NO DATA")
+	}
+	return strings.Join(parts, " "), nil
+}
+
+func (v SqlView) DROP() SqlDrop {
+	kind := "VIEW"
+	if v.materialized {
+		kind = "MATERIALIZED VIEW"
+	}
+	return SqlDrop{kind: kind, name: v.identifier()}
+}
+
+//The possibly schema qualified name of the view quoted where needed i.e. reporting."Recent"
+func (v SqlView) identifier() string {
+	return Table(v.name).identifier(Postgres)
+}
+
+//Statement replacing the records of a materialized view with a fresh run of its query
+func (v SqlView) REFRESH() SqlRefresh {
+	return SqlRefresh{name: v.identifier()}
+}
+
+type SqlRefresh struct {
+	name         string
+	concurrently bool
+	noData       bool
+}
+
+//Refresh without locking out reads. The materialized view needs a unique index and must have been
+//populated before.
+func (r SqlRefresh) CONCURRENTLY() SqlRefresh {
+	r.concurrently = true
+	return r
+}
+
+//Empty the materialized view instead of running its query
+func (r SqlRefresh) WITH_NO_DATA() SqlRefresh {
+	r.noData = true
+	return r
+}
+
+func (r SqlRefresh) String() string {
+	parts := []string{"REFRESH MATERIALIZED VIEW"}
+	if r.concurrently {
+		parts = append(parts, "CONCURRENTLY")
+	}
+	parts = append(parts, r.name)
+	if r.noData {
+		parts = append(parts, "WITH NO DATA")
+	}
+	return strings.Join(parts, " ")
+}
+
+//DROP statement of a schema object
+type SqlDrop struct {
+	kind         string
+	name         string
+	concurrently bool
+	ifExists     bool
+	cascade      bool
+}
+
+func (d SqlDrop) IF_EXISTS() SqlDrop {
+	d.ifExists = true
+	return d
+}
+
+//Also drop the objects that depend on the dropped one i.e. views over a table
+func (d SqlDrop) CASCADE() SqlDrop {
+	d.cascade = true
+	return d
+}
+
+func (d SqlDrop) String() string {
+	parts := []string{"DROP", d.kind}
+	if d.concurrently {
+		parts = append(parts, "CONCURRENTLY")
+	}
+	if d.ifExists {
+		parts = append(parts, "IF EXISTS")
+	}
+	parts = append(parts, d.name)
+	if d.cascade {
+		parts = append(parts, "CASCADE")
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,110 @@
+package supersql_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rayattack/supersql"
+	"github.com/rayattack/supersql/supersqltest"
+)
+
+func TestView(t *testing.T) {
+	q := supersqltest.New().Root()
+	recent := q.SELECT("title", "rating").FROM("film").WHERE("release_year > ? AND rating <> ?", 2005, "NC-17")
+
+	ddl, err := supersql.View("recent_films", recent).OR_REPLACE().CREATE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ddl != "CREATE OR REPLACE VIEW recent_films AS SELECT title, rating FROM film WHERE release_year > 2005 AND rating <> 'NC-17'" {
+		t.Fatal(ddl)
+	}
+
+	quoted := q.SELECT("title").FROM("film").WHERE("title = ?", "Director's Cut")
+	ddl, _ = supersql.View("cuts", quoted).COLUMNS("name").CREATE()
+	if ddl != "CREATE VIEW cuts (name) AS SELECT title FROM film WHERE title = 'Director''s Cut'" {
+		t.Fatal(ddl)
+	}
+	asked := q.SELECT("title").FROM("film").WHERE("description = ? AND note <> 'why?' AND rating = ?", "what?", "PG")
+	ddl, _ = supersql.View("asked", asked).CREATE()
+	if ddl != "CREATE VIEW asked AS SELECT title FROM film WHERE description = 'what?' AND note <> 'why?' AND rating = 'PG'" {
+		t.Fatal(ddl)
+	}
+	limited := q.SELECT("title").FROM("film").ORDER_BY("title").LIMIT(10)
+	if ddl, err := supersql.View("first_films", limited).CREATE(); err != nil || ddl != "CREATE VIEW first_films AS SELECT title FROM film ORDER BY title LIMIT 10" {
+		t.Fatal(ddl, err)
+	}
+	flagged := q.SELECT("title").FROM("film").WHERE("special = ? AND rate < ? AND deleted_at IS ? AND poster = ?", true, 2.99, nil, []byte{0xca, 0xfe})
+	ddl, _ = supersql.View("flagged", flagged).CREATE()
+	if ddl != `CREATE VIEW flagged AS SELECT title FROM film WHERE special = TRUE AND rate < 2.99 AND deleted_at IS NULL AND poster = '\xcafe'` {
+		t.Fatal(ddl)
+	}
+	if drop := supersql.View("cuts", quoted).DROP().IF_EXISTS().CASCADE().String(); drop != "DROP VIEW IF EXISTS cuts CASCADE" {
+		t.Fatal(drop)
+	}
+
+	reporting := supersql.View(`reporting."Recent"`, recent)
+	if ddl, _ := reporting.CREATE(); ddl != `CREATE VIEW reporting."Recent" AS SELECT title, rating FROM film WHERE release_year > 2005 AND rating <> 'NC-17'` {
+		t.Fatal(ddl)
+	}
+	if drop := reporting.DROP().String(); drop != `DROP VIEW reporting."Recent"` {
+		t.Fatal(drop)
+	}
+}
+
+func TestMaterializedView(t *testing.T) {
+	q := supersqltest.New().Root()
+	sales := supersql.MaterializedView("sales_by_store", q.SELECT("store_id", "sum(amount) AS total").FROM("payment"))
+
+	ddl, err := sales.WITH_NO_DATA().CREATE()
+	if err != nil || ddl != "CREATE MATERIALIZED VIEW sales_by_store AS SELECT store_id, sum(amount) AS total FROM payment WITH NO DATA" {
+		t.Fatal(ddl, err)
+	}
+	if refresh := sales.REFRESH().CONCURRENTLY().String(); refresh != "REFRESH MATERIALIZED VIEW CONCURRENTLY sales_by_store" {
+		t.Fatal(refresh)
+	}
+	if refresh := sales.REFRESH().WITH_NO_DATA().String(); refresh != "REFRESH MATERIALIZED VIEW sales_by_store WITH NO DATA" {
+		t.Fatal(refresh)
+	}
+	if drop := sales.DROP().String(); drop != "DROP MATERIALIZED VIEW sales_by_store" {
+		t.Fatal(drop)
+	}
+
+	if _, err := sales.OR_REPLACE().CREATE(); err == nil {
+		t.Fail()
+	}
+	if _, err := supersql.View("v", q.SELECT("1")).WITH_NO_DATA().CREATE(); err == nil {
+		t.Fail()
+	}
+}
+
+func TestViewOfFailedCommand(t *testing.T) {
+	q := supersqltest.New().Root()
+	mysql := q.WithDialect(supersql.MySQL)
+	failed := mysql.INSERT_INTO("film", []string{"title"}).VALUES([]interface{}{"x"}).RETURNING("film_id")
+	if _, err := supersql.View("v", failed).CREATE(); err == nil {
+		t.Fail()
+	}
+	if _, err := supersql.View("v", nil).CREATE(); err == nil || errors.Unwrap(err) != nil {
+		t.Fail()
+	}
+}
+
+func TestSchemaObjectsInTransaction(t *testing.T) {
+	fake := supersqltest.New()
+	film := supersql.Table("film")
+	err := fake.Root().TRANSACTION(func(tx *supersql.SqlQuery) error {
+		if _, err := tx.RUN(supersql.Index("film_title", film, "title").CREATE()).GO(); err != nil {
+			return err
+		}
+		_, err := tx.RUN(film.DROP().IF_EXISTS().CASCADE().String()).GO()
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := fake.Calls()
+	if calls[1].SQL != "CREATE INDEX film_title ON film (title)" || calls[2].SQL != "DROP TABLE IF EXISTS film CASCADE" {
+		t.Fatal(calls)
+	}
+}
@@ -0,0 +1,242 @@
+package supersql
+
+import (
+	"fmt"
+	"strings"
+)
+
+//Start or end of a window frame
+type FrameBound string
+
+const (
+	UNBOUNDED_PRECEDING FrameBound = "UNBOUNDED PRECEDING"
+	CURRENT_ROW         FrameBound = "CURRENT ROW"
+	UNBOUNDED_FOLLOWING FrameBound = "UNBOUNDED FOLLOWING"
+)
+
+//The frame starts or ends offset rows, peer groups or values before the current row i.e.
+//PRECEDING(3) or PRECEDING("'7 days'::interval") for RANGE frames over dates
+func PRECEDING(offset interface{}) FrameBound {
+	return FrameBound(fmt.Sprintf("%v PRECEDING", offset))
+}
+
+//The frame starts or ends offset rows, peer groups or values after the current row
+func FOLLOWING(offset interface{}) FrameBound {
+	return FrameBound(fmt.Sprintf("%v FOLLOWING", offset))
+}
+
+//Definition of a window, the records a window function sees for every record. Every modifier
+//returns a copy so a definition can be the base of others.
+//
+//	w := supersql.Window().PARTITION_BY("customer_id").ORDER_BY("rental_date")
+//	q.SELECT("rental_id", supersql.ROW_NUMBER().OVER(w)+" AS n", supersql.SUM("amount").OVER(w.ROWS(supersql.UNBOUNDED_PRECEDING, supersql.CURRENT_ROW)))
+type SqlWindow struct {
+	base      string
+	partition []string
+	order     []string
+	frame     string
+}
+
+//A window definition, extending the named window base from the WINDOW clause when one is given
+func Window(base ...string) SqlWindow {
+	w := SqlWindow{}
+	if len(base) > 0 {
+		w.base = base[0]
+	}
+	return w
+}
+
+func (w SqlWindow) PARTITION_BY(exprs ...string) SqlWindow {
+	w.partition = append(w.partition[:len(w.partition):len(w.partition)], exprs...)
+	return w
+}
+
+func (w SqlWindow) ORDER_BY(exprs ...string) SqlWindow {
+	w.order = append(w.order[:len(w.order):len(w.order)], exprs...)
+	return w
+}
+
+func (w SqlWindow) framed(mode string, start FrameBound, end []FrameBound) SqlWindow {
+	if len(end) == 0 {
+		w.frame = fmt.Sprintf("%s %s", mode, start)
+	} else {
+		w.frame = fmt.Sprintf("%s BETWEEN %s AND %s", mode, start, end[0])
+	}
+	return w
+}
+
+//Frame counted in records i.e. ROWS(PRECEDING(2), CURRENT_ROW) for a three record moving window.
+//Without an end the frame ends at the current record.
+func (w SqlWindow) ROWS(start FrameBound, end ...FrameBound) SqlWindow {
+	return w.framed("ROWS", start, end)
+}
+
+//Frame counted in values of the single ORDER BY expression i.e. RANGE(PRECEDING("'7 days'::interval"), CURRENT_ROW)
+func (w SqlWindow) RANGE(start FrameBound, end ...FrameBound) SqlWindow {
+	return w.framed("RANGE", start, end)
+}
+
+//Frame counted in groups of records that are peers under ORDER BY
+func (w SqlWindow) GROUPS(start FrameBound, end ...FrameBound) SqlWindow {
+	return w.framed("GROUPS", start, end)
+}
+
+//Leave records out of the frame: CURRENT ROW, GROUP, TIES or NO OTHERS
+func (w SqlWindow) EXCLUDE(what string) SqlWindow {
+	if w.frame != "" {
+		w.frame = fmt.Sprintf("%s EXCLUDE %s", w.frame, what)
+	}
+	return w
+}
+
+//The definition without its parentheses i.e. PARTITION BY customer_id ORDER BY rental_date
+func (w SqlWindow) String() string {
+	parts := []string{}
+	if w.base != "" {
+		parts = append(parts, w.base)
+	}
+	if len(w.partition) > 0 {
+		parts = append(parts, "PARTITION BY "+strings.Join(w.partition, ", "))
+	}
+	if len(w.order) > 0 {
+		parts = append(parts, "ORDER BY "+strings.Join(w.order, ", "))
+	}
+	if w.frame != "" {
+		parts = append(parts, w.frame)
+	}
+	return strings.Join(parts, " ")
+}
+
+//Expressions of the definition that can refer to columns
+func (w SqlWindow) expressions() []string {
+	return append(append([]string{}, w.partition...), w.order...)
+}
+
+//Call of a window function or of an aggregate used as one, completed into a select expression with
+//OVER(...)
+type SqlWindowFunction struct {
+	call   string
+	filter string
+}
+
+func windowFunction(name string, args ...interface{}) SqlWindowFunction {
+	values := []string{}
+	for _, arg := range args {
+		values = append(values, fmt.Sprint(arg))
+	}
+	return SqlWindowFunction{call: fmt.Sprintf("%s(%s)", name, strings.Join(values, ", "))}
+}
+
+//Number of the record within its partition counting from 1
+func ROW_NUMBER() SqlWindowFunction {
+	return windowFunction("ROW_NUMBER")
+}
+
+//Rank of the record with gaps after peers
+func RANK() SqlWindowFunction {
+	return windowFunction("RANK")
+}
+
+//Rank of the record without gaps after peers
+func DENSE_RANK() SqlWindowFunction {
+	return windowFunction("DENSE_RANK")
+}
+
+func PERCENT_RANK() SqlWindowFunction {
+	return windowFunction("PERCENT_RANK")
+}
+
+func CUME_DIST() SqlWindowFunction {
+	return windowFunction("CUME_DIST")
+}
+
+//Number of the bucket the record falls into when the partition is split into buckets
+func NTILE(buckets int) SqlWindowFunction {
+	return windowFunction("NTILE", buckets)
+}
+
+//Value of expr offset records before the current one, offset defaults to 1. A second argument is
+//the default used when there is no such record i.e. LAG("amount", 1, 0).
+func LAG(expr string, offsetAndDefault ...interface{}) SqlWindowFunction {
+	return windowFunction("LAG", append([]interface{}{expr}, offsetAndDefault...)...)
+}
+
+//Value of expr offset records after the current one, see LAG
+func LEAD(expr string, offsetAndDefault ...interface{}) SqlWindowFunction {
+	return windowFunction("LEAD", append([]interface{}{expr}, offsetAndDefault...)...)
+}
+
+func FIRST_VALUE(expr string) SqlWindowFunction {
+	return windowFunction("FIRST_VALUE", expr)
+}
+
+//Value of expr for the last record of the frame, which ends at the current record unless the
+//window has a frame
+func LAST_VALUE(expr string) SqlWindowFunction {
+	return windowFunction("LAST_VALUE", expr)
+}
+
+func NTH_VALUE(expr string, n int) SqlWindowFunction {
+	return windowFunction("NTH_VALUE", expr, n)
+}
+
+//Running or moving sum of expr over the frame
+func SUM(expr string) SqlWindowFunction {
+	return windowFunction("SUM", expr)
+}
+
+func AVG(expr string) SqlWindowFunction {
+	return windowFunction("AVG", expr)
+}
+
+func COUNT(expr string) SqlWindowFunction {
+	return windowFunction("COUNT", expr)
+}
+
+func MIN(expr string) SqlWindowFunction {
+	return windowFunction("MIN", expr)
+}
+
+func MAX(expr string) SqlWindowFunction {
+	return windowFunction("MAX", expr)
+}
+
+//Any other function call used as a window function i.e. WINDOW_FUNCTION("string_agg(title, ', ')")
+func WINDOW_FUNCTION(call string) SqlWindowFunction {
+	return SqlWindowFunction{call: call}
+}
+
+//Only let records matching condition into an aggregate
+func (f SqlWindowFunction) FILTER(condition string) SqlWindowFunction {
+	f.filter = condition
+	return f
+}
+
+//Complete the call with the window it runs over, either a SqlWindow or the name of a window from
+//the WINDOW clause
+func (f SqlWindowFunction) OVER(window interface{}) string {
+	call := f.call
+	if f.filter != "" {
+		call = fmt.Sprintf("%s FILTER (WHERE %s)", call, f.filter)
+	}
+	switch w := window.(type) {
+	case SqlWindow:
+		return fmt.Sprintf("%s OVER (%s)", call, w)
+	case string:
+		return fmt.Sprintf("%s OVER %s", call, w)
+	}
+	return fmt.Sprintf("%s OVER (%v)", call, window)
+}
+
+//Name a window so window functions can run over it with OVER("name"). Consecutive invocations add
+//to the same WINDOW clause which goes after WHERE and before ORDER BY.
+func (q SqlQuery) WINDOW(name string, window SqlWindow) Command {
+	q.reference(window.expressions()...)
+	if q.windowed {
+		q.ssql = fmt.Sprintf("%s, %s AS (%s)", q.ssql, name, window)
+	} else {
+		q.ssql = fmt.Sprintf("%s WINDOW %s AS (%s)", q.ssql, name, window)
+	}
+	q.windowed = true
+	return q
+}
@@ -0,0 +1,56 @@
+package supersql_test
+
+import (
+	"testing"
+
+	"github.com/rayattack/supersql"
+	"github.com/rayattack/supersql/supersqltest"
+)
+
+func TestWindowDefinitions(t *testing.T) {
+	w := supersql.Window().PARTITION_BY("language_id").ORDER_BY("rental_duration DESC", "title")
+	if w.String() != "PARTITION BY language_id ORDER BY rental_duration DESC, title" {
+		t.Fatal(w.String())
+	}
+	cases := map[string]string{
+		supersql.ROW_NUMBER().OVER(w): "ROW_NUMBER() OVER (PARTITION BY language_id ORDER BY rental_duration DESC, title)",
+		supersql.RANK().OVER("w"):     "RANK() OVER w",
+		supersql.SUM("amount").OVER(w.ROWS(supersql.UNBOUNDED_PRECEDING, supersql.CURRENT_ROW)):                                                  "SUM(amount) OVER (PARTITION BY language_id ORDER BY rental_duration DESC, title ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW)",
+		supersql.AVG("amount").OVER(supersql.Window().ORDER_BY("paid").RANGE(supersql.PRECEDING("'7 days'::interval"), supersql.CURRENT_ROW)):    "AVG(amount) OVER (ORDER BY paid RANGE BETWEEN '7 days'::interval PRECEDING AND CURRENT ROW)",
+		supersql.COUNT("*").FILTER("amount > 5").OVER(supersql.Window("w").GROUPS(supersql.PRECEDING(1), supersql.FOLLOWING(1)).EXCLUDE("TIES")): "COUNT(*) FILTER (WHERE amount > 5) OVER (w GROUPS BETWEEN 1 PRECEDING AND 1 FOLLOWING EXCLUDE TIES)",
+		supersql.LAG("amount", 1, 0).OVER(supersql.Window().ORDER_BY("paid").ROWS(supersql.PRECEDING(2))):                                        "LAG(amount, 1, 0) OVER (ORDER BY paid ROWS 2 PRECEDING)",
+		supersql.NTILE(4).OVER(supersql.Window()): "NTILE(4) OVER ()",
+	}
+	for got, expected := range cases {
+		if got != expected {
+			t.Errorf("expected %s got %s", expected, got)
+		}
+	}
+	if w.ORDER_BY("film_id").String() == w.String() {
+		t.Fatal("modifiers should return a copy")
+	}
+}
+
+func TestWindowClause(t *testing.T) {
+	q := supersqltest.New().Root()
+	w := supersql.Window().PARTITION_BY("language_id").ORDER_BY("rental_duration")
+	query := q.SELECT("title", supersql.RANK().OVER("w")+" AS position", supersql.FIRST_VALUE("title").OVER("longest")).
+		FROM(film).
+		WINDOW("w", w).
+		WINDOW("longest", supersql.Window("w").ROWS(supersql.UNBOUNDED_PRECEDING, supersql.UNBOUNDED_FOLLOWING))
+	expected := "SELECT title, RANK() OVER w AS position, FIRST_VALUE(title) OVER longest FROM film " +
+		"WINDOW w AS (PARTITION BY language_id ORDER BY rental_duration), longest AS (w ROWS BETWEEN UNBOUNDED PRECEDING AND UNBOUNDED FOLLOWING)"
+	if query.PP() != expected {
+		t.Fatal(query.PP())
+	}
+	if _, _, err := query.SQL(); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := q.SELECT("title").FROM(film).WINDOW("w", supersql.Window().PARTITION_BY("rating")).SQL(); err == nil {
+		t.Fatal("columns of window definitions should be checked")
+	}
+	again := q.SELECT("title").FROM(film).WINDOW("w", w).SELECT("film_id").FROM(film).WINDOW("v", w)
+	if again.PP() != "SELECT film_id FROM film WINDOW v AS (PARTITION BY language_id ORDER BY rental_duration)" {
+		t.Fatal(again.PP())
+	}
+}